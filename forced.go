@@ -0,0 +1,92 @@
+package main
+
+/*
+ * Look for a forced win by the player to move in a squava position,
+ * with a threat-space search: nothing but moves that leave a hole
+ * the other player has to fill, which lets it see a lot further
+ * ahead than a full-width search.
+ *
+ * ./forced 1,1 3,3 1,3 2,2 3,1     # position as moves, X first
+ * ./forced -d 31 1,1 3,3 1,3       # look up to 31 plies ahead
+ *
+ * No forced win by threats doesn't mean no forced win at all,
+ * a win might take quiet moves that threaten nothing.
+ */
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/threats"
+)
+
+func main() {
+	maxPlies := flag.Int("d", 25, "look for wins this many plies ahead, counting both players' moves")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s [flags] [m,n [m,n ...]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	rules, err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// X is MAXIMIZER, and moves first
+	bd := game.Standard.Bitboard()
+	player := game.MAXIMIZER
+	for _, str := range flag.Args() {
+		mn := strings.Split(str, ",")
+		if len(mn) != 2 {
+			fmt.Fprintf(os.Stderr, "Bad move %q, want m,n\n", str)
+			os.Exit(1)
+		}
+		m, err1 := strconv.Atoi(mn[0])
+		n, err2 := strconv.Atoi(mn[1])
+		if err1 != nil || err2 != nil || !game.Standard.OnBoard(m, n) {
+			fmt.Fprintf(os.Stderr, "Bad move %q, want m,n from 0 to 4\n", str)
+			os.Exit(1)
+		}
+		cell := game.Cell(m, n)
+		if bd.At(cell) != game.UNSET {
+			fmt.Fprintf(os.Stderr, "<%d,%d> already taken\n", m, n)
+			os.Exit(1)
+		}
+		bd.MakeMove(cell, player)
+		player = -player
+	}
+	if rules.Winner(&bd) != game.UNSET {
+		fmt.Fprintf(os.Stderr, "Game already over\n")
+		os.Exit(1)
+	}
+
+	fmt.Printf("%v\n", &bd)
+	mark := "X"
+	if player == game.MINIMIZER {
+		mark = "O"
+	}
+
+	// Control-C gives up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	before := time.Now()
+	line, nodes := threats.Search(ctx, &bd, rules, player, *maxPlies)
+	elapsed := time.Since(before)
+	stop()
+
+	if line == nil {
+		fmt.Printf("%s has no forced win by threats in %d plies, %d positions, %v\n", mark, *maxPlies, nodes, elapsed)
+		os.Exit(0)
+	}
+	fmt.Printf("%s wins in %d: %v, %d positions, %v\n", mark, threats.Plies(line), engine.Moves(game.Standard, line), nodes, elapsed)
+}
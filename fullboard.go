@@ -0,0 +1,239 @@
+package main
+
+/*
+ * Check the conjecture that O (second player) wins every 25-move
+ * game. A game lasts 25 moves when neither player makes a
+ * 4-in-a-row or a 3-in-a-row in the first 24, so the board before
+ * X's 13th move has no 3-in-a-row of either mark in it. Find every
+ * such board, have X fill in the last empty cell, and decide the
+ * game under -rule. The usual way, -rule win, X's 4-in-a-row wins,
+ * even if it has a 3-in-a-row inside it, X's 3-in-a-row loses,
+ * nothing is a cat game.
+ *
+ * Boards that are rotations or reflections of each other
+ * count once.
+ *
+ * ./fullboard
+ * ./fullboard -w 4    # 4 goroutines
+ * ./fullboard -rule lose
+ */
+
+import (
+	"flag"
+	"fmt"
+	"math/bits"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"squava/src/game"
+)
+
+// Cells 0 through prefixCells-1 get filled in before handing
+// the rest of the board to a goroutine: 3 ways per cell.
+const prefixCells = 5
+
+// fullBoard is a final position's canonical board, with the
+// lowest-numbered cell X could have moved in last.
+type fullBoard struct {
+	bd   game.Bitboard
+	last int
+}
+
+// result is one goroutine's full boards, keyed by their
+// canonical boards, and count of 24 mark boards
+type result struct {
+	finals map[game.Bitboard]fullBoard
+	count  int
+}
+
+type prefix struct {
+	bd            game.Bitboard
+	first, second int // marks of each player in the prefix
+}
+
+func main() {
+	workers := flag.Int("w", runtime.NumCPU(), "number of goroutines")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	flag.Parse()
+	rules, err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "Need at least 1 goroutine\n")
+		os.Exit(1)
+	}
+
+	start := time.Now()
+
+	prefixes := make(chan prefix)
+	results := make(chan result)
+	for i := 0; i < *workers; i++ {
+		go func() {
+			r := result{finals: make(map[game.Bitboard]fullBoard)}
+			for p := range prefixes {
+				bd := p.bd
+				r.count += fill(&bd, prefixCells, p.first, p.second, r.finals)
+			}
+			results <- r
+		}()
+	}
+
+	go func() {
+		var bd game.Bitboard
+		enumeratePrefixes(&bd, 0, 0, 0, prefixes)
+		close(prefixes)
+	}()
+
+	finals := make(map[game.Bitboard]fullBoard)
+	count := 0
+	for i := 0; i < *workers; i++ {
+		r := <-results
+		for canonical, f := range r.finals {
+			add(finals, canonical, f.last)
+		}
+		count += r.count
+	}
+
+	// Sort, so that the same example prints from run to run
+	boards := make([]game.Bitboard, 0, len(finals))
+	for canonical := range finals {
+		boards = append(boards, canonical)
+	}
+	sort.Slice(boards, func(i, j int) bool { return boards[i].Less(&boards[j]) })
+
+	var outcomes [3]int // indexed by winner+1
+	var examples [3]*fullBoard
+	for _, canonical := range boards {
+		f := finals[canonical]
+		winner := rules.MoveWinner(&f.bd, f.last)
+		outcomes[winner+1]++
+		if examples[winner+1] == nil {
+			examples[winner+1] = &f
+		}
+	}
+
+	fmt.Printf("%d positions after 24 moves with no winner\n", count)
+	fmt.Printf("%d full boards, up to rotation and reflection, %v\n", len(finals), time.Since(start))
+	fmt.Printf("-rule %v:\n", rules)
+	fmt.Printf("  O wins:   %d\n", outcomes[game.MINIMIZER+1])
+	fmt.Printf("  X wins:   %d\n", outcomes[game.MAXIMIZER+1])
+	fmt.Printf("  cat game: %d\n", outcomes[game.UNSET+1])
+
+	if outcomes[game.MAXIMIZER+1] == 0 && outcomes[game.UNSET+1] == 0 {
+		fmt.Printf("O wins every 25-move game\n")
+		return
+	}
+	for _, winner := range []int{game.MAXIMIZER, game.UNSET} {
+		if f := examples[winner+1]; f != nil {
+			fmt.Printf("\nCounter-example, %s:\n", describe(winner))
+			printGame(f)
+		}
+	}
+}
+
+func describe(winner int) string {
+	switch winner {
+	case game.MAXIMIZER:
+		return "X wins"
+	case game.MINIMIZER:
+		return "O wins"
+	}
+	return "cat game"
+}
+
+// enumeratePrefixes sends every way of filling cells cell
+// through prefixCells-1 that has no 3-in-a-row.
+func enumeratePrefixes(bd *game.Bitboard, cell, first, second int, prefixes chan<- prefix) {
+	if cell == prefixCells {
+		prefixes <- prefix{*bd, first, second}
+		return
+	}
+	// The one empty cell could be in the prefix
+	if first+second == cell {
+		enumeratePrefixes(bd, cell+1, first, second, prefixes)
+	}
+	for _, player := range []int{game.MAXIMIZER, game.MINIMIZER} {
+		bd.MakeMove(cell, player)
+		if bd.MoveWinner(cell) == game.UNSET {
+			if player == game.MAXIMIZER {
+				enumeratePrefixes(bd, cell+1, first+1, second, prefixes)
+			} else {
+				enumeratePrefixes(bd, cell+1, first, second+1, prefixes)
+			}
+		}
+		bd.UnmakeMove(cell, player)
+	}
+}
+
+// fill fills in cells cell through 24 with 12 marks of each player
+// and one empty cell, without any 3-in-a-row, then has X move in
+// the empty cell. It adds the full boards to finals, keyed by their
+// canonical boards, and returns the number of 24 mark boards.
+func fill(bd *game.Bitboard, cell, first, second int, finals map[game.Bitboard]fullBoard) int {
+	if cell == 25 {
+		last := bits.TrailingZeros64(bd.Empty())
+		bd.MakeMove(last, game.MAXIMIZER)
+		canonical, transform := bd.Canonical()
+		add(finals, canonical, game.TransformedCells[transform][last])
+		bd.UnmakeMove(last, game.MAXIMIZER)
+		return 1
+	}
+	count := 0
+	if first+second == cell {
+		count += fill(bd, cell+1, first, second, finals)
+	}
+	if first < 12 {
+		bd.MakeMove(cell, game.MAXIMIZER)
+		if bd.MoveWinner(cell) == game.UNSET {
+			count += fill(bd, cell+1, first+1, second, finals)
+		}
+		bd.UnmakeMove(cell, game.MAXIMIZER)
+	}
+	if second < 12 {
+		bd.MakeMove(cell, game.MINIMIZER)
+		if bd.MoveWinner(cell) == game.UNSET {
+			count += fill(bd, cell+1, first, second+1, finals)
+		}
+		bd.UnmakeMove(cell, game.MINIMIZER)
+	}
+	return count
+}
+
+// add puts a full board in finals, unless it's already
+// there with a lower-numbered last cell.
+func add(finals map[game.Bitboard]fullBoard, canonical game.Bitboard, last int) {
+	if f, ok := finals[canonical]; !ok || last < f.last {
+		finals[canonical] = fullBoard{canonical, last}
+	}
+}
+
+// printGame prints a full board, and one order of moves
+// that reaches it, with X's move in f.last coming last.
+func printGame(f *fullBoard) {
+	fmt.Printf("%v", &f.bd)
+	var xs, ys []int
+	for cell := 0; cell < 25; cell++ {
+		switch {
+		case cell == f.last:
+		case f.bd.At(cell) == game.MAXIMIZER:
+			xs = append(xs, cell)
+		default:
+			ys = append(ys, cell)
+		}
+	}
+	xs = append(xs, f.last)
+	fmt.Printf("moves:")
+	for i := range xs {
+		x, y := game.Coords(xs[i])
+		fmt.Printf(" %d,%d", x, y)
+		if i < len(ys) {
+			x, y = game.Coords(ys[i])
+			fmt.Printf(" %d,%d", x, y)
+		}
+	}
+	fmt.Printf("\n")
+}
@@ -7,25 +7,13 @@ import (
 	"math/rand"
 	"os"
 	"time"
-)
-
-type Board [5][5]int
 
-const WIN = 10000
-const LOSS = -10000
-const MAXIMIZER = 1
-const MINIMIZER = -1
-const UNSET = 0
+	"squava/src/game"
+)
 
 var maxDepth int = 9
 var deterministic bool
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
-
 func main() {
 
 	maxDepthPtr := flag.Int("d", 10, "maximum lookahead depth")
@@ -37,33 +25,20 @@ func main() {
 
 	deterministic = *deterministicPtr
 
-	// Set up for use by deltaValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
-	}
-
 	maxDepth = *maxDepthPtr
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	var bd Board
+	var bd game.Board
 	var endOfGame bool = false
 
 	for !endOfGame {
 
 		i, j, val := maximizerMove(&bd, maxDepth)
-		bd[i][j] = MAXIMIZER
+		bd[i][j] = game.MAXIMIZER
 		if *printBoardPtr {
 			fmt.Printf("Maximizer move: %d %d (%d)\n", i, j, val)
-			printBoard(&bd)
+			bd.Print()
 		} else {
 			fmt.Printf("%d %d (%d)\n", i, j, val)
 		}
@@ -73,10 +48,10 @@ func main() {
 		}
 
 		i, j, val = minimizerMove(&bd, maxDepth)
-		bd[i][j] = MINIMIZER
+		bd[i][j] = game.MINIMIZER
 		if *printBoardPtr {
 			fmt.Printf("Minimizer move: %d %d (%d)\n", i, j, val)
-			printBoard(&bd)
+			bd.Print()
 		} else {
 			fmt.Printf("%d %d (%d)\n", i, j, val)
 		}
@@ -86,89 +61,42 @@ func main() {
 
 	if *printBoardPtr {
 		var phrase string
-		switch findWinner(&bd) {
-		case MAXIMIZER:
+		switch bd.Winner() {
+		case game.MAXIMIZER:
 			phrase = "\nX wins\n"
-		case UNSET:
+		case game.UNSET:
 			phrase = "\nCat wins\n"
-		case MINIMIZER:
+		case game.MINIMIZER:
 			phrase = "\nO wins\n"
 		}
-		fmt.Printf(phrase)
+		fmt.Print(phrase)
 
-		printBoard(&bd)
+		bd.Print()
 	}
 
 	os.Exit(0)
 }
 
-func findWinner(bd *Board) int {
-	for _, quad := range winningQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return bd[quad[0][0]][quad[0][1]]
-		}
-	}
-
-	for _, triplet := range losingTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -bd[triplet[0][0]][triplet[0][1]]
-		}
-	}
-
-	return 0
-}
-
-// It turns out that you only have to look at
-// the 4-in-a-rows that contain these 9 cells
-// to check every 4-in-a-row. Similarly, you
-// only need to check these 9 cells to check
-// all the losing 3-in-a-row combos. You don't
-// have to look at each and every cell.
-var checkableCells [9][2]int = [9][2]int{
-	{0, 2}, {1, 2}, {2, 0},
-	{2, 1}, {2, 2}, {2, 3},
-	{2, 4}, {3, 2}, {4, 2},
-}
-
 // Calculates and returns the value of the move (x,y)
 // Only considers value gained or lost from the cell (x,y)
-func deltaValue(bd *Board, ply int, x, y int) (stopRecursing bool, value int) {
+func deltaValue(bd *game.Board, ply int, x, y int) (stopRecursing bool, value int) {
 
-	relevantQuads := indexedWinningQuads[x][y]
+	if winner := bd.MoveWinner(x, y); winner != game.UNSET {
+		return true, winner * (game.WIN - ply)
+	}
+
+	relevantQuads := game.IndexedWinningQuads[x][y]
 	for _, quad := range relevantQuads {
 		sum := bd[quad[0][0]][quad[0][1]]
 		sum += bd[quad[1][0]][quad[1][1]]
 		sum += bd[quad[2][0]][quad[2][1]]
 		sum += bd[quad[3][0]][quad[3][1]]
 
-		if sum == 4 || sum == -4 {
-			return true, bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-		}
 		if sum == 3 || sum == -3 {
 			value += sum * 10
 		}
 	}
 
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return true, -sum / 3 * (WIN - ply)
-		}
-	}
-
 	// Give it a slight bias for those early
 	// moves when all losing-triplets and winning-quads
 	// are beyond the horizon.
@@ -187,38 +115,25 @@ func deltaValue(bd *Board, ply int, x, y int) (stopRecursing bool, value int) {
 // Calculates and returns the value of the entire board.
 // It only looks at the cells in checkableCells[], so it
 // doesn't double-count very many combos.
-func wholeBoardValue(bd *Board) (value int) {
+func wholeBoardValue(bd *game.Board) (value int) {
+
+	if winner := bd.Winner(); winner != game.UNSET {
+		return winner * game.WIN
+	}
 
-	for _, cell := range checkableCells {
-		relevantQuads := indexedWinningQuads[cell[0]][cell[1]]
+	for _, cell := range game.CheckableCells {
+		relevantQuads := game.IndexedWinningQuads[cell[0]][cell[1]]
 		for _, quad := range relevantQuads {
 			sum := bd[quad[0][0]][quad[0][1]]
 			sum += bd[quad[1][0]][quad[1][1]]
 			sum += bd[quad[2][0]][quad[2][1]]
 			sum += bd[quad[3][0]][quad[3][1]]
 
-			if sum == 4 || sum == -4 {
-				return bd[quad[0][0]][quad[0][1]] * WIN
-			}
-
-			// Avoid 2 loops over checkableCells[] in the case of
-			// no 4-in-a-row wins
 			// Try to get into 3-of-winning-4 situtations
 			if sum == 3 || sum == -3 {
 				value += sum * 10
 			}
 		}
-
-		relevantTriplets := indexedLosingTriplets[cell[0]][cell[1]]
-		for _, triplet := range relevantTriplets {
-			sum := bd[triplet[0][0]][triplet[0][1]]
-			sum += bd[triplet[1][0]][triplet[1][1]]
-			sum += bd[triplet[2][0]][triplet[2][1]]
-
-			if sum == 3 || sum == -3 {
-				return -sum / 3 * WIN
-			}
-		}
 	}
 
 	// Give it a slight bias for those early
@@ -232,7 +147,7 @@ func wholeBoardValue(bd *Board) (value int) {
 	return value
 }
 
-func alphaBeta(bd *Board, ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int) {
+func alphaBeta(bd *game.Board, ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int) {
 
 	stopRecursing, delta := deltaValue(bd, ply, x, y)
 
@@ -243,14 +158,14 @@ func alphaBeta(bd *Board, ply int, player int, alpha int, beta int, x int, y int
 	}
 
 	switch player {
-	case MAXIMIZER:
-		value = 2*LOSS // Possible to score less than LOSS
+	case game.MAXIMIZER:
+		value = 2 * game.LOSS // Possible to score less than LOSS
 		for i, row := range bd {
 			for j, marker := range row {
-				if marker == UNSET {
-					bd[i][j] = MAXIMIZER
-					n := alphaBeta(bd, ply+1, MINIMIZER, alpha, beta, i, j, boardValue)
-					bd[i][j] = UNSET
+				if marker == game.UNSET {
+					bd[i][j] = game.MAXIMIZER
+					n := alphaBeta(bd, ply+1, game.MINIMIZER, alpha, beta, i, j, boardValue)
+					bd[i][j] = game.UNSET
 					if n > value {
 						value = n
 					}
@@ -263,14 +178,14 @@ func alphaBeta(bd *Board, ply int, player int, alpha int, beta int, x int, y int
 				}
 			}
 		}
-	case MINIMIZER:
-		value = 2*WIN // You can score greater than WIN
+	case game.MINIMIZER:
+		value = 2 * game.WIN // You can score greater than WIN
 		for i, row := range bd {
 			for j, marker := range row {
-				if marker == UNSET {
+				if marker == game.UNSET {
 					bd[i][j] = player
 					n := alphaBeta(bd, ply+1, -player, alpha, beta, i, j, boardValue)
-					bd[i][j] = UNSET
+					bd[i][j] = game.UNSET
 					if n < value {
 						value = n
 					}
@@ -288,115 +203,14 @@ func alphaBeta(bd *Board, ply int, player int, alpha int, beta int, x int, y int
 	return value
 }
 
-func printBoard(bd *Board) {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
-		}
-		fmt.Printf("\n")
-	}
-}
-
-var losingTriplets [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}},
-	[][]int{[]int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}},
-	[][]int{[]int{2, 0}, []int{1, 1}, []int{0, 2}},
-	[][]int{[]int{2, 0}, []int{3, 1}, []int{4, 2}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}},
-	[][]int{[]int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 2}, []int{1, 3}, []int{2, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 2}, []int{2, 3}, []int{1, 4}},
-	[][]int{[]int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 2}, []int{3, 3}, []int{2, 4}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{2, 4}, []int{3, 4}, []int{4, 4}},
-}
-var winningQuads [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}, []int{4, 4}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}, []int{1, 4}},
-}
-
 var scores [][]int = [][]int{
-/*
-	[]int{3, 3, 0, 3, 3},
-	[]int{3, 4, 1, 4, 3},
-	[]int{0, 1, 0, 1, 0},
-	[]int{3, 4, 1, 4, 3},
-	[]int{3, 3, 0, 3, 3},
-*/
+	/*
+		[]int{3, 3, 0, 3, 3},
+		[]int{3, 4, 1, 4, 3},
+		[]int{0, 1, 0, 1, 0},
+		[]int{3, 4, 1, 4, 3},
+		[]int{3, 3, 0, 3, 3},
+	*/
 	[]int{0, 0, 0, 0, 0},
 	[]int{0, 0, 0, 0, 0},
 	[]int{0, 0, 0, 0, 0},
@@ -404,7 +218,7 @@ var scores [][]int = [][]int{
 	[]int{0, 0, 0, 0, 0},
 }
 
-func readMove(bd *Board, printStuff bool) (x, y int) {
+func readMove(bd *game.Board, printStuff bool) (x, y int) {
 	readMove := false
 	for !readMove {
 		if printStuff {
@@ -434,23 +248,23 @@ func readMove(bd *Board, printStuff bool) (x, y int) {
 	return x, y
 }
 
-func maximizerMove(bd *Board, desiredDepth int) (int, int, int) {
+func maximizerMove(bd *game.Board, desiredDepth int) (int, int, int) {
 
 	var moves [25][2]int
 	var next int
 
 	maxDepth = desiredDepth
 
-	max := 2*LOSS  // A board can score less than LOSS
+	max := 2 * game.LOSS // A board can score less than LOSS
 
 	boardValue := wholeBoardValue(bd)
 
 	for i, row := range bd {
 		for j, mark := range row {
-			if mark == UNSET {
-				bd[i][j] = MAXIMIZER
-				val := alphaBeta(bd, 1, MINIMIZER, LOSS, WIN, i, j, boardValue)
-				bd[i][j] = UNSET
+			if mark == game.UNSET {
+				bd[i][j] = game.MAXIMIZER
+				val := alphaBeta(bd, 1, game.MINIMIZER, game.LOSS, game.WIN, i, j, boardValue)
+				bd[i][j] = game.UNSET
 				if val >= max {
 					if val > max {
 						max = val
@@ -472,23 +286,23 @@ func maximizerMove(bd *Board, desiredDepth int) (int, int, int) {
 	return moves[r][0], moves[r][1], max
 }
 
-func minimizerMove(bd *Board, desiredDepth int) (int, int, int) {
+func minimizerMove(bd *game.Board, desiredDepth int) (int, int, int) {
 
 	var moves [25][2]int
 	var next int
 
 	maxDepth = desiredDepth
 
-	min := 2*WIN
+	min := 2 * game.WIN
 
 	boardValue := wholeBoardValue(bd)
 
 	for i, row := range bd {
 		for j, mark := range row {
-			if mark == UNSET {
-				bd[i][j] = MINIMIZER
-				val := alphaBeta(bd, 1, MAXIMIZER, LOSS, WIN, i, j, boardValue)
-				bd[i][j] = UNSET
+			if mark == game.UNSET {
+				bd[i][j] = game.MINIMIZER
+				val := alphaBeta(bd, 1, game.MAXIMIZER, game.LOSS, game.WIN, i, j, boardValue)
+				bd[i][j] = game.UNSET
 				if val <= min {
 					if val < min {
 						min = val
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/three"
+)
+
+// rules decides what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does
+var rules game.Rules
+
+// shape is the size of the board, the lengths of the
+// lines that win and lose, and whether lines wrap around
+var shape *game.Shape
+
+func main() {
+
+	maxDepth := flag.Int("d", 5, "maximum lookahead depth, moves by any player (paranoid)")
+	deterministic := flag.Bool("D", false, "Play deterministically")
+	var types [three.Players]*string
+	for i := range types {
+		types[i] = flag.String(fmt.Sprint(i+1), "P", fmt.Sprintf("player %d (%s) type, P: paranoid alpha/beta, M: MCTS", i+1, three.Marks[i]))
+	}
+	gameCount := flag.Int("n", 1, "play <number> games non-interactively")
+	iterations := flag.Int("i", 100000, "MCTS iterations")
+	UCTK := flag.Float64("u", 0.50, "UCTK coefficient (MCTS)")
+	perMove := flag.Duration("t", 0, "time per move, paranoid deepens iteratively instead of -d, MCTS stops early")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	size := flag.String("size", "6x6", "board size, WxH or N for NxN")
+	winLength := flag.Int("win", 4, "this many in a row wins")
+	loseLength := flag.Int("lose", 3, "this many in a row loses")
+	torus := flag.Bool("torus", false, "lines wrap around the edges of the board")
+	flag.Parse()
+
+	var err error
+	if rules, err = game.ParseRules(*rulesName); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if shape, err = game.ParseShape(*size, *winLength, *loseLength, *torus); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var players [three.Players]three.Engine
+	for i, t := range types {
+		switch strings.ToUpper(*t) {
+		case "P":
+			players[i] = three.NewParanoid(*deterministic, *maxDepth)
+		case "M":
+			players[i] = three.NewMCTS(*iterations, *UCTK)
+		default:
+			fmt.Fprintf(os.Stderr, "unknown player type %q, want P or M\n", *t)
+			os.Exit(1)
+		}
+	}
+
+	if *gameCount > 1 {
+		nonInteractiveGames(*gameCount, players, *perMove)
+		return
+	}
+
+	gameStart := time.Now()
+	bd := playGame(players, *perMove, true)
+	gameET := time.Since(gameStart)
+
+	if winner := bd.Winner(); winner != three.NoPlayer {
+		fmt.Printf("player %d %s (%s) wins, %v\n", winner+1, three.Marks[winner], players[winner].Name(), gameET)
+	} else {
+		fmt.Printf("Draw, %v\n", gameET)
+	}
+	fmt.Printf("%v", &bd)
+}
+
+// chooseMove has player choose its next move,
+// cut off after perMove, if that's more than 0.
+func chooseMove(player three.Engine, perMove time.Duration) (engine.Move, engine.SearchInfo, error) {
+	ctx := context.Background()
+	if perMove > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, perMove)
+		defer cancel()
+	}
+	return player.ChooseMove(ctx)
+}
+
+// playGame plays one game between players, and returns
+// the referee's board at the end of it. If verbose, it prints
+// each move, each player going out, and the board after
+// each round of moves.
+func playGame(players [three.Players]three.Engine, perMove time.Duration, verbose bool) three.Board {
+	for _, player := range players {
+		player.SetGame(shape, rules)
+	}
+
+	// The referee's board decides who won, not any player
+	bd := three.NewBoard(shape, rules)
+	for !bd.Over() {
+		mover := bd.ToMove()
+
+		move, info, err := chooseMove(players[mover], perMove)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", players[mover].Name(), err)
+			os.Exit(1)
+		}
+
+		bd.MakeMove(shape.Cell(move.X, move.Y))
+		for i, player := range players {
+			if i != mover {
+				player.MakeMove(move.X, move.Y)
+			}
+		}
+
+		if !verbose {
+			continue
+		}
+		fmt.Printf("%s (%s) %v (%v) [%d] %v pv %v\n", three.Marks[mover], players[mover].Name(), move, info.Value, info.LeafCount, info.Elapsed, info.PV)
+		if bd.Out(mover) {
+			fmt.Printf("%s (%s) is out\n", three.Marks[mover], players[mover].Name())
+		}
+		if bd.Over() || bd.ToMove() <= mover {
+			fmt.Printf("%v\n", &bd)
+		}
+	}
+	return bd
+}
+
+// nonInteractiveGames plays gameCount games, printing
+// the winner of each, then how many games each player won.
+func nonInteractiveGames(gameCount int, players [three.Players]three.Engine, perMove time.Duration) {
+	var wins [three.Players]int
+	draws := 0
+	for i := 0; i < gameCount; i++ {
+		bd := playGame(players, perMove, false)
+		winner := bd.Winner()
+		mark := "draw"
+		if winner != three.NoPlayer {
+			wins[winner]++
+			mark = three.Marks[winner]
+		} else {
+			draws++
+		}
+		moves := 0
+		for player := 0; player < three.Players; player++ {
+			moves += bits.OnesCount64(bd.Marks(player))
+		}
+		fmt.Printf("%d %s %s %s %d %s\n", i, players[0].Name(), players[1].Name(), players[2].Name(), moves, mark)
+	}
+	for player, name := range players {
+		fmt.Printf("%s (%s) %d ", three.Marks[player], name.Name(), wins[player])
+	}
+	fmt.Printf("draws %d\n", draws)
+}
@@ -1,51 +1,187 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"math/bits"
 	"math/rand"
+	"os"
 	"strings"
 	"time"
 
 	"squava/src/abbook"
 	"squava/src/alphabeta"
+	"squava/src/clock"
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/lazysmp"
 	"squava/src/mcts"
 	"squava/src/negascout"
+	"squava/src/score"
+	"squava/src/tablebase"
+	"squava/src/threats"
+	"squava/src/ttable"
 )
 
-const (
-	MAXIMIZER = 1
-	MINIMIZER = -1
-)
+// TimeLimiter is an engine.Engine that can search for as long as
+// a time budget allows, rather than to a fixed depth
+type TimeLimiter interface {
+	SetTimeLimit(time.Duration)
+}
+
+// Tabler is an engine.Engine that has a transposition table
+type Tabler interface {
+	SetTable(*ttable.Table)
+	Table() *ttable.Table
+}
+
+// Tablebaser is an engine.Engine that can play perfectly
+// from a tablebase, late in the game
+type Tablebaser interface {
+	SetTablebase(*tablebase.Table)
+}
+
+// ThreatSearcher is an engine.Engine that looks for a forced
+// win by threats before it searches
+type ThreatSearcher interface {
+	SetThreatDepth(plies int)
+}
+
+// Quiescer is an engine.Engine that makes forced moves
+// past its search horizon
+type Quiescer interface {
+	SetQuiescence(plies int)
+	Extensions() int
+}
+
+// Orderer is an engine.Engine that counts which
+// of its moves cause cutoffs
+type Orderer interface {
+	Cutoffs() alphabeta.Cutoffs
+}
+
+// rules decides what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does
+var rules game.Rules
 
-type Player interface {
-	Name() string
-	MakeMove(int, int, int) // x,y coords, type of player (MINIMIZER, MAXIMIZER)
-	SetDepth(int)
-	ChooseMove() (int, int, int, int) // x,y coords of move, value, leaf node count
-	PrintBoard()
-	SetScores(bool)
-	FindWinner() int
+// shape is the size of the board, the lengths of the
+// lines that win and lose, and whether lines wrap around
+var shape = game.Standard
+
+// start has handicap marks already on the board, and the swap rule
+var start game.Start
+
+// threatPlies is how far ahead players look for forced wins by threats
+var threatPlies = threats.DefaultPlies
+
+// quiescence is how many forced moves past the horizon players make
+var quiescence = threats.DefaultQuiescence
+
+// threads is how many goroutines a lazy SMP player,
+// or a parallel MCTS player, searches with
+var threads = lazysmp.DefaultThreads
+
+// mctsOptions are the settings of an MCTS player
+type mctsOptions struct {
+	uctk       float64
+	iterations int
+	rave       float64
+	parallel   mcts.Parallel
 }
 
+// mcts1 and mcts2 are the settings of MCTS first and second players
+var mcts1, mcts2 mctsOptions
+
 func main() {
 
 	maxDepthPtr := flag.Int("d", 10, "maximum lookahead depth (alpha/beta)")
 	deterministic := flag.Bool("D", false, "Play deterministically")
 	randomizeScores := flag.Bool("r", false, "Randomize bias scores")
-	firstType := flag.String("1", "A", "first player type, A: alphabeta, N: negascout, B: A/B+book opening, G: A/B+avoid bad positions, M: MCTS")
-	secondType := flag.String("2", "M", "second player type, A: alphabeta, N: negascout, B: A/B+book opening, G: A/B+avoid bad positions, M: MCTS")
+	firstType := flag.String("1", "A", "first player type, A: alphabeta, N: negascout, B: A/B+book opening, G: A/B+avoid bad positions, M: MCTS, S: lazy SMP alphabeta")
+	secondType := flag.String("2", "M", "second player type, A: alphabeta, N: negascout, B: A/B+book opening, G: A/B+avoid bad positions, M: MCTS, S: lazy SMP alphabeta")
 	nonInteractive := flag.Int("n", 1, "play <number> games non-interactively")
-	u1 := flag.Float64("u1", 0.50, "UCTK coefficient, player 1 (MCTS)")
-	u2 := flag.Float64("u2", 0.50, "UCTK coefficient, player 2 (MCTS)")
-	i1 := flag.Int("i1", 500000, "MCTS iterations, player 1")
-	i2 := flag.Int("i2", 500000, "MCTS iterations, player 2")
+	flag.Float64Var(&mcts1.uctk, "u1", 0.50, "UCTK coefficient, player 1 (MCTS)")
+	flag.Float64Var(&mcts2.uctk, "u2", 0.50, "UCTK coefficient, player 2 (MCTS)")
+	flag.IntVar(&mcts1.iterations, "i1", 500000, "MCTS iterations, player 1")
+	flag.IntVar(&mcts2.iterations, "i2", 500000, "MCTS iterations, player 2")
+	flag.Float64Var(&mcts1.rave, "k1", 0, "RAVE equivalence parameter, player 1 (MCTS), 0 for plain UCT")
+	flag.Float64Var(&mcts2.rave, "k2", 0, "RAVE equivalence parameter, player 2 (MCTS), 0 for plain UCT")
+	ttMB := flag.Int("tt", ttable.DefaultMB, "transposition table megabytes (alpha/beta, negascout), 0 for none")
+	ttReplace := flag.String("ttr", "depth", "transposition table replacement policy, depth or always")
+	perMove := flag.Duration("t", 0, "time per move, alpha/beta and negascout deepen iteratively instead of -d, MCTS stops early")
+	gameTime := flag.Duration("T", 0, "total time per player per game, 0 for no game clock")
+	increment := flag.Duration("I", 0, "time added to game clock after each move")
+	tbFile := flag.String("tb", "", "tablebase file, from tbgen, for perfect play late in the game")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	size := flag.String("size", "5x5", "board size, WxH or N for NxN")
+	winLength := flag.Int("win", 4, "this many in a row wins")
+	loseLength := flag.Int("lose", 3, "this many in a row loses")
+	torus := flag.Bool("torus", false, "lines wrap around the edges of the board")
+	xMarks := flag.String("x", "", "X's marks already on the board, like \"2,2 1,3\"")
+	oMarks := flag.String("o", "", "O's marks already on the board, like \"2,2 1,3\"")
+	forbid := flag.String("forbid", "", "cells nobody can mark, like \"2,2 1,3\"")
+	swap := flag.Bool("swap", false, "after the first move, the second player can take over the first player's side")
+	flag.IntVar(&threads, "N", lazysmp.DefaultThreads, "goroutines searching for the lazy SMP player (S), and parallel MCTS players")
+	m1 := flag.String("m1", "serial", "MCTS parallelization, player 1: serial, root, tree or leaf")
+	m2 := flag.String("m2", "serial", "MCTS parallelization, player 2: serial, root, tree or leaf")
+	flag.IntVar(&quiescence, "q", threats.DefaultQuiescence, "most forced moves past the search horizon (alpha/beta, negascout), 0 for none")
+	flag.IntVar(&threatPlies, "threats", threats.DefaultPlies, "look this many plies ahead for forced wins by threats before searching (alpha/beta, negascout), 0 for none")
 	flag.Parse()
 
+	var err error
+	if rules, err = game.ParseRules(*rulesName); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if mcts1.parallel, err = mcts.ParseParallel(*m1); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if mcts2.parallel, err = mcts.ParseParallel(*m2); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if shape, err = game.ParseShape(*size, *winLength, *loseLength, *torus); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if shape, start, err = game.ParseStart(shape, *xMarks, *oMarks, *forbid, *swap); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if b := start.Board(shape, game.MAXIMIZER); rules.Winner(&b) != game.UNSET {
+		fmt.Fprintf(os.Stderr, "the marks already on the board decide the game\n")
+		os.Exit(1)
+	}
+
+	tc := timeControl{*perMove, *gameTime, *increment}
+
+	policy := ttable.DEPTH
+	if strings.ToLower(*ttReplace) == "always" {
+		policy = ttable.ALWAYS
+	}
+
+	var tb *tablebase.Table
+	if *tbFile != "" {
+		if tb, err = tablebase.Load(*tbFile); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *tbFile, err)
+			os.Exit(1)
+		}
+		if tb.Rules() != rules {
+			fmt.Fprintf(os.Stderr, "%s: tablebase for -rule %v, not %v\n", *tbFile, tb.Rules(), rules)
+			os.Exit(1)
+		}
+		if shape != game.Standard {
+			fmt.Fprintf(os.Stderr, "%s: tablebases are only for the standard 5x5 board\n", *tbFile)
+			os.Exit(1)
+		}
+	}
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	if *nonInteractive > 1 {
-		nonInteractiveGames(*nonInteractive, *firstType, *secondType, *randomizeScores, *maxDepthPtr)
+		nonInteractiveGames(*nonInteractive, *firstType, *secondType, *randomizeScores, *maxDepthPtr, *ttMB, policy, tc, tb)
 		return
 	}
 
@@ -53,59 +189,66 @@ func main() {
 
 	moveCounter := 0
 
+	// The referee's board decides who won, not either player
+	bd := start.Board(shape, game.MAXIMIZER)
+
 	first, second := createPlayers(*firstType,
 		*secondType, *maxDepthPtr, *deterministic)
 
-	if *firstType == "M" {
-		first.(*mcts.MCTS).SetUCTK(*u1)
-		first.(*mcts.MCTS).SetIterations(*i1)
-	}
-
-	if *secondType == "M" {
-		second.(*mcts.MCTS).SetUCTK(*u2)
-		second.(*mcts.MCTS).SetIterations(*i2)
-	}
+	setTables(first, second, *ttMB, policy)
+	setTablebases(first, second, tb)
+	firstClock, secondClock := tc.clocks()
 
 	first.SetScores(*randomizeScores)
 	second.SetScores(*randomizeScores)
+	setStarts(first, second)
 
 	gameStart := time.Now()
-	for moveCounter < 25 {
-
-		first.SetDepth(moveCounter)
+	for bd.Empty() != 0 {
 
-		before := time.Now()
-		i, j, value, leafCount := first.ChooseMove()
-		et := time.Since(before)
-		second.MakeMove(i, j, MINIMIZER)
+		move, info := chooseMove(first, firstClock, bd.Count(), bits.OnesCount64(bd.Empty()))
+		i, j := move.X, move.Y
+		second.MakeMove(i, j, game.MINIMIZER)
+		bd.MakeMove(shape.Cell(i, j), game.MAXIMIZER)
 
 		moveCounter++
-		fmt.Printf("X (%s) <%d,%d> (%d) [%d] %v\n", first.Name(), i, j, value, leafCount, et)
+		fmt.Printf("X (%s) %v (%v) [%d]%s %v pv %v\n", first.Name(), move, info.Value, info.LeafCount, hitRate(first)+extensions(first)+firstMoveRate(first), info.Elapsed, info.PV)
 
-		winner = first.FindWinner() // main() thinks first is maximizer
-		if winner != 0 || moveCounter >= 25 {
+		if firstClock.Flagged() {
+			fmt.Printf("X (%s) ran out of time\n", first.Name())
+			winner = game.MINIMIZER
 			break
 		}
 
-		second.SetDepth(moveCounter)
+		winner = rules.Winner(&bd)
+		if winner != game.UNSET || bd.Empty() == 0 {
+			break
+		}
 
-		before = time.Now()
-		i, j, value, leafCount = second.ChooseMove()
-		et = time.Since(before)
-		first.MakeMove(i, j, MINIMIZER)
+		if start.Swap && moveCounter == 1 && swapSides(first, second, move, bd.Count()) {
+			fmt.Printf("O (%s) swaps, and plays X from here on\n", second.Name())
+			first, second = second, first
+			firstClock, secondClock = secondClock, firstClock
+		}
 
-		moveCounter++
-		fmt.Printf("O (%s) <%d,%d> (%d) [%d] %v\n", second.Name(), i, j, value, leafCount, et)
+		move, info = chooseMove(second, secondClock, bd.Count(), bits.OnesCount64(bd.Empty()))
+		i, j = move.X, move.Y
+		first.MakeMove(i, j, game.MINIMIZER)
+		bd.MakeMove(shape.Cell(i, j), game.MINIMIZER)
 
-		first.PrintBoard()
+		moveCounter++
+		fmt.Printf("O (%s) %v (%v) [%d]%s %v pv %v\n", second.Name(), move, info.Value, info.LeafCount, hitRate(second)+extensions(second)+firstMoveRate(second), info.Elapsed, info.PV)
 
-		winner1 := first.FindWinner()
-		winner2 := -second.FindWinner() // main thinks second is minimizer
-		if winner1 != winner2 {
-			fmt.Printf("Winner disagreement. First %d, second %d\n", winner1, winner2)
+		if secondClock.Flagged() {
+			fmt.Printf("O (%s) ran out of time\n", second.Name())
+			winner = game.MAXIMIZER
+			break
 		}
-		if winner2 != 0 {
-			winner = winner2
+
+		fmt.Printf("%v\n", &bd)
+
+		winner = rules.Winner(&bd)
+		if winner != game.UNSET {
 			break
 		}
 
@@ -113,52 +256,74 @@ func main() {
 	gameET := time.Since(gameStart)
 
 	switch winner {
-	case 1:
+	case game.MAXIMIZER:
 		fmt.Printf("player 1 X (%s) wins, %v\n", first.Name(), gameET)
-	case -1:
+	case game.MINIMIZER:
 		fmt.Printf("player 2 O (%s) wins, %v\n", second.Name(), gameET)
 	default:
 		fmt.Printf("Cat wins\n")
 	}
 
-	first.PrintBoard()
+	fmt.Printf("%v", &bd)
 
 }
 
-func nonInteractiveGames(gameCount int, firstType, secondType string, randomize bool, maxDepth int) {
+func nonInteractiveGames(gameCount int, firstType, secondType string, randomize bool, maxDepth int, ttMB int, policy int, tc timeControl, tb *tablebase.Table) {
 
 	for i := 0; i < gameCount; i++ {
 		moveCounter := 0
 
 		first, second := createPlayers(firstType, secondType, maxDepth, randomize)
+		setTables(first, second, ttMB, policy)
+		setTablebases(first, second, tb)
+		setStarts(first, second)
+		firstClock, secondClock := tc.clocks()
 
 		fmt.Printf("%d %s %s %d %v ", i, first.Name(), second.Name(), maxDepth, randomize)
 
-		var moves [25][2]int
-		var values [25][2]int
+		var moves [game.MaxCells][2]int
+		var values [game.MaxCells][2]score.Score
 		var winner int
+		swapped := false
+		bd := start.Board(shape, game.MAXIMIZER)
 
-		for moveCounter < 25 {
+		for bd.Empty() != 0 {
 
-			first.SetDepth(moveCounter)
-			i, j, value, _ := first.ChooseMove()
+			move, info := chooseMove(first, firstClock, bd.Count(), bits.OnesCount64(bd.Empty()))
+			i, j := move.X, move.Y
 			moves[moveCounter][0], moves[moveCounter][1] = i, j
-			values[moveCounter][0] = value
-			second.MakeMove(i, j, MINIMIZER)
+			values[moveCounter][0] = info.Value
+			second.MakeMove(i, j, game.MINIMIZER)
+			bd.MakeMove(shape.Cell(i, j), game.MAXIMIZER)
 			moveCounter++
-			winner = first.FindWinner()
-			if winner != 0 || moveCounter >= 25 {
+			if firstClock.Flagged() {
+				winner = game.MINIMIZER
+				break
+			}
+			winner = rules.Winner(&bd)
+			if winner != game.UNSET || bd.Empty() == 0 {
 				break
 			}
 
-			second.SetDepth(moveCounter)
-			i, j, value, _ = second.ChooseMove()
+			if start.Swap && moveCounter == 1 && swapSides(first, second, move, bd.Count()) {
+				swapped = true
+				first, second = second, first
+				firstClock, secondClock = secondClock, firstClock
+			}
+
+			move, info = chooseMove(second, secondClock, bd.Count(), bits.OnesCount64(bd.Empty()))
+			i, j = move.X, move.Y
 			moves[moveCounter][0], moves[moveCounter][1] = i, j
-			values[moveCounter][1] = value
-			first.MakeMove(i, j, MINIMIZER)
+			values[moveCounter][1] = info.Value
+			first.MakeMove(i, j, game.MINIMIZER)
+			bd.MakeMove(shape.Cell(i, j), game.MINIMIZER)
 			moveCounter++
-			winner = -second.FindWinner() // main thinks second is minimizer
-			if winner != 0 {
+			if secondClock.Flagged() {
+				winner = game.MAXIMIZER
+				break
+			}
+			winner = rules.Winner(&bd)
+			if winner != game.UNSET {
 				break
 			}
 		}
@@ -168,26 +333,37 @@ func nonInteractiveGames(gameCount int, firstType, secondType string, randomize
 		for i := 0; i < moveCounter; i++ {
 			marker := [2]string{"", ""}
 			for j := 0; j < 2; j++ {
-				if values[i][j] > 9000 {
+				if values[i][j].IsWin() {
 					marker[j] = "+"
 				}
-				if values[i][j] < -9000 {
+				if values[i][j].IsLoss() {
 					marker[j] = "-"
 				}
 			}
 			fmt.Printf(" %d%s,%d%s", moves[i][0], marker[0], moves[i][1], marker[1])
 		}
+		if swapped {
+			fmt.Printf(" swapped")
+		}
 
 		fmt.Printf("\n")
 	}
 }
 
-func createPlayers(firstType, secondType string, maxDepth int, deterministic bool) (Player, Player) {
+// setMCTS gives an MCTS player its settings
+func setMCTS(player *mcts.MCTS, options mctsOptions) {
+	player.SetUCTK(options.uctk)
+	player.SetIterations(options.iterations)
+	player.SetRAVE(options.rave)
+	player.SetParallel(options.parallel, threads)
+}
+
+func createPlayers(firstType, secondType string, maxDepth int, deterministic bool) (engine.Engine, engine.Engine) {
 
 	firstType = strings.ToUpper(firstType)
 	secondType = strings.ToUpper(secondType)
 
-	var first, second Player
+	var first, second engine.Engine
 
 	switch firstType {
 	case "A":
@@ -201,6 +377,10 @@ func createPlayers(firstType, secondType string, maxDepth int, deterministic boo
 		first.(*alphabeta.AlphaBeta).SetAvoid()
 	case "M":
 		first = mcts.New(deterministic, maxDepth)
+		setMCTS(first.(*mcts.MCTS), mcts1)
+	case "S":
+		first = lazysmp.New(deterministic, maxDepth)
+		first.(*lazysmp.LazySMP).SetThreads(threads)
 	}
 
 	switch secondType {
@@ -215,7 +395,158 @@ func createPlayers(firstType, secondType string, maxDepth int, deterministic boo
 		second.(*alphabeta.AlphaBeta).SetAvoid()
 	case "M":
 		second = mcts.New(deterministic, maxDepth)
+		setMCTS(second.(*mcts.MCTS), mcts2)
+	case "S":
+		second = lazysmp.New(deterministic, maxDepth)
+		second.(*lazysmp.LazySMP).SetThreads(threads)
+	}
+
+	first.SetRules(rules)
+	second.SetRules(rules)
+
+	for _, player := range []engine.Engine{first, second} {
+		if t, ok := player.(ThreatSearcher); ok {
+			t.SetThreatDepth(threatPlies)
+		}
+		if q, ok := player.(Quiescer); ok {
+			q.SetQuiescence(quiescence)
+		}
+	}
+
+	if shape != game.Standard {
+		for _, player := range []engine.Engine{first, second} {
+			s, ok := player.(engine.Shaper)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "%s only plays on the standard 5x5 board\n", player.Name())
+				os.Exit(1)
+			}
+			s.SetShape(shape)
+		}
+	}
+
+	if start.Swap {
+		for _, player := range []engine.Engine{first, second} {
+			if _, ok := player.(engine.Swapper); !ok {
+				fmt.Fprintf(os.Stderr, "%s can't play by the swap rule\n", player.Name())
+				os.Exit(1)
+			}
+		}
 	}
 
 	return first, second
 }
+
+// setStarts puts the marks already on the board
+// on both players' boards, first player X.
+func setStarts(first, second engine.Engine) {
+	engine.SetStart(first, shape, start, game.MAXIMIZER)
+	engine.SetStart(second, shape, start, game.MINIMIZER)
+}
+
+// swapSides offers second the first player's first move, move,
+// under the swap rule, with marks marks on the board. If second
+// takes it, both players trade sides, and swapSides returns true.
+func swapSides(first, second engine.Engine, move engine.Move, marks int) bool {
+	second.SetDepth(marks)
+	if !engine.WantSwap(context.Background(), second, shape, move) {
+		return false
+	}
+	first.(engine.Swapper).SwapSides()
+	second.(engine.Swapper).SwapSides()
+	return true
+}
+
+// setTables gives players that have a transposition
+// table one of the size and replacement policy asked for.
+func setTables(first, second engine.Engine, ttMB int, policy int) {
+	for _, player := range []engine.Engine{first, second} {
+		if t, ok := player.(Tabler); ok {
+			if ttMB > 0 {
+				t.SetTable(ttable.New(ttMB, policy))
+			} else {
+				t.SetTable(nil)
+			}
+		}
+	}
+}
+
+// setTablebases gives players that can use a tablebase tb
+func setTablebases(first, second engine.Engine, tb *tablebase.Table) {
+	for _, player := range []engine.Engine{first, second} {
+		if t, ok := player.(Tablebaser); ok {
+			t.SetTablebase(tb)
+		}
+	}
+}
+
+// hitRate formats the transposition table hit rate of
+// player's last move, or nothing if player doesn't have a table.
+func hitRate(player engine.Engine) string {
+	if t, ok := player.(Tabler); ok && t.Table() != nil {
+		table := t.Table()
+		return fmt.Sprintf(" tt %d/%d %.1f%%", table.Hits, table.Probes, 100.*table.HitRate())
+	}
+	return ""
+}
+
+// extensions formats how many forced moves past the horizon
+// player's last search made, or nothing if player makes none.
+func extensions(player engine.Engine) string {
+	if q, ok := player.(Quiescer); ok && quiescence > 0 {
+		return fmt.Sprintf(" ext %d", q.Extensions())
+	}
+	return ""
+}
+
+// firstMoveRate formats the percentage of player's last
+// search's cutoffs that came from the first move tried,
+// or nothing if player doesn't count cutoffs.
+func firstMoveRate(player engine.Engine) string {
+	if o, ok := player.(Orderer); ok {
+		return fmt.Sprintf(" first %.1f%%", 100.*o.Cutoffs().FirstMoveRate())
+	}
+	return ""
+}
+
+// timeControl holds the time flags, so that each
+// game can start both players with fresh clocks.
+type timeControl struct {
+	perMove   time.Duration
+	total     time.Duration
+	increment time.Duration
+}
+
+func (tc timeControl) clocks() (*clock.Clock, *clock.Clock) {
+	return clock.New(tc.perMove, tc.total, tc.increment),
+		clock.New(tc.perMove, tc.total, tc.increment)
+}
+
+// chooseMove has player choose its next move, within the time
+// budget from its clock, if the clock is on, and charges the clock.
+// empty is the number of empty cells left on the board.
+// Players that can deepen their search get the budget as a time
+// limit, the others get cut off by the context when it runs out.
+func chooseMove(player engine.Engine, c *clock.Clock, moveCounter int, empty int) (engine.Move, engine.SearchInfo) {
+	player.SetDepth(moveCounter)
+
+	ctx := context.Background()
+	if c.Enabled() {
+		budget := c.Budget(empty)
+		if t, ok := player.(TimeLimiter); ok {
+			t.SetTimeLimit(budget)
+		} else {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, budget)
+			defer cancel()
+		}
+	}
+
+	move, info, err := player.ChooseMove(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", player.Name(), err)
+		os.Exit(1)
+	}
+	c.Used(info.Elapsed)
+
+	return move, info
+}
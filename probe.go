@@ -1,475 +1,230 @@
 package main
 
+/*
+ * Multi-PV analysis of a squava position: value every move the
+ * player to move could make, and show the best ones, each with the
+ * line of play (principal variation) the search expects after it.
+ *
+ * ./probe 6                      # empty board, 6 moves deep
+ * ./probe 8 1,1 3,3 1,3          # position as moves, X first
+ * ./probe -n 3 8 1,1 3,3 1,3     # just the 3 best moves
+ * ./probe -j 8 1,1 3,3 1,3       # JSON, for feeding to notebooks
+ *
+ * Values are from the point of view of the player to move, bigger
+ * is better. A value that a forced win or loss decided shows up
+ * as "win in k" or "loss in k", k counting both players' moves,
+ * other values are the board valuation's, like "+31" or "-63".
+ * Control-C stops the search, and shows the deepest one finished.
+ */
+
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
-	"time"
-)
-
-type Board [5][5]int
 
-const WIN = 10000
-const LOSS = -10000
-const MAXIMIZER = 1
-const MINIMIZER = -1
-const UNSET = 0
+	"squava/src/alphabeta"
+	"squava/src/game"
+	"squava/src/ttable"
+)
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes alphaBeta() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
+// analysis is the JSON output. Moves are [x,y] pairs.
+type analysis struct {
+	Moves     [][2]int       `json:"moves"`
+	ToMove    string         `json:"to_move"`
+	Engine    string         `json:"engine"`
+	Depth     int            `json:"depth"`
+	Stopped   bool           `json:"stopped"`
+	LeafNodes int            `json:"leaf_nodes"`
+	Seconds   float64        `json:"seconds"`
+	Lines     []analysisLine `json:"lines"`
+}
 
-var maxDepth int
-var leafNodes int
+type analysisLine struct {
+	Move    [2]int   `json:"move"`
+	Value   int      `json:"value"`
+	Outcome string   `json:"outcome,omitempty"` // "win" or "loss", if forced
+	Plies   int      `json:"plies,omitempty"`   // until the forced win or loss
+	PV      [][2]int `json:"pv"`
+}
 
 func main() {
-
-	if len(os.Args) < 2 {
-		fmt.Printf("./probe depth [m,n [m,n ...]]\n")
+	count := flag.Int("n", 0, "show only the n best moves, 0 for all of them")
+	jsonOutput := flag.Bool("j", false, "JSON output")
+	plain := flag.Bool("A", false, "plain alpha/beta valuation, instead of avoiding bad positions")
+	ttMB := flag.Int("tt", ttable.DefaultMB, "transposition table megabytes, 0 for none")
+	timeLimit := flag.Duration("t", 0, "search deeper and deeper for this long, instead of to depth")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s [flags] depth [m,n [m,n ...]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Set up for use by value-calculation section of alphaBeta()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
+	rules, err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 
-	maxDepth, _ = strconv.Atoi(os.Args[1])
-
-	var bd Board
+	maxDepth, err := strconv.Atoi(flag.Arg(0))
+	if err != nil || maxDepth < 1 {
+		fmt.Fprintf(os.Stderr, "Bad depth %q\n", flag.Arg(0))
+		os.Exit(1)
+	}
 
 	var moveSequence [][2]int
-	var nextPly int
-	var nextPlayer int = MAXIMIZER
-	var cell [2]int
-
-	for _, str := range os.Args[2:] {
+	for _, str := range flag.Args()[1:] {
 		mn := strings.Split(str, ",")
-		m, _ := strconv.Atoi(mn[0])
-		n, _ := strconv.Atoi(mn[1])
-		cell[0], cell[1] = m, n
-		moveSequence = append(moveSequence, cell)
-		bd[m][n] = nextPlayer
-		nextPly++
+		if len(mn) != 2 {
+			fmt.Fprintf(os.Stderr, "Bad move %q, want m,n\n", str)
+			os.Exit(1)
+		}
+		m, err1 := strconv.Atoi(mn[0])
+		n, err2 := strconv.Atoi(mn[1])
+		if err1 != nil || err2 != nil || m < 0 || m > 4 || n < 0 || n > 4 {
+			fmt.Fprintf(os.Stderr, "Bad move %q, want m,n from 0 to 4\n", str)
+			os.Exit(1)
+		}
+		moveSequence = append(moveSequence, [2]int{m, n})
+	}
+
+	player := alphabeta.New(true, maxDepth)
+	if !*plain {
+		player.SetAvoid()
+	}
+	player.SetScores(false)
+	player.SetTable(nil)
+	if *ttMB > 0 {
+		player.SetTable(ttable.New(*ttMB, ttable.DEPTH))
+	}
+	player.SetTimeLimit(*timeLimit)
+	player.SetRules(rules)
+
+	// The engine chooses moves for MAXIMIZER, so give MAXIMIZER the
+	// next move by flipping the marks if needed. The referee's
+	// board keeps X as the first player, for printing.
+	var bd game.Board
+	nextPlayer := game.MAXIMIZER
+	enginePlayer := game.MAXIMIZER
+	if len(moveSequence)%2 == 1 {
+		enginePlayer = game.MINIMIZER
+	}
+	for _, move := range moveSequence {
+		if bd[move[0]][move[1]] != game.UNSET {
+			fmt.Fprintf(os.Stderr, "<%d,%d> already taken\n", move[0], move[1])
+			os.Exit(1)
+		}
+		bd[move[0]][move[1]] = nextPlayer
+		player.MakeMove(move[0], move[1], enginePlayer)
 		nextPlayer = -nextPlayer
+		enginePlayer = -enginePlayer
 	}
-
-	fmt.Printf("next ply: %d\nnext player: %d\n", nextPly, nextPlayer)
-	player := MAXIMIZER
-	for ply, cell := range moveSequence {
-		fmt.Printf("Ply %d, player %d, move <%d,%d>\n",
-			ply, player, cell[0], cell[1])
-		player = -player
-	}
-	fmt.Printf("\n\n")
-
-	printBoard(&bd)
-
-	var bestValue int
-	var bestMoves [25][2]int
-	var bestNext int
-	var totalLeafNodes int
-
-	switch nextPlayer {
-	case MAXIMIZER:
-		bestValue = 2 * LOSS
-	case MINIMIZER:
-		bestValue = 2 * WIN
-	}
-
-	for i, row := range bd {
-		for j, mark := range row {
-			if mark == UNSET {
-				bd[i][j] = nextPlayer
-				stopRecursing, val := deltaValue(maxDepth, &bd, 0, i, j, bestValue)
-				leafNodes = 0
-				before := time.Now()
-				if !stopRecursing {
-					val, leafNodes = alphaBeta(maxDepth, &bd, 0, -nextPlayer, 2*LOSS, 2*WIN, i, j, val)
-				} else {
-					leafNodes = 1
-				}
-				switch nextPlayer {
-				case MAXIMIZER:
-					if val >= bestValue {
-						if val > bestValue {
-							bestValue = val
-							bestNext = 0
-						}
-						bestMoves[bestNext][0] = i
-						bestMoves[bestNext][1] = j
-						bestNext++
-					}
-				case MINIMIZER:
-					if val <= bestValue {
-						if val < bestValue {
-							bestValue = val
-							bestNext = 0
-						}
-						bestMoves[bestNext][0] = i
-						bestMoves[bestNext][1] = j
-						bestNext++
-					}
-				}
-				after := time.Now()
-				bd[i][j] = UNSET
-				totalLeafNodes += leafNodes
-				fmt.Printf("<%d,%d>\t%d [%d]\t%v\n", i, j, val, leafNodes, after.Sub(before))
-			}
-		}
+	if winner := rules.BoardWinner(&bd); winner != game.UNSET {
+		fmt.Fprintf(os.Stderr, "Game already over\n")
+		os.Exit(1)
 	}
 
-	fmt.Printf("Best move(s), have value %d:\n", bestValue)
-	for i := 0; i < bestNext; i++ {
-		fmt.Printf("\t<%d,%d>\n", bestMoves[i][0], bestMoves[i][1])
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	lines, info, err := player.Analyze(ctx, *count)
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", player.Name(), err)
+		os.Exit(1)
 	}
-	fmt.Printf("%d total leafnodes\n", totalLeafNodes)
 
-	fmt.Printf("%s  %d  ", os.Args[0], maxDepth)
-	for _, cell := range moveSequence {
-		fmt.Printf(" %d,%d", cell[0], cell[1])
+	toMove := "X"
+	if nextPlayer == game.MINIMIZER {
+		toMove = "O"
 	}
-	fmt.Printf(" %d,%d", bestMoves[0][0], bestMoves[0][1])
-	fmt.Printf("\n")
-
-	bd[bestMoves[0][0]][bestMoves[0][1]] = nextPlayer
 
-	printBoard(&bd)
-	fmt.Printf("\n")
-
-	os.Exit(0)
-}
-
-// Calculates and returns the value of the move (x,y)
-// Only considers value gained or lost from the cell (x,y)
-func deltaValue(maxPlies int, bd *Board, ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
-
-	relevantQuads := indexedWinningQuads[x][y]
-	for _, quad := range relevantQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return true, bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-		}
-		if sum == 3 || sum == -3 {
-			value += sum * 10
+	if *jsonOutput {
+		a := analysis{
+			Moves:     moveSequence,
+			ToMove:    toMove,
+			Engine:    player.Name(),
+			Depth:     info.Depth,
+			Stopped:   info.Stopped,
+			LeafNodes: info.LeafCount,
+			Seconds:   info.Elapsed.Seconds(),
+			Lines:     []analysisLine{},
 		}
-	}
-
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return true, -sum / 3 * (WIN - ply)
+		if a.Moves == nil {
+			a.Moves = [][2]int{}
 		}
-	}
-
-	for _, triplet := range no2 {
-		for _, pair := range triplet {
-			if x == pair[0] && y == pair[1] {
-				sum := bd[triplet[0][0]][triplet[0][1]]
-				sum += bd[triplet[1][0]][triplet[1][1]]
-				sum += bd[triplet[2][0]][triplet[2][1]]
-				if sum == 2 || sum == -2 {
-					value += bd[x][y] * -100
-				}
-				break
+		for _, line := range lines {
+			l := analysisLine{
+				Move:  [2]int{line.Move.X, line.Move.Y},
+				Value: int(line.Value),
+				Plies: line.Value.Plies(),
 			}
-		}
-	}
-
-	for _, quad := range noMiddle2 {
-		player := bd[x][y]
-		if (x == quad[1][0] && y == quad[1][1] && player == bd[quad[2][0]][quad[2][1]]) ||
-			(x == quad[2][0] && y == quad[2][1] && player == bd[quad[1][0]][quad[1][1]]) {
-
-			sum := bd[quad[0][0]][quad[0][1]]
-			sum += bd[quad[1][0]][quad[1][1]]
-			sum += bd[quad[2][0]][quad[2][1]]
-			sum += bd[quad[3][0]][quad[3][1]]
-
-			if sum == 2 || sum == -2 {
-				value += player * -100
+			switch {
+			case line.Value.IsWin():
+				l.Outcome = "win"
+			case line.Value.IsLoss():
+				l.Outcome = "loss"
 			}
-		}
-	}
-
-	// Give it a slight bias for those early
-	// moves when all losing-triplets and winning-quads
-	// are beyond the horizon.
-	value += bd[x][y] * scores[x][y]
-
-	// If squava has a "cat game", then this is wrong. Cat
-	// games could stop recursing here.
-	stopRecursing = false
-	if ply == maxPlies {
-		stopRecursing = true
-		value += currentValue
-	}
-
-	return stopRecursing, value
-}
-
-// It turns out that you only have to look at
-// the 4-in-a-rows that contain these 9 cells
-// to check every 4-in-a-row. Similarly, you
-// only need to check these 9 cells to check
-// all the losing 3-in-a-row combos. You don't
-// have to look at each and every cell.
-var checkableCells [9][2]int = [9][2]int{
-	{0, 2}, {1, 2}, {2, 0},
-	{2, 1}, {2, 2}, {2, 3},
-	{2, 4}, {3, 2}, {4, 2},
-}
-
-// player makes move in this invocation.
-// previous player (-player) just made the move <x,y>,
-// bd has value boardValue.
-func alphaBeta(maxDepth int, bd *Board, ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int, leafNodes int) {
-
-	leafNodes = 0
-
-	switch player {
-	case MAXIMIZER:
-		value = 2 * LOSS // Possible to score less than LOSS
-		for i, row := range bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					bd[i][j] = MAXIMIZER
-					stopRecursing, delta := deltaValue(maxDepth, bd, ply, x, y, boardValue)
-					if stopRecursing {
-						bd[i][j] = UNSET
-						return delta, leafNodes + 1
-					}
-					n, leaves := alphaBeta(maxDepth, bd, ply+1, MINIMIZER, alpha, beta, i, j, boardValue+delta)
-					bd[i][j] = UNSET
-					leafNodes += leaves
-					if n > value {
-						value = n
-					}
-					if value > alpha {
-						alpha = value
-					}
-					if beta <= alpha {
-						return value, leafNodes + 1
-					}
-				}
+			for _, m := range line.PV {
+				l.PV = append(l.PV, [2]int{m.X, m.Y})
 			}
+			a.Lines = append(a.Lines, l)
 		}
-	case MINIMIZER:
-		value = 2 * WIN // You can score greater than WIN
-		for i, row := range bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					bd[i][j] = player
-					stopRecursing, delta := deltaValue(maxDepth, bd, ply, x, y, boardValue)
-					if stopRecursing {
-						bd[i][j] = UNSET
-						return delta, leafNodes + 1
-					}
-					n, leaves := alphaBeta(maxDepth, bd, ply+1, -player, alpha, beta, i, j, boardValue+delta)
-					bd[i][j] = UNSET
-					leafNodes += leaves
-					if n < value {
-						value = n
-					}
-					if value < beta {
-						beta = value
-					}
-					if beta <= alpha {
-						return value, leafNodes + 1
-					}
-				}
-			}
+		enc := json.NewEncoder(os.Stdout)
+		if err := enc.Encode(a); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 
-	return value, leafNodes
-}
-
-func printBoard(bd *Board) {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
+	fmt.Printf("next ply: %d\nnext player: %s\n", len(moveSequence), toMove)
+	mark := "X"
+	for ply, cell := range moveSequence {
+		fmt.Printf("Ply %d, %s, move <%d,%d>\n", ply, mark, cell[0], cell[1])
+		if mark == "X" {
+			mark = "O"
+		} else {
+			mark = "X"
 		}
-		fmt.Printf("\n")
 	}
-}
-
-// 4-in-a-row where you don't want to have the middle 2
-var noMiddle2 = [4][4][2]int{
-	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
-	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
-	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
-	{{1, 4}, {2, 3}, {3, 2}, {4, 1}},
-}
-
-// 3-in-a-row where you don't want any 2 plus a blank
-var no2 = [4][3][2]int{
-
-	{{2, 0}, {1, 1}, {0, 2}},
-	{{0, 2}, {1, 3}, {2, 4}},
-	{{4, 2}, {3, 3}, {2, 4}},
-	{{4, 2}, {3, 1}, {2, 0}},
-}
-
-var losingTriplets [][][]int = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}},
-	{{0, 0}, {0, 1}, {0, 2}},
-	{{0, 0}, {1, 1}, {2, 2}},
-	{{1, 0}, {2, 0}, {3, 0}},
-	{{1, 0}, {1, 1}, {1, 2}},
-	{{1, 0}, {2, 1}, {3, 2}},
-	{{2, 0}, {3, 0}, {4, 0}},
-	{{2, 0}, {2, 1}, {2, 2}},
-	{{2, 0}, {1, 1}, {0, 2}},
-	{{2, 0}, {3, 1}, {4, 2}},
-	{{3, 0}, {3, 1}, {3, 2}},
-	{{3, 0}, {2, 1}, {1, 2}},
-	{{4, 0}, {4, 1}, {4, 2}},
-	{{4, 0}, {3, 1}, {2, 2}},
-	{{0, 1}, {1, 1}, {2, 1}},
-	{{0, 1}, {0, 2}, {0, 3}},
-	{{0, 1}, {1, 2}, {2, 3}},
-	{{1, 1}, {2, 1}, {3, 1}},
-	{{1, 1}, {1, 2}, {1, 3}},
-	{{1, 1}, {2, 2}, {3, 3}},
-	{{2, 1}, {3, 1}, {4, 1}},
-	{{2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {1, 2}, {0, 3}},
-	{{2, 1}, {3, 2}, {4, 3}},
-	{{3, 1}, {3, 2}, {3, 3}},
-	{{3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}},
-	{{4, 1}, {3, 2}, {2, 3}},
-	{{0, 2}, {1, 2}, {2, 2}},
-	{{0, 2}, {0, 3}, {0, 4}},
-	{{0, 2}, {1, 3}, {2, 4}},
-	{{1, 2}, {2, 2}, {3, 2}},
-	{{1, 2}, {1, 3}, {1, 4}},
-	{{1, 2}, {2, 3}, {3, 4}},
-	{{2, 2}, {3, 2}, {4, 2}},
-	{{2, 2}, {2, 3}, {2, 4}},
-	{{2, 2}, {1, 3}, {0, 4}},
-	{{2, 2}, {3, 3}, {4, 4}},
-	{{3, 2}, {3, 3}, {3, 4}},
-	{{3, 2}, {2, 3}, {1, 4}},
-	{{4, 2}, {4, 3}, {4, 4}},
-	{{4, 2}, {3, 3}, {2, 4}},
-	{{0, 3}, {1, 3}, {2, 3}},
-	{{1, 3}, {2, 3}, {3, 3}},
-	{{2, 3}, {3, 3}, {4, 3}},
-	{{0, 4}, {1, 4}, {2, 4}},
-	{{1, 4}, {2, 4}, {3, 4}},
-	{{2, 4}, {3, 4}, {4, 4}},
-}
-var winningQuads [][][]int = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
-	{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
-	{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
-	{{0, 1}, {1, 1}, {2, 1}, {3, 1}},
-	{{0, 1}, {0, 2}, {0, 3}, {0, 4}},
-	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
-	{{0, 2}, {1, 2}, {2, 2}, {3, 2}},
-	{{0, 3}, {1, 3}, {2, 3}, {3, 3}},
-	{{0, 4}, {1, 4}, {2, 4}, {3, 4}},
-	{{1, 0}, {2, 0}, {3, 0}, {4, 0}},
-	{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
-	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
-	{{1, 1}, {2, 1}, {3, 1}, {4, 1}},
-	{{1, 1}, {1, 2}, {1, 3}, {1, 4}},
-	{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
-	{{1, 2}, {2, 2}, {3, 2}, {4, 2}},
-	{{1, 3}, {2, 3}, {3, 3}, {4, 3}},
-	{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
-	{{2, 0}, {2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {2, 2}, {2, 3}, {2, 4}},
-	{{3, 0}, {3, 1}, {3, 2}, {3, 3}},
-	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
-	{{3, 1}, {3, 2}, {3, 3}, {3, 4}},
-	{{3, 1}, {2, 2}, {1, 3}, {0, 4}},
-	{{4, 0}, {4, 1}, {4, 2}, {4, 3}},
-	{{4, 0}, {3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}, {4, 4}},
-	{{4, 1}, {3, 2}, {2, 3}, {1, 4}},
-}
-
-var uniqueCells [][]int = [][]int{
-	{0, 0},
-	{0, 1},
-	{0, 2},
-	{1, 1},
-	{1, 2},
-	{2, 2},
-}
-
-var orderedCells [25][2]int = [25][2]int{
-	{1, 1},
-	{1, 3},
-	{3, 1},
-	{3, 3},
-
-	{2, 2},
+	fmt.Printf("\n\n")
 
-	{1, 2},
-	{2, 1},
-	{2, 3},
-	{3, 2},
+	bd.Print()
 
-	{0, 1},
-	{0, 2},
-	{0, 3},
+	for _, line := range lines {
+		fmt.Printf("%v\t%v\tpv %v\n", line.Move, line.Value, line.PV)
+	}
+	stopped := ""
+	if info.Stopped {
+		stopped = ", stopped"
+	}
+	fmt.Printf("%s, depth %d%s, %d leaf nodes, %v\n", player.Name(), info.Depth, stopped, info.LeafCount, info.Elapsed)
 
-	{1, 0},
-	{2, 0},
-	{3, 0},
+	if len(lines) == 0 {
+		os.Exit(0)
+	}
 
-	{1, 4},
-	{2, 4},
-	{3, 4},
+	// A command to probe the position after the best move
+	best := lines[0].Move
+	fmt.Printf("%s  %d  ", os.Args[0], maxDepth)
+	for _, cell := range moveSequence {
+		fmt.Printf(" %d,%d", cell[0], cell[1])
+	}
+	fmt.Printf(" %d,%d\n", best.X, best.Y)
 
-	{4, 1},
-	{4, 2},
-	{4, 3},
+	bd[best.X][best.Y] = nextPlayer
 
-	{0, 0},
-	{0, 4},
-	{4, 0},
-	{4, 4},
-}
+	bd.Print()
+	fmt.Printf("\n")
 
-var scores [][]int = [][]int{
-	{3, 3, 0, 3, 3},
-	{3, 4, 1, 4, 3},
-	{0, 1, 0, 1, 0},
-	{3, 4, 1, 4, 3},
-	{3, 3, 0, 3, 3},
+	os.Exit(0)
 }
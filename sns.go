@@ -7,26 +7,25 @@ import (
 	"math/rand"
 	"os"
 	"time"
-)
-
-type Board [5][5]int
 
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
+	"squava/src/clock"
+	"squava/src/game"
+	"squava/src/score"
 )
 
 var leafNodeCount int = 0
 var maxDepth int = 10 // initializing to 10 not a mistake
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
+// Time control: when timeLimited, negaScout gives up
+// after deadline, and sets stopped.
+var timeLimited bool
+var deadline time.Time
+var stopped bool
+var nodeCount int
+
+// rules decides what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does
+var rules game.Rules
 
 func main() {
 
@@ -37,23 +36,22 @@ func main() {
 	printBoardPtr := flag.Bool("n", false, "Don't print board, just emit moves")
 	firstMovePtr := flag.String("M", "", "Tell computer to make this first move (x,y)")
 	randomizeScores := flag.Bool("r", false, "Randomize bias scores")
+	perMove := flag.Duration("t", 0, "time per move, iterative deepening instead of -d")
+	gameTime := flag.Duration("T", 0, "total time for computer's moves, 0 for no game clock")
+	increment := flag.Duration("I", 0, "time added to game clock after each move")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
 	flag.Parse()
 
-	*printBoardPtr = !*printBoardPtr
-
-	// Set up for use by staticValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
+	var err error
+	if rules, err = game.ParseRules(*rulesName); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 
+	computerClock := clock.New(*perMove, *gameTime, *increment)
+
+	*printBoardPtr = !*printBoardPtr
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	setScores(*randomizeScores)
@@ -63,15 +61,15 @@ func main() {
 		humanFirst = false
 	}
 
-	var bd Board
+	var bd game.Board
 
 	if *firstMovePtr != "" {
 		var x1, y1 int
 		fmt.Sscanf(*firstMovePtr, "%d,%d", &x1, &y1)
 		fmt.Printf("My move: %d %d\n", x1, y1)
 		humanFirst = true
-		bd[x1][y1] = MAXIMIZER
-		printBoard(&bd)
+		bd[x1][y1] = game.MAXIMIZER
+		bd.Print()
 	}
 
 	var endOfGame bool = false
@@ -84,7 +82,7 @@ func main() {
 		var l, m int
 		if humanFirst {
 			l, m = readMove(&bd, *printBoardPtr)
-			bd[l][m] = MINIMIZER
+			bd[l][m] = game.MINIMIZER
 			endOfGame, _ = staticValue(&bd, 0)
 			moveCounter++
 		}
@@ -96,18 +94,25 @@ func main() {
 		humanFirst = true
 
 		leafNodeCount = 0
-		a, b, score := chooseMove(&bd, *deterministic)
+		var a, b, value int
+		if computerClock.Enabled() {
+			start := time.Now()
+			a, b, value = timedChooseMove(&bd, *deterministic, computerClock.Budget(len(bd.Moves())))
+			computerClock.Used(time.Since(start))
+		} else {
+			a, b, value = chooseMove(&bd, *deterministic)
+		}
 
 		if a < 0 {
 			break // Cat gets the game
 		}
 
-		bd[a][b] = MAXIMIZER
+		bd[a][b] = game.MAXIMIZER
 		moveCounter++
 
 		if *printBoardPtr {
-			fmt.Printf("My move: %d %d (%d) [%d]\n", a, b, score, leafNodeCount)
-			printBoard(&bd)
+			fmt.Printf("My move: %d %d (%v) [%d]\n", a, b, score.Score(value), leafNodeCount)
+			bd.Print()
 		} else {
 			fmt.Printf("%d %d\n", a, b)
 		}
@@ -118,17 +123,17 @@ func main() {
 
 	if *printBoardPtr {
 		var phrase string
-		switch findWinner(&bd) {
-		case MAXIMIZER:
+		switch rules.BoardWinner(&bd) {
+		case game.MAXIMIZER:
 			phrase = "\nX wins\n"
-		case UNSET:
+		case game.UNSET:
 			phrase = "\nCat wins\n"
-		case MINIMIZER:
+		case game.MINIMIZER:
 			phrase = "\nO wins\n"
 		}
-		fmt.Printf(phrase)
+		fmt.Print(phrase)
 
-		printBoard(&bd)
+		bd.Print()
 	}
 
 	os.Exit(0)
@@ -158,16 +163,16 @@ var initialOrderedMoves [25][2]int = [25][2]int{
 
 var orderedMoves [3][][2]int
 
-func reorderMoves(bd *Board) {
+func reorderMoves(bd *game.Board) {
 	var goodCells [][2]int
 	var badCells [][2]int
 	var dullCells [][2]int
 	unsetCount := 0
 	for _, cell := range initialOrderedMoves {
-		if bd[cell[0]][cell[1]] == UNSET {
+		if bd[cell[0]][cell[1]] == game.UNSET {
 			unsetCount++
 			interesting := false
-			relevantQuads := indexedWinningQuads[cell[0]][cell[1]]
+			relevantQuads := game.IndexedWinningQuads[cell[0]][cell[1]]
 			for _, quad := range relevantQuads {
 				sum := bd[quad[0][0]][quad[0][1]]
 				sum += bd[quad[1][0]][quad[1][1]]
@@ -198,7 +203,7 @@ func reorderMoves(bd *Board) {
 				}
 			}
 			if !interesting {
-				relevantTriplets := indexedLosingTriplets[cell[0]][cell[1]]
+				relevantTriplets := game.IndexedLosingTriplets[cell[0]][cell[1]]
 				for _, triplet := range relevantTriplets {
 					sum := bd[triplet[0][0]][triplet[0][1]]
 					sum += bd[triplet[1][0]][triplet[1][1]]
@@ -231,30 +236,33 @@ func reorderMoves(bd *Board) {
 	orderedMoves[2] = goodCells
 }
 
-func chooseMove(bd *Board, deterministic bool) (int, int, int) {
+func chooseMove(bd *game.Board, deterministic bool) (int, int, int) {
 
-	var moves = MoveKeeper{next: 0, max: 3 * LOSS}
+	var moves = MoveKeeper{next: 0, max: 3 * game.LOSS}
 
 	reorderMoves(bd)
 
-	beta := 2 * WIN
-	alpha := 2 * LOSS
-	score := 3 * LOSS
+	beta := 2 * game.WIN
+	alpha := 2 * game.LOSS
+	score := 3 * game.LOSS
 	n := beta
 
 	for _, cell := range orderedMoves[2] {
 		i, j := cell[0], cell[1]
-		if bd[i][j] == UNSET {
-			bd[i][j] = MAXIMIZER
-			cur := -negaScout(bd, 1, MINIMIZER, -n, -alpha)
+		if bd[i][j] == game.UNSET {
+			bd[i][j] = game.MAXIMIZER
+			cur := -negaScout(bd, 1, game.MINIMIZER, -n, -alpha)
 			if cur > score {
 				if n == beta {
 					score = cur
 				} else {
-					score = -negaScout(bd, 1, MINIMIZER, -beta, -cur)
+					score = -negaScout(bd, 1, game.MINIMIZER, -beta, -cur)
 				}
 			}
-			bd[i][j] = UNSET
+			bd[i][j] = game.UNSET
+			if stopped {
+				return -1, -1, 0
+			}
 			if score > alpha {
 				alpha = score
 			}
@@ -269,29 +277,61 @@ func chooseMove(bd *Board, deterministic bool) (int, int, int) {
 	return moves.chooseMove(deterministic)
 }
 
-func findWinner(bd *Board) int {
-	for _, quad := range winningQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return bd[quad[0][0]][quad[0][1]]
+// timedChooseMove deepens the search one ply at a time until
+// limit runs out, and returns the move found by the deepest
+// search that finished.
+func timedChooseMove(bd *game.Board, deterministic bool, limit time.Duration) (x, y, value int) {
+	start := time.Now()
+	deadline = start.Add(limit)
+	timeLimited = true
+	stopped = false
+	nodeCount = 0
+	defer func() { timeLimited = false }()
+
+	empty := 0
+	for _, row := range bd {
+		for _, mark := range row {
+			if mark == game.UNSET {
+				empty++
+			}
 		}
 	}
 
-	for _, triplet := range losingTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -bd[triplet[0][0]][triplet[0][1]]
+	// Depth 0 only values the computer's own moves, and
+	// never runs out of time, so there's always a move.
+	x, y = -1, -1
+	for depth := 0; depth < empty; depth++ {
+		maxDepth = depth
+		a, b, v := chooseMove(bd, deterministic)
+		if stopped {
+			break
+		}
+		x, y, value = a, b, v
+		if score.Score(value).Decided() {
+			break // forced win or loss, deeper won't change it
+		}
+		if time.Since(start) > limit/2 {
+			break // next depth would take longer than what's left
 		}
 	}
 
-	return 0
+	return x, y, value
+}
+
+// timeUp checks the clock every so often during
+// a timed search, and stops the search after deadline.
+func timeUp() bool {
+	if !timeLimited {
+		return false
+	}
+	if stopped {
+		return true
+	}
+	nodeCount++
+	if nodeCount&1023 == 0 && time.Now().After(deadline) {
+		stopped = true
+	}
+	return stopped
 }
 
 var deadlyQuads [4][4][2]int = [4][4][2]int{
@@ -301,52 +341,28 @@ var deadlyQuads [4][4][2]int = [4][4][2]int{
 	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
 }
 
-// It turns out that you only have to look at
-// the 4-in-a-rows that contain these 9 cells
-// to check every 4-in-a-row. Similarly, you
-// only need to check these 9 cells to check
-// all the losing 3-in-a-row combos. You don't
-// have to look at each and every cell.
-var checkableCells [9][2]int = [9][2]int{
-	{0, 2}, {1, 2}, {2, 0},
-	{2, 1}, {2, 2}, {2, 3},
-	{2, 4}, {3, 2}, {4, 2},
-}
-
-func staticValue(bd *Board, ply int) (stopRecursing bool, value int) {
+func staticValue(bd *game.Board, ply int) (stopRecursing bool, value int) {
 
 	leafNodeCount++
 
-	for _, cell := range checkableCells {
-		relevantQuads := indexedWinningQuads[cell[0]][cell[1]]
+	if winner := rules.BoardWinner(bd); winner != game.UNSET {
+		return true, winner * int(score.Win(ply))
+	}
+
+	for _, cell := range game.CheckableCells {
+		relevantQuads := game.IndexedWinningQuads[cell[0]][cell[1]]
 		for _, quad := range relevantQuads {
 			sum := bd[quad[0][0]][quad[0][1]]
 			sum += bd[quad[1][0]][quad[1][1]]
 			sum += bd[quad[2][0]][quad[2][1]]
 			sum += bd[quad[3][0]][quad[3][1]]
 
-			if sum == 4 || sum == -4 {
-				return true, bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-			}
 			if sum == 3 || sum == -3 {
 				value += sum * 10
 			}
 		}
 	}
 
-	for _, cell := range checkableCells {
-		relevantTriplets := indexedLosingTriplets[cell[0]][cell[1]]
-		for _, triplet := range relevantTriplets {
-			sum := bd[triplet[0][0]][triplet[0][1]]
-			sum += bd[triplet[1][0]][triplet[1][1]]
-			sum += bd[triplet[2][0]][triplet[2][1]]
-
-			if sum == 3 || sum == -3 {
-				return true, -sum / 3 * (WIN - ply)
-			}
-		}
-	}
-
 	for _, quad := range deadlyQuads {
 		outer := bd[quad[0][0]][quad[0][1]] + bd[quad[3][0]][quad[3][1]]
 		inner := bd[quad[1][0]][quad[1][1]] + bd[quad[2][0]][quad[2][1]]
@@ -377,19 +393,23 @@ func staticValue(bd *Board, ply int) (stopRecursing bool, value int) {
 	return stopRecursing, value
 }
 
-func negaScout(bd *Board, ply int, player int, alpha int, beta int) (value int) {
+func negaScout(bd *game.Board, ply int, player int, alpha int, beta int) (value int) {
+
+	if ply > 1 && timeUp() {
+		return 0
+	}
 
 	stopRecursing, boardValue := staticValue(bd, ply)
 	if stopRecursing {
 		return player * boardValue
 	}
 
-	score := 3 * LOSS
+	score := 3 * game.LOSS
 	n := beta
 
 	for _, cell := range orderedMoves[player+1] {
 		i, j := cell[0], cell[1]
-		if bd[i][j] == UNSET {
+		if bd[i][j] == game.UNSET {
 			bd[i][j] = player
 			cur := -negaScout(bd, ply+1, -player, -n, -alpha)
 			if cur > score {
@@ -399,7 +419,7 @@ func negaScout(bd *Board, ply int, player int, alpha int, beta int) (value int)
 					score = -negaScout(bd, ply+1, -player, -beta, -cur)
 				}
 			}
-			bd[i][j] = UNSET
+			bd[i][j] = game.UNSET
 			if score > alpha {
 				alpha = score
 			}
@@ -413,110 +433,9 @@ func negaScout(bd *Board, ply int, player int, alpha int, beta int) (value int)
 	return score
 }
 
-func printBoard(bd *Board) {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
-		}
-		fmt.Printf("\n")
-	}
-}
-
-var losingTriplets [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}},
-	[][]int{[]int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}},
-	[][]int{[]int{2, 0}, []int{1, 1}, []int{0, 2}},
-	[][]int{[]int{2, 0}, []int{3, 1}, []int{4, 2}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}},
-	[][]int{[]int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 2}, []int{1, 3}, []int{2, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 2}, []int{2, 3}, []int{1, 4}},
-	[][]int{[]int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 2}, []int{3, 3}, []int{2, 4}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{2, 4}, []int{3, 4}, []int{4, 4}},
-}
-var winningQuads [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}, []int{4, 4}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}, []int{1, 4}},
-}
-
 var scores [5][5]int
 
-func readMove(bd *Board, print bool) (x, y int) {
+func readMove(bd *game.Board, print bool) (x, y int) {
 	readMove := false
 	for !readMove {
 		if print {
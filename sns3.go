@@ -1,6 +1,5 @@
 package main
 
-
 import (
 	"flag"
 	"fmt"
@@ -8,27 +7,13 @@ import (
 	"math/rand"
 	"os"
 	"time"
-)
-
-type Board [5][5]int
 
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
+	"squava/src/game"
 )
 
 var leafNodeCount int = 0
 var maxDepth int = 10 // initializing to 10 not a mistake
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
-
 func main() {
 
 	humanFirstPtr := flag.Bool("H", true, "Human takes first move")
@@ -42,19 +27,6 @@ func main() {
 
 	*printBoardPtr = !*printBoardPtr
 
-	// Set up for use by staticValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
-	}
-
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	setScores(*randomizeScores)
@@ -64,15 +36,15 @@ func main() {
 		humanFirst = false
 	}
 
-	var bd Board
+	var bd game.Board
 
 	if *firstMovePtr != "" {
 		var x1, y1 int
 		fmt.Sscanf(*firstMovePtr, "%d,%d", &x1, &y1)
 		fmt.Printf("My move: %d %d\n", x1, y1)
 		humanFirst = true
-		bd[x1][y1] = MAXIMIZER
-		printBoard(&bd)
+		bd[x1][y1] = game.MAXIMIZER
+		bd.Print()
 	}
 
 	var endOfGame bool = false
@@ -85,7 +57,7 @@ func main() {
 		var l, m int
 		if humanFirst {
 			l, m = readMove(&bd, *printBoardPtr)
-			bd[l][m] = MINIMIZER
+			bd[l][m] = game.MINIMIZER
 			endOfGame, _ = staticValue(&bd, 0)
 			moveCounter++
 		}
@@ -103,13 +75,13 @@ func main() {
 			break // Cat gets the game
 		}
 
-		bd[a][b] = MAXIMIZER
+		bd[a][b] = game.MAXIMIZER
 		moveCounter++
 
 		if *printBoardPtr {
 			fmt.Printf("My move: %d %d (%d) [%d]\n", a, b, score, leafNodeCount)
 			printHistory(hist)
-			printBoard(&bd)
+			bd.Print()
 		} else {
 			fmt.Printf("%d %d\n", a, b)
 		}
@@ -120,17 +92,17 @@ func main() {
 
 	if *printBoardPtr {
 		var phrase string
-		switch findWinner(&bd) {
-		case MAXIMIZER:
+		switch bd.Winner() {
+		case game.MAXIMIZER:
 			phrase = "\nX wins\n"
-		case UNSET:
+		case game.UNSET:
 			phrase = "\nCat wins\n"
-		case MINIMIZER:
+		case game.MINIMIZER:
 			phrase = "\nO wins\n"
 		}
-		fmt.Printf(phrase)
+		fmt.Print(phrase)
 
-		printBoard(&bd)
+		bd.Print()
 	}
 
 	os.Exit(0)
@@ -150,21 +122,21 @@ func setDepth(moveCounter int, endGameDepth int) {
 
 // Choose computer's next move: return x,y coords of move and its score.
 var orderedMoves [25][2]int = [25][2]int{
-	{1,1}, {1,3}, {3,3}, {3,1},
-	{0,1}, {0,3}, {1,4}, {3,4}, {4,3}, {4,1}, {3,0}, {1,0},
-	{0,0}, {0,4}, {4,4}, {4,0},
-	{2,2},
-	{1,2}, {2,3}, {3,2}, {2,1},
-	{0,2}, {2,0}, {2,4}, {4,2},
+	{1, 1}, {1, 3}, {3, 3}, {3, 1},
+	{0, 1}, {0, 3}, {1, 4}, {3, 4}, {4, 3}, {4, 1}, {3, 0}, {1, 0},
+	{0, 0}, {0, 4}, {4, 4}, {4, 0},
+	{2, 2},
+	{1, 2}, {2, 3}, {3, 2}, {2, 1},
+	{0, 2}, {2, 0}, {2, 4}, {4, 2},
 }
 
-func chooseMove(bd *Board, deterministic bool) (int, int, int, []*MoveValue) {
+func chooseMove(bd *game.Board, deterministic bool) (int, int, int, []*MoveValue) {
 
 	var moves = new(MoveKeeper)
-	moves.max = 3*LOSS
+	moves.max = 3 * game.LOSS
 
-	beta := 2*WIN
-	alpha := 2*LOSS
+	beta := 2 * game.WIN
+	alpha := 2 * game.LOSS
 
 	b := beta
 	later := false
@@ -172,15 +144,15 @@ func chooseMove(bd *Board, deterministic bool) (int, int, int, []*MoveValue) {
 	for _, cell := range orderedMoves {
 		i, j := cell[0], cell[1]
 		mark := bd[i][j]
-		if mark == UNSET {
-			bd[i][j] = MAXIMIZER
-			val, hist := negaScout(bd, 1, MINIMIZER, -b, -alpha)
+		if mark == game.UNSET {
+			bd[i][j] = game.MAXIMIZER
+			val, hist := negaScout(bd, 1, game.MINIMIZER, -b, -alpha)
 			val = -val
 			if val > alpha && val < beta && later {
-				val, hist = negaScout(bd, 1, MINIMIZER, -beta, -alpha)
+				val, hist = negaScout(bd, 1, game.MINIMIZER, -beta, -alpha)
 				val = -val
 			}
-			bd[i][j] = UNSET
+			bd[i][j] = game.UNSET
 			// fmt.Printf("	<%d,%d> (%d)\n", i, j, val)
 			if val > alpha {
 				alpha = val
@@ -196,88 +168,39 @@ func chooseMove(bd *Board, deterministic bool) (int, int, int, []*MoveValue) {
 
 	var m MoveValue
 	var h []*MoveValue
-	m.x, m.y, m.value,h = moves.chooseMove()
-	m.player = MAXIMIZER
+	m.x, m.y, m.value, h = moves.chooseMove()
+	m.player = game.MAXIMIZER
 	m.ply = 0
 
 	h = append(h, &m)
-                                                
-	return m.x,m.y,m.value,h
-}
-
-func findWinner(bd *Board) int {
-	for _, quad := range winningQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return bd[quad[0][0]][quad[0][1]]
-		}
-	}
-
-	for _, triplet := range losingTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -bd[triplet[0][0]][triplet[0][1]]
-		}
-	}
-
-	return 0
-}
 
-// It turns out that you only have to look at
-// the 4-in-a-rows that contain these 9 cells
-// to check every 4-in-a-row. Similarly, you
-// only need to check these 9 cells to check
-// all the losing 3-in-a-row combos. You don't
-// have to look at each and every cell.
-var checkableCells [9][2]int = [9][2]int{
-	{0, 2}, {1, 2}, {2, 0},
-	{2, 1}, {2, 2}, {2, 3},
-	{2, 4}, {3, 2}, {4, 2},
+	return m.x, m.y, m.value, h
 }
 
 // Calculates and returns the value of the move (x,y)
 // Only considers value gained or lost from the cell (x,y)
-func staticValue(bd *Board, ply int) (stopRecursing bool, value int) {
+func staticValue(bd *game.Board, ply int) (stopRecursing bool, value int) {
 
 	leafNodeCount++
 
-	for _, cell := range checkableCells {
-		relevantQuads := indexedWinningQuads[cell[0]][cell[1]]
+	if winner := bd.Winner(); winner != game.UNSET {
+		return true, winner * game.WIN
+	}
+
+	for _, cell := range game.CheckableCells {
+		relevantQuads := game.IndexedWinningQuads[cell[0]][cell[1]]
 		for _, quad := range relevantQuads {
 			sum := bd[quad[0][0]][quad[0][1]]
 			sum += bd[quad[1][0]][quad[1][1]]
 			sum += bd[quad[2][0]][quad[2][1]]
 			sum += bd[quad[3][0]][quad[3][1]]
 
-			if sum == 4 || sum == -4 {
-				return true, bd[quad[0][0]][quad[0][1]] * WIN
-			}
 			if sum == 3 || sum == -3 {
 				value += sum * 10
 			}
 		}
 	}
 
-	for _, cell := range checkableCells {
-		relevantTriplets := indexedLosingTriplets[cell[0]][cell[1]]
-		for _, triplet := range relevantTriplets {
-			sum := bd[triplet[0][0]][triplet[0][1]]
-			sum += bd[triplet[1][0]][triplet[1][1]]
-			sum += bd[triplet[2][0]][triplet[2][1]]
-
-			if sum == 3 || sum == -3 {
-				return true, -sum / 3 * WIN
-			}
-		}
-	}
-
 	// Give it a slight bias for those early
 	// moves when all losing-triplets and winning-quads
 	// are beyond the horizon.
@@ -298,20 +221,20 @@ func staticValue(bd *Board, ply int) (stopRecursing bool, value int) {
 }
 
 type MoveValue struct {
-	value int
-	x int
-	y int
-	ply int
-	player int
+	value    int
+	x        int
+	y        int
+	ply      int
+	player   int
 	leafnode int
 }
 
-func negaScout(bd *Board, ply int, player int, alpha int, beta int) (val int, history []*MoveValue) {
+func negaScout(bd *game.Board, ply int, player int, alpha int, beta int) (val int, history []*MoveValue) {
 
 	stopRecursing, boardValue := staticValue(bd, ply)
 	if stopRecursing {
 		var m MoveValue
-		m.value, m.x, m.y, m.ply, m.player  = player*boardValue, -2, -2, ply, player
+		m.value, m.x, m.y, m.ply, m.player = player*boardValue, -2, -2, ply, player
 		m.leafnode = leafNodeCount
 		hist := make([]*MoveValue, 1)
 		hist[0] = &m
@@ -320,21 +243,21 @@ func negaScout(bd *Board, ply int, player int, alpha int, beta int) (val int, hi
 
 	b := beta
 	later := false
-	var x,y int
+	var x, y int
 	var hist []*MoveValue
 
-	OUT:
+OUT:
 	for i, row := range bd {
 		for j, marker := range row {
-			if marker == UNSET {
+			if marker == game.UNSET {
 				bd[i][j] = player
-				t,h := negaScout(bd, ply+1, -player, -b, -alpha)
+				t, h := negaScout(bd, ply+1, -player, -b, -alpha)
 				t = -t
 				if t > alpha && t < beta && later {
-					t,h = negaScout(bd, ply+1, -player, -beta, -alpha)
+					t, h = negaScout(bd, ply+1, -player, -beta, -alpha)
 					t = -t
 				}
-				bd[i][j] = UNSET
+				bd[i][j] = game.UNSET
 				if t > alpha {
 					x = i
 					y = j
@@ -342,16 +265,16 @@ func negaScout(bd *Board, ply int, player int, alpha int, beta int) (val int, hi
 					alpha = t
 				}
 				if alpha >= beta {
-/*
-					var m MoveValue
-					m.x = x
-					m.y = y
-					m.player = player
-					m.ply = ply
-					m.value = alpha
-					hist = append(hist, &m)
-					return alpha, hist
-*/
+					/*
+						var m MoveValue
+						m.x = x
+						m.y = y
+						m.player = player
+						m.ply = ply
+						m.value = alpha
+						hist = append(hist, &m)
+						return alpha, hist
+					*/
 					break OUT
 				}
 				b = alpha + 1
@@ -371,110 +294,9 @@ func negaScout(bd *Board, ply int, player int, alpha int, beta int) (val int, hi
 	return alpha, hist
 }
 
-func printBoard(bd *Board) {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
-		}
-		fmt.Printf("\n")
-	}
-}
-
-var losingTriplets [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}},
-	[][]int{[]int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}},
-	[][]int{[]int{2, 0}, []int{1, 1}, []int{0, 2}},
-	[][]int{[]int{2, 0}, []int{3, 1}, []int{4, 2}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}},
-	[][]int{[]int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 2}, []int{1, 3}, []int{2, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 2}, []int{2, 3}, []int{1, 4}},
-	[][]int{[]int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 2}, []int{3, 3}, []int{2, 4}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{2, 4}, []int{3, 4}, []int{4, 4}},
-}
-var winningQuads [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}, []int{4, 4}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}, []int{1, 4}},
-}
-
 var scores [5][5]int
 
-func readMove(bd *Board, print bool) (x, y int) {
+func readMove(bd *game.Board, print bool) (x, y int) {
 	readMove := false
 	for !readMove {
 		if print {
@@ -528,7 +350,7 @@ func setScores(randomize bool) {
 
 type MoveKeeper struct {
 	moves [2]int
-	next  int        // index into moves[]
+	next  int // index into moves[]
 	max   int
 	hist  []*MoveValue
 }
@@ -552,7 +374,7 @@ func printHistory(history []*MoveValue) {
 		if move != nil {
 			fmt.Printf("   %d - %d, %d: <%d,%d> [%d]\n", i, move.ply, move.player, move.x, move.y, move.value)
 		} else {
-			fmt.Printf("   %d - nil MoveValue\n",i)
+			fmt.Printf("   %d - nil MoveValue\n", i)
 		}
 	}
 }
@@ -0,0 +1,99 @@
+package main
+
+/*
+ * Solve a squava position exactly, by proof-number search: a win
+ * for the first player (X), a win for the second player (O), or a
+ * draw, and the size of the proof.
+ *
+ * ./solve 1,1 3,3 1,3 3,1 0,0 4,4   # position as moves, X first
+ * ./solve -N 1000000 2,2 1,1        # give up after a million positions
+ *
+ * Control-C gives up on the position.
+ */
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/solver"
+)
+
+func main() {
+	maxNodes := flag.Int("N", 0, "give up after expanding this many positions, 0 for no limit")
+	timeLimit := flag.Duration("t", 0, "give up after this long, 0 for no limit")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s [flags] [m,n [m,n ...]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	rules, err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var bd game.Board
+	player := game.MAXIMIZER
+	for _, str := range flag.Args() {
+		mn := strings.Split(str, ",")
+		if len(mn) != 2 {
+			fmt.Fprintf(os.Stderr, "Bad move %q, want m,n\n", str)
+			os.Exit(1)
+		}
+		m, err1 := strconv.Atoi(mn[0])
+		n, err2 := strconv.Atoi(mn[1])
+		if err1 != nil || err2 != nil || m < 0 || m > 4 || n < 0 || n > 4 {
+			fmt.Fprintf(os.Stderr, "Bad move %q, want m,n from 0 to 4\n", str)
+			os.Exit(1)
+		}
+		if bd[m][n] != game.UNSET {
+			fmt.Fprintf(os.Stderr, "<%d,%d> already taken\n", m, n)
+			os.Exit(1)
+		}
+		if winner := rules.BoardWinner(&bd); winner != game.UNSET {
+			fmt.Fprintf(os.Stderr, "Game already over before <%d,%d>\n", m, n)
+			os.Exit(1)
+		}
+		bd[m][n] = player
+		player = -player
+	}
+
+	bd.Print()
+	mark := "X"
+	if player == game.MINIMIZER {
+		mark = "O"
+	}
+	fmt.Printf("%s to move\n", mark)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeLimit)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, err := solver.Solve(ctx, rules, game.NewBitboard(&bd), player, *maxNodes)
+	elapsed := time.Since(start)
+	if err != nil {
+		fmt.Printf("unsolved: %v, %d nodes, %v\n", err, result.Nodes, elapsed)
+		os.Exit(2)
+	}
+
+	fmt.Printf("%v\n", result.Outcome)
+	if result.Move != engine.NoMove {
+		fmt.Printf("%s moves %v\n", mark, result.Move)
+	}
+	fmt.Printf("proof tree size %d, %d nodes, %v\n", result.ProofSize, result.Nodes, elapsed)
+}
@@ -4,32 +4,43 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math/bits"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
-)
-
-// Board is internal representation of a 5x5 tictactoe style
-// game board.
-type Board [5][5]int
 
-// Manifest constants used repeatedly.
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
+	"squava/src/clock"
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/score"
 )
 
 var leafNodeCount int
 var maxDepth int = 10 // initializing to 10 not a mistake
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
+// alphaBeta keeps the lines of moves it expects in pv,
+// chooseMove puts the chosen move's line in principalVariation.
+var pv engine.PVTable
+var principalVariation []engine.Move
+
+// Time control: when timeLimited, alphaBeta gives up
+// after deadline, and sets stopped.
+var timeLimited bool
+var deadline time.Time
+var stopped bool
+var nodeCount int
+
+// rules decides what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does
+var rules game.Rules
+
+// shape is the size of the board, the lengths of the
+// lines that win and lose, and whether lines wrap around
+var shape = game.Standard
+
+// start has handicap marks already on the board, and the swap rule
+var start game.Start
 
 func main() {
 
@@ -41,22 +52,45 @@ func main() {
 	firstMovePtr := flag.String("M", "", "Tell computer to make this first move (x,y)")
 	randomizeScores := flag.Bool("r", false, "Randomize bias scores")
 	useBook := flag.Bool("B", false, "Use book start or defense")
+	perMove := flag.Duration("t", 0, "time per move, iterative deepening instead of -d")
+	gameTime := flag.Duration("T", 0, "total time for computer's moves, 0 for no game clock")
+	increment := flag.Duration("I", 0, "time added to game clock after each move")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	size := flag.String("size", "5x5", "board size, WxH or N for NxN")
+	winLength := flag.Int("win", 4, "this many in a row wins")
+	loseLength := flag.Int("lose", 3, "this many in a row loses")
+	torus := flag.Bool("torus", false, "lines wrap around the edges of the board")
+	xMarks := flag.String("x", "", "X's marks already on the board, like \"2,2 1,3\"")
+	oMarks := flag.String("o", "", "O's marks already on the board, like \"2,2 1,3\"")
+	forbid := flag.String("forbid", "", "cells nobody can mark, like \"2,2 1,3\"")
+	swap := flag.Bool("swap", false, "after the first move, the second player can take over the first player's side")
 	flag.Parse()
 
-	*printBoardPtr = !*printBoardPtr
-
-	// Set up for use by deltaValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
+	var err error
+	if rules, err = game.ParseRules(*rulesName); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
+	if shape, err = game.ParseShape(*size, *winLength, *loseLength, *torus); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
+	if shape, start, err = game.ParseStart(shape, *xMarks, *oMarks, *forbid, *swap); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if *useBook && shape != game.Standard {
+		fmt.Fprintf(os.Stderr, "The opening book only knows the standard 5x5 board\n")
+		os.Exit(1)
+	}
+	if *useBook && !start.Usual() {
+		fmt.Fprintf(os.Stderr, "The opening book only knows games from an empty board, without the swap rule\n")
+		os.Exit(1)
+	}
+
+	computerClock := clock.New(*perMove, *gameTime, *increment)
+
+	*printBoardPtr = !*printBoardPtr
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
@@ -67,19 +101,36 @@ func main() {
 		humanFirst = false
 	}
 
-	moveCounter := 0
-	var bd Board
+	// The computer's marks are MAXIMIZER's, whoever is X
+	xPlayer := game.MINIMIZER
+	if !humanFirst || *firstMovePtr != "" {
+		xPlayer = game.MAXIMIZER
+	}
+	bd := start.Board(shape, xPlayer)
+	if rules.Winner(&bd) != game.UNSET {
+		fmt.Fprintf(os.Stderr, "The marks already on the board decide the game\n")
+		os.Exit(1)
+	}
+	moveCounter := bd.Count()
+
+	// Under the swap rule, the second player can take
+	// over the first player's side after the first move.
+	swapPending := start.Swap
+	firstMove := moveCounter + 1
 
 	if *useBook {
+		// The book plays on a 5x5 Board
+		var book game.Board
 		fmt.Printf("Using opening book\n")
 		*firstMovePtr = ""
 		if humanFirst {
 			l, m := readMove(&bd, *printBoardPtr)
-			bd[l][m] = MINIMIZER
-			moveCounter = 1 + bookDefend(&bd, l, m)
+			book[l][m] = game.MINIMIZER
+			moveCounter = 1 + bookDefend(&book, l, m)
 		} else {
-			moveCounter += bookStart(&bd)
+			moveCounter += bookStart(&book)
 		}
+		bd = game.NewBitboard(&book)
 	}
 
 	if *firstMovePtr != "" {
@@ -87,8 +138,12 @@ func main() {
 		fmt.Sscanf(*firstMovePtr, "%d,%d", &x1, &y1)
 		fmt.Printf("My move: %d %d\n", x1, y1)
 		humanFirst = true
-		bd[x1][y1] = MAXIMIZER
-		printBoard(&bd)
+		bd.MakeMove(shape.Cell(x1, y1), game.MAXIMIZER)
+		fmt.Printf("%v", &bd)
+		if swapPending {
+			swapPending = false
+			humanFirst = !offerSwap(&bd, *printBoardPtr)
+		}
 	}
 
 	endOfGame := false
@@ -98,9 +153,12 @@ func main() {
 
 		var l, m int
 		if humanFirst {
+			if bd.Empty() == 0 {
+				break // Cat gets the game
+			}
 			l, m = readMove(&bd, *printBoardPtr)
-			bd[l][m] = MINIMIZER
-			endOfGame, _ = deltaValue(&bd, 0, l, m, 0)
+			bd.MakeMove(shape.Cell(l, m), game.MINIMIZER)
+			endOfGame, _ = deltaValue(&bd, 0, shape.Cell(l, m), 0)
 			moveCounter++
 		}
 
@@ -112,41 +170,69 @@ func main() {
 
 		leafNodeCount = 0
 		start := time.Now()
-		a, b, score := chooseMove(&bd, *deterministic)
+		var a, b, value int
+		if computerClock.Enabled() {
+			a, b, value = timedChooseMove(&bd, *deterministic, computerClock.Budget(bits.OnesCount64(bd.Empty())))
+		} else {
+			a, b, value = chooseMove(&bd, *deterministic)
+		}
 		end := time.Now()
 		elapsed := end.Sub(start)
+		computerClock.Used(elapsed)
 
 		if a < 0 {
 			break // Cat gets the game
 		}
 
-		bd[a][b] = MAXIMIZER
+		if swapPending && moveCounter == firstMove {
+			// The human made the first move. If the
+			// best reply is worth less than nothing,
+			// the first move is worth having.
+			swapPending = false
+			if value < 0 {
+				bd = bd.Swapped()
+				if *printBoardPtr {
+					fmt.Printf("I swap, your first move is mine\n")
+					fmt.Printf("%v", &bd)
+				} else {
+					fmt.Printf("swap\n")
+				}
+				continue
+			}
+		}
+
+		bd.MakeMove(shape.Cell(a, b), game.MAXIMIZER)
 		moveCounter++
 
 		if *printBoardPtr {
-			fmt.Printf("My move: %d %d (%d) [%d] %v\n", a, b, score, leafNodeCount, elapsed)
-			printBoard(&bd)
+			fmt.Printf("My move: %d %d (%v) [%d] %v pv %v\n", a, b, score.Score(value), leafNodeCount, elapsed, principalVariation)
+			fmt.Printf("%v", &bd)
 		} else {
 			fmt.Printf("%d %d\n", a, b)
 		}
 
-		endOfGame, _ = deltaValue(&bd, 0, a, b, 0)
+		endOfGame, _ = deltaValue(&bd, 0, shape.Cell(a, b), 0)
 
+		if swapPending && moveCounter == firstMove && !endOfGame {
+			// The computer made the first move
+			swapPending = false
+			humanFirst = !offerSwap(&bd, *printBoardPtr)
+		}
 	}
 
 	if *printBoardPtr {
 		var phrase string
-		switch findWinner(&bd) {
-		case MAXIMIZER:
+		switch rules.Winner(&bd) {
+		case game.MAXIMIZER:
 			phrase = "\nX wins\n"
-		case UNSET:
+		case game.UNSET:
 			phrase = "\nCat wins\n"
-		case MINIMIZER:
+		case game.MINIMIZER:
 			phrase = "\nO wins\n"
 		}
 		fmt.Print(phrase)
 
-		printBoard(&bd)
+		fmt.Printf("%v", &bd)
 	}
 
 	os.Exit(0)
@@ -165,88 +251,119 @@ func setDepth(moveCounter int, endGameDepth int) {
 }
 
 // Choose computer's next move: return x,y coords of move and its score.
-func chooseMove(bd *Board, deterministic bool) (xcoord int, ycoord int, value int) {
+func chooseMove(bd *game.Bitboard, deterministic bool) (xcoord int, ycoord int, value int) {
 
-	var moves = moveKeeper{max: 2 * LOSS}
+	var moves = moveKeeper{max: 2 * game.LOSS}
+	var lines [game.MaxCells][]engine.Move
 
-	for i, row := range bd {
-		for j, mark := range row {
-			if mark == UNSET {
-				bd[i][j] = MAXIMIZER
-				stopRecursing, value := deltaValue(bd, 0, i, j, 0)
-				if !stopRecursing {
-					value = alphaBeta(bd, 1, MINIMIZER, 2*LOSS, 2*WIN, i, j, value)
-				} else {
-					leafNodeCount++
-				}
-				bd[i][j] = UNSET
-				moves.setMove(i, j, value)
-			}
+	for empty := bd.Empty(); empty != 0; empty &= empty - 1 {
+		cell := bits.TrailingZeros64(empty)
+		bd.MakeMove(cell, game.MAXIMIZER)
+		stopRecursing, value := deltaValue(bd, 0, cell, 0)
+		if !stopRecursing {
+			value = alphaBeta(bd, 1, game.MINIMIZER, 2*game.LOSS, 2*game.WIN, cell, value)
+		} else {
+			leafNodeCount++
+			pv.Clear(1)
+			value = firstPly(value)
+		}
+		bd.UnmakeMove(cell, game.MAXIMIZER)
+		if stopped {
+			return -1, -1, 0
 		}
+		pv.Update(0, cell)
+		lines[cell] = pv.Line(shape, 0)
+		i, j := shape.Coords(cell)
+		moves.setMove(i, j, value)
 	}
 
-	return moves.chooseMove(deterministic)
+	xcoord, ycoord, value = moves.chooseMove(deterministic)
+	principalVariation = nil
+	if xcoord >= 0 {
+		principalVariation = lines[shape.Cell(xcoord, ycoord)]
+	}
+	return xcoord, ycoord, value
 }
 
-func findWinner(bd *Board) int {
-	for _, quad := range winningQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return bd[quad[0][0]][quad[0][1]]
+// timedChooseMove deepens the search one ply at a time until
+// limit runs out, and returns the move found by the deepest
+// search that finished.
+func timedChooseMove(bd *game.Bitboard, deterministic bool, limit time.Duration) (xcoord int, ycoord int, value int) {
+	start := time.Now()
+	deadline = start.Add(limit)
+	timeLimited = true
+	stopped = false
+	nodeCount = 0
+	defer func() { timeLimited = false }()
+
+	empty := bits.OnesCount64(bd.Empty())
+
+	// Depth 0 only values the computer's own moves, so
+	// it always finishes and leaves a move to make.
+	xcoord, ycoord = -1, -1
+	for depth := 0; depth < empty; depth++ {
+		maxDepth = depth
+		x, y, v := chooseMove(bd, deterministic)
+		if stopped {
+			break
 		}
-	}
-
-	for _, triplet := range losingTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -bd[triplet[0][0]][triplet[0][1]]
+		xcoord, ycoord, value = x, y, v
+		if score.Score(value).Decided() {
+			break // forced win or loss, deeper won't change it
+		}
+		if time.Since(start) > limit/2 {
+			break // next depth would take longer than what's left
 		}
 	}
 
-	return 0
+	return xcoord, ycoord, value
 }
 
-// Calculates and returns the value of the move (x,y)
-// Only considers value gained or lost from the cell (x,y)
-func deltaValue(bd *Board, ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
-
-	relevantQuads := indexedWinningQuads[x][y]
-	for _, quad := range relevantQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
+// timeUp checks the clock every so often during
+// a timed search, and stops the search after deadline.
+func timeUp() bool {
+	if !timeLimited {
+		return false
+	}
+	if stopped {
+		return true
+	}
+	nodeCount++
+	if nodeCount&1023 == 0 && time.Now().After(deadline) {
+		stopped = true
+	}
+	return stopped
+}
 
-		if sum == 4 || sum == -4 {
-			return true, bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-		}
-		if sum == 3 || sum == -3 {
-			value += sum * 10
-		}
+// firstPly fixes up the value deltaValue gives the computer's own
+// move. deltaValue calls that move ply 0, so that depth 0 searches
+// stop there, but a win or loss on it comes on the first ply.
+func firstPly(value int) int {
+	switch s := score.Score(value); {
+	case s.IsWin():
+		return int(score.Win(1))
+	case s.IsLoss():
+		return int(score.Loss(1))
 	}
+	return value
+}
 
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
+// Calculates and returns the value of the move in cell
+// Only considers value gained or lost from the cell
+func deltaValue(bd *game.Bitboard, ply int, cell int, currentValue int) (stopRecursing bool, value int) {
 
-		if sum == 3 || sum == -3 {
-			return true, -sum / 3 * (WIN - ply)
-		}
+	if winner := rules.MoveWinner(bd, cell); winner != game.UNSET {
+		return true, winner * int(score.Win(ply))
 	}
 
+	// Quads through cell one mark short of a win
+	player := bd.At(cell)
+	value += bd.ThreeOfFour(cell, player) * player * 30
+
 	// Give it a slight bias for those early
 	// moves when all losing-triplets and winning-quads
 	// are beyond the horizon.
-	value += bd[x][y] * scores[x][y]
+	value += player * scores[cell]
 
 	// If squava has a "cat game", then this is wrong. Cat
 	// games could stop recursing here.
@@ -259,55 +376,57 @@ func deltaValue(bd *Board, ply int, x, y int, currentValue int) (stopRecursing b
 	return stopRecursing, value
 }
 
-func alphaBeta(bd *Board, ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int) {
+func alphaBeta(bd *game.Bitboard, ply int, player int, alpha int, beta int, cell int, boardValue int) (value int) {
 
-	stopRecursing, delta := deltaValue(bd, ply, x, y, boardValue)
+	if timeUp() {
+		return 0
+	}
+
+	pv.Clear(ply)
+
+	stopRecursing, delta := deltaValue(bd, ply, cell, boardValue)
 	if stopRecursing {
 		return delta
 	}
 	boardValue += delta
 
 	switch player {
-	case MAXIMIZER:
-		value = 2 * LOSS // Possible to score less than LOSS
-		for i, row := range bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					bd[i][j] = MAXIMIZER
-					n := alphaBeta(bd, ply+1, MINIMIZER, alpha, beta, i, j, boardValue)
-					bd[i][j] = UNSET
-					if n > value {
-						value = n
-					}
-					if value > alpha {
-						alpha = value
-					}
-					if beta <= alpha {
-						leafNodeCount++
-						return value
-					}
-				}
+	case game.MAXIMIZER:
+		value = 2 * game.LOSS // Possible to score less than LOSS
+		for empty := bd.Empty(); empty != 0; empty &= empty - 1 {
+			next := bits.TrailingZeros64(empty)
+			bd.MakeMove(next, game.MAXIMIZER)
+			n := alphaBeta(bd, ply+1, game.MINIMIZER, alpha, beta, next, boardValue)
+			bd.UnmakeMove(next, game.MAXIMIZER)
+			if n > value {
+				value = n
+				pv.Update(ply, next)
+			}
+			if value > alpha {
+				alpha = value
+			}
+			if beta <= alpha {
+				leafNodeCount++
+				return value
 			}
 		}
-	case MINIMIZER:
-		value = 2 * WIN // You can score greater than WIN
-		for i, row := range bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					bd[i][j] = player
-					n := alphaBeta(bd, ply+1, -player, alpha, beta, i, j, boardValue)
-					bd[i][j] = UNSET
-					if n < value {
-						value = n
-					}
-					if value < beta {
-						beta = value
-					}
-					if beta <= alpha {
-						leafNodeCount++
-						return value
-					}
-				}
+	case game.MINIMIZER:
+		value = 2 * game.WIN // You can score greater than WIN
+		for empty := bd.Empty(); empty != 0; empty &= empty - 1 {
+			next := bits.TrailingZeros64(empty)
+			bd.MakeMove(next, player)
+			n := alphaBeta(bd, ply+1, -player, alpha, beta, next, boardValue)
+			bd.UnmakeMove(next, player)
+			if n < value {
+				value = n
+				pv.Update(ply, next)
+			}
+			if value < beta {
+				beta = value
+			}
+			if beta <= alpha {
+				leafNodeCount++
+				return value
 			}
 		}
 	}
@@ -315,101 +434,10 @@ func alphaBeta(bd *Board, ply int, player int, alpha int, beta int, x int, y int
 	return value
 }
 
-func printBoard(bd *Board) {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			fmt.Printf("%s ", string("O_X"[v+1]))
-		}
-		fmt.Printf("\n")
-	}
-}
-
-var losingTriplets [][][]int = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}},
-	{{0, 0}, {0, 1}, {0, 2}},
-	{{0, 0}, {1, 1}, {2, 2}},
-	{{1, 0}, {2, 0}, {3, 0}},
-	{{1, 0}, {1, 1}, {1, 2}},
-	{{1, 0}, {2, 1}, {3, 2}},
-	{{2, 0}, {3, 0}, {4, 0}},
-	{{2, 0}, {2, 1}, {2, 2}},
-	{{2, 0}, {1, 1}, {0, 2}},
-	{{2, 0}, {3, 1}, {4, 2}},
-	{{3, 0}, {3, 1}, {3, 2}},
-	{{3, 0}, {2, 1}, {1, 2}},
-	{{4, 0}, {4, 1}, {4, 2}},
-	{{4, 0}, {3, 1}, {2, 2}},
-	{{0, 1}, {1, 1}, {2, 1}},
-	{{0, 1}, {0, 2}, {0, 3}},
-	{{0, 1}, {1, 2}, {2, 3}},
-	{{1, 1}, {2, 1}, {3, 1}},
-	{{1, 1}, {1, 2}, {1, 3}},
-	{{1, 1}, {2, 2}, {3, 3}},
-	{{2, 1}, {3, 1}, {4, 1}},
-	{{2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {1, 2}, {0, 3}},
-	{{2, 1}, {3, 2}, {4, 3}},
-	{{3, 1}, {3, 2}, {3, 3}},
-	{{3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}},
-	{{4, 1}, {3, 2}, {2, 3}},
-	{{0, 2}, {1, 2}, {2, 2}},
-	{{0, 2}, {0, 3}, {0, 4}},
-	{{0, 2}, {1, 3}, {2, 4}},
-	{{1, 2}, {2, 2}, {3, 2}},
-	{{1, 2}, {1, 3}, {1, 4}},
-	{{1, 2}, {2, 3}, {3, 4}},
-	{{2, 2}, {3, 2}, {4, 2}},
-	{{2, 2}, {2, 3}, {2, 4}},
-	{{2, 2}, {1, 3}, {0, 4}},
-	{{2, 2}, {3, 3}, {4, 4}},
-	{{3, 2}, {3, 3}, {3, 4}},
-	{{3, 2}, {2, 3}, {1, 4}},
-	{{4, 2}, {4, 3}, {4, 4}},
-	{{4, 2}, {3, 3}, {2, 4}},
-	{{0, 3}, {1, 3}, {2, 3}},
-	{{1, 3}, {2, 3}, {3, 3}},
-	{{2, 3}, {3, 3}, {4, 3}},
-	{{0, 4}, {1, 4}, {2, 4}},
-	{{1, 4}, {2, 4}, {3, 4}},
-	{{2, 4}, {3, 4}, {4, 4}},
-}
-var winningQuads [][][]int = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
-	{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
-	{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
-	{{0, 1}, {1, 1}, {2, 1}, {3, 1}},
-	{{0, 1}, {0, 2}, {0, 3}, {0, 4}},
-	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
-	{{0, 2}, {1, 2}, {2, 2}, {3, 2}},
-	{{0, 3}, {1, 3}, {2, 3}, {3, 3}},
-	{{0, 4}, {1, 4}, {2, 4}, {3, 4}},
-	{{1, 0}, {2, 0}, {3, 0}, {4, 0}},
-	{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
-	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
-	{{1, 1}, {2, 1}, {3, 1}, {4, 1}},
-	{{1, 1}, {1, 2}, {1, 3}, {1, 4}},
-	{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
-	{{1, 2}, {2, 2}, {3, 2}, {4, 2}},
-	{{1, 3}, {2, 3}, {3, 3}, {4, 3}},
-	{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
-	{{2, 0}, {2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {2, 2}, {2, 3}, {2, 4}},
-	{{3, 0}, {3, 1}, {3, 2}, {3, 3}},
-	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
-	{{3, 1}, {3, 2}, {3, 3}, {3, 4}},
-	{{3, 1}, {2, 2}, {1, 3}, {0, 4}},
-	{{4, 0}, {4, 1}, {4, 2}, {4, 3}},
-	{{4, 0}, {3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}, {4, 4}},
-	{{4, 1}, {3, 2}, {2, 3}, {1, 4}},
-}
+// scores is a bias for each cell
+var scores []int
 
-var scores [5][5]int
-
-func readMove(bd *Board, print bool) (x, y int) {
+func readMove(bd *game.Bitboard, print bool) (x, y int) {
 	readMove := false
 	for !readMove {
 		if print {
@@ -424,13 +452,17 @@ func readMove(bd *Board, print bool) (x, y int) {
 			os.Exit(1)
 		}
 		switch {
-		case x < 0 || x > 4 || y < 0 || y > 4:
+		case !shape.OnBoard(x, y):
+			if print {
+				fmt.Printf("Choose a row from 0 to %d and a column from 0 to %d, try again\n", shape.Height()-1, shape.Width()-1)
+			}
+		case shape.AllCells()&game.Bit(shape.Cell(x, y)) == 0:
 			if print {
-				fmt.Printf("Choose two numbers between 0 and 4, try again\n")
+				fmt.Printf("Cell (%d, %d) is out of play, try again\n", x, y)
 			}
-		case bd[x][y] == 0:
+		case bd.At(shape.Cell(x, y)) == 0:
 			readMove = true
-		case bd[x][y] != 0:
+		case bd.At(shape.Cell(x, y)) != 0:
 			if print {
 				fmt.Printf("Cell (%d, %d) already occupied, try again\n", x, y)
 			}
@@ -439,21 +471,43 @@ func readMove(bd *Board, print bool) (x, y int) {
 	return x, y
 }
 
+// offerSwap asks the human whether to take over the computer's
+// first move, under the swap rule, and trades sides on bd if so.
+func offerSwap(bd *game.Bitboard, print bool) bool {
+	if print {
+		fmt.Printf("Swap, and take my move as yours? (y/n) ")
+	}
+	var answer string
+	if _, err := fmt.Scanf("%s\n", &answer); err == io.EOF {
+		os.Exit(0)
+	}
+	if !strings.HasPrefix(strings.ToLower(answer), "y") {
+		return false
+	}
+	*bd = bd.Swapped()
+	if print {
+		fmt.Printf("%v", bd)
+	}
+	return true
+}
+
+// setScores sets the bias for each cell. Boards other
+// than 5x5 get no bias unless it's random, and on a
+// torus no cell is a corner or on an edge.
 func setScores(randomize bool) {
+	scores = make([]int, shape.Cells())
 	if randomize {
 		vals := [11]int{-5, -4, -3 - 2, -1, 0, 1, 2, 3, 4, 5}
-		for i, row := range scores {
-			for j := range row {
-				scores[i][j] = vals[rand.Intn(11)]
-			}
+		for cell := range scores {
+			scores[cell] = vals[rand.Intn(11)]
 		}
-	} else {
-		scores = [5][5]int{
-			{3, 3, 0, 3, 3},
-			{3, 4, 1, 4, 3},
-			{0, 1, 0, 1, 0},
-			{3, 4, 1, 4, 3},
-			{3, 3, 0, 3, 3},
+	} else if shape == game.Standard {
+		scores = []int{
+			3, 3, 0, 3, 3,
+			3, 4, 1, 4, 3,
+			0, 1, 0, 1, 0,
+			3, 4, 1, 4, 3,
+			3, 3, 0, 3, 3,
 		}
 	}
 }
@@ -488,7 +542,7 @@ var firstMoves [4][2]int = [4][2]int{
 	{1, 1},
 }
 
-func bookDefend(bd *Board, firstX int, firstY int) int {
+func bookDefend(bd *game.Board, firstX int, firstY int) int {
 	state := FIRST
 	moveCount := 0
 
@@ -507,7 +561,7 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 						// Since <firstX, firstY> have an X, <a,b> must be empty
 						cX = a
 						cY = b
-						bd[cX][cY] = MAXIMIZER
+						bd[cX][cY] = game.MAXIMIZER
 						moveCount++
 						break OUTERFIRST
 					}
@@ -516,9 +570,10 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 			state = DIAGONAL
 
 			fmt.Printf("My move: %d %d\n", cX, cY)
-			printBoard(bd)
-			l, m := readMove(bd, true)
-			bd[l][m] = MINIMIZER
+			bd.Print()
+			b := game.NewBitboard(bd)
+			l, m := readMove(&b, true)
+			bd[l][m] = game.MINIMIZER
 			moveCount++
 
 		case DIAGONAL:
@@ -527,7 +582,7 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 		OUTERDIAGONAL:
 			for i, row := range bd {
 				for j, mark := range row {
-					if !(i == firstX && j == firstY) && mark == MINIMIZER {
+					if !(i == firstX && j == firstY) && mark == game.MINIMIZER {
 						lastx = i
 						lasty = j
 						break OUTERDIAGONAL
@@ -540,8 +595,8 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 				for j := -3; j < 4; j += 6 {
 					a, b := lastx+i, lasty+j
 					if a >= 0 && a <= 4 && b >= 0 && b <= 4 {
-						if bd[a][b] == UNSET {
-							bd[a][b] = MAXIMIZER
+						if bd[a][b] == game.UNSET {
+							bd[a][b] = game.MAXIMIZER
 							moveCount++
 							cX, cY = a, b
 							break FOUNDMOVE
@@ -550,14 +605,14 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 				}
 			}
 			fmt.Printf("My move: %d %d\n", cX, cY)
-			printBoard(bd)
+			bd.Print()
 		}
 	}
 
 	return moveCount
 }
 
-func bookStart(bd *Board) int {
+func bookStart(bd *game.Board) int {
 
 	state := FIRST
 	moveCount := 0
@@ -569,15 +624,15 @@ func bookStart(bd *Board) int {
 		case FIRST:
 			c := firstMoves[rand.Intn(4)]
 			cX, cY = c[0], c[1]
-			bd[cX][cY] = MAXIMIZER
+			bd[cX][cY] = game.MAXIMIZER
 			state = DIAGONAL
 			moveCount++
 		case DIAGONAL:
 			pX, pY := cX+3, cY+3
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cX += 3
 				cY += 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 				state = CORNER
 			} else {
@@ -585,15 +640,15 @@ func bookStart(bd *Board) int {
 			}
 		case CORNER:
 			pX, pY := cX-3, cY
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cX -= 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 			} else {
 				pX, pY = cX, cY-3
-				if bd[pX][pY] == UNSET {
+				if bd[pX][pY] == game.UNSET {
 					cY -= 3
-					bd[cX][cY] = MAXIMIZER
+					bd[cX][cY] = game.MAXIMIZER
 					moveCount++
 				}
 			}
@@ -601,31 +656,32 @@ func bookStart(bd *Board) int {
 		case OTHERCORNER:
 			// Didn't get desired diagonal
 			pX, pY := cX, cY+3
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cY += 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 				state = OTHERDIAGONAL
 			} else {
 				fmt.Printf("Unreachable state in OTHERCORNER\n")
-				printBoard(bd)
+				bd.Print()
 				os.Exit(99)
 			}
 		case OTHERDIAGONAL:
 			pX, pY := cX+3, cY-3
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cX += 3
 				cY -= 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 			}
 			state = LAST
 		}
 		if (moveCount % 2) == 1 {
 			fmt.Printf("My move: %d %d\n", cX, cY)
-			printBoard(bd)
-			l, m := readMove(bd, true)
-			bd[l][m] = MINIMIZER
+			bd.Print()
+			b := game.NewBitboard(bd)
+			l, m := readMove(&b, true)
+			bd[l][m] = game.MINIMIZER
 			moveCount++
 		}
 	}
@@ -637,7 +693,7 @@ func bookStart(bd *Board) int {
 // best possible move.
 
 type moveKeeper struct {
-	moves [25][2]int
+	moves [game.MaxCells][2]int
 	next  int // index into moves[]
 	max   int
 }
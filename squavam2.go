@@ -9,14 +9,13 @@ import (
 	"os"
 	"sync"
 	"time"
-)
 
-// UNSET denotes an empty cell on board.
-const UNSET = 0
+	"squava/src/game"
+)
 
 type GameState struct {
 	playerJustMoved int
-	board           [25]int
+	board           game.Board
 	cachedResults   [3]float64
 }
 
@@ -31,6 +30,10 @@ type Node struct {
 	lck             *sync.Mutex
 }
 
+// rules decides what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does
+var rules game.Rules
+
 func main() {
 
 	iterMax := flag.Int("i", 10000, "maximum iterations")
@@ -38,8 +41,15 @@ func main() {
 	threadCountPtr := flag.Int("N", 10, "Use this many threads")
 	computerFirstPtr := flag.Bool("C", false, "Computer takes first move")
 
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
 	flag.Parse()
 
+	var err error
+	if rules, err = game.ParseRules(*rulesName); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	state := NewGameState()
@@ -262,47 +272,16 @@ func (p *GameState) resetCachedResults() {
 
 func (p *GameState) DoMove(move int) {
 	p.playerJustMoved = -p.playerJustMoved
-	p.board[move] = p.playerJustMoved
+	x, y := game.Coords(move)
+	p.board.MakeMove(x, y, p.playerJustMoved)
 }
 
 func (p *GameState) GetMoves() ([]int, bool) {
-
-	// Only have to check the 9 cells in importantCells[]
-	// for 4 or 3 in a row configs.
-	for _, m := range importantCells {
-		if p.board[m] != UNSET {
-			for _, quad := range winningQuads[m] {
-				sum := p.board[quad[0]] + p.board[quad[1]] + p.board[quad[2]] + p.board[quad[3]]
-				if sum == 4 || sum == -4 {
-					return []int{}, true
-				}
-			}
-		}
-		if p.board[m] != UNSET {
-			for _, triplet := range losingTriplets[m] {
-				sum := p.board[triplet[0]] + p.board[triplet[1]] + p.board[triplet[2]]
-				if sum == 3 || sum == -3 {
-					return []int{}, true
-				}
-			}
-		}
-	}
-
-	// Get here, p.board does not represent a win or a loss.
-	// Pick out empty cells in p.board for a list of valid moves.
-	// I don't believe "cat" games exist in Squava, but this code
-	// handles that case.
-
-	endOfGame := true
-	var moves []int
-	for i := 0; i < 25; i++ {
-		if p.board[i] == 0 {
-			endOfGame = false
-			moves = append(moves, i)
-		}
-	}
-
-	return moves, endOfGame
+	// A board with a win or a loss on it has no
+	// valid moves. I don't believe "cat" games exist
+	// in Squava, but a full board ends the game too.
+	moves := p.board.Moves()
+	return moves, len(moves) == 0
 }
 
 func (p *GameState) GetResult(playerjm int) float64 {
@@ -310,61 +289,23 @@ func (p *GameState) GetResult(playerjm int) float64 {
 	if cached >= 0.0 {
 		return cached
 	}
-	// Need to check all 4-in-a-row wins before checking
-	// any 3-in-a-row losses, otherwise the result ends
-	// up wrong.
-	for _, i := range importantCells {
-		if p.board[i] != UNSET {
-			for _, quad := range winningQuads[i] {
-				sum := p.board[quad[0]] + p.board[quad[1]] + p.board[quad[2]] + p.board[quad[3]]
-				if sum == 4 || sum == -4 {
-					if sum == 4*playerjm {
-						p.cachedResults[playerjm+1] = 1.0
-						return 1.0
-					}
-					p.cachedResults[playerjm+1] = 0.0
-					return 0.0
-				}
-			}
-		}
-	}
-	for _, i := range importantCells {
-		if p.board[i] != UNSET {
-			for _, triplet := range losingTriplets[i] {
-				sum := p.board[triplet[0]] + p.board[triplet[1]] + p.board[triplet[2]]
-				if sum == 3 || sum == -3 {
-					if sum == 3*playerjm {
-						p.cachedResults[playerjm+1] = 0.0
-						return 0.0
-					}
-					p.cachedResults[playerjm+1] = 1.0
-					return 1.0
-				}
-			}
-		}
+	result := 0.0
+	if rules.BoardWinner(&p.board) == playerjm {
+		result = 1.0
 	}
-	return 0.0 // Should probably never get here.
+	p.cachedResults[playerjm+1] = result
+	return result
 }
 
 func (p *GameState) String() string {
-	s := "   0 1 2 3 4\n"
-	for i := 0; i < 25; i++ {
-		if (i % 5) == 0 {
-			s += string((i/5)+'0') + "  "
-		}
-		s += string("O_X"[p.board[i]+1]) + " "
-		if (i % 5) == 4 {
-			s += "\n"
-		}
-	}
-	return s
+	return p.board.String()
 }
 
 func (p *GameState) String2() string {
 	return fmt.Sprintf("%d, %v", p.playerJustMoved, p.board)
 }
 
-func readMove(bd *[25]int) int {
+func readMove(bd *game.Board) int {
 	readMove := false
 	var x, y, m int
 	for !readMove {
@@ -381,8 +322,8 @@ func readMove(bd *[25]int) int {
 		case x < 0 || x > 4 || y < 0 || y > 4:
 			fmt.Printf("Choose two numbers between 0 and 4, try again\n")
 		default:
-			m = 5*x + y
-			if bd[m] == 0 {
+			m = game.Cell(x, y)
+			if bd[x][y] == game.UNSET {
 				readMove = true
 			} else {
 				fmt.Printf("Cell (%d, %d) already occupied, try again\n", x, y)
@@ -391,47 +332,3 @@ func readMove(bd *[25]int) int {
 	}
 	return m
 }
-
-var importantCells = [9]int{2, 7, 10, 11, 12, 13, 14, 17, 22}
-
-// 25 rows only to make looping easier. The filled-in
-// rows are the only quads you actually have to check
-// to find out if there's a win
-var winningQuads = [25][][]int{
-	{}, {},
-	{{0, 1, 2, 3}, {1, 2, 3, 4}, {2, 7, 12, 17}},
-	{}, {}, {}, {},
-	{{5, 6, 7, 8}, {6, 7, 8, 9}, {7, 12, 17, 22}},
-	{}, {},
-	{{0, 5, 10, 15}, {5, 10, 15, 20}},
-	{{1, 6, 11, 16}, {6, 11, 16, 21}, {3, 7, 11, 15}, {5, 11, 17, 23}},
-	{{10, 11, 12, 13}, {11, 12, 13, 14}, {4, 8, 12, 16}, {8, 12, 16, 20}, {0, 6, 12, 18}, {6, 12, 18, 24}},
-	{{3, 8, 13, 18}, {8, 13, 18, 23}, {1, 7, 13, 19}, {9, 13, 17, 21}},
-	{{4, 9, 14, 19}, {9, 14, 19, 24}},
-	{}, {},
-	{{15, 16, 17, 18}, {16, 17, 18, 19}},
-	{}, {}, {}, {},
-	{{20, 21, 22, 23}, {21, 22, 23, 24}},
-	{}, {},
-}
-
-// 25 rows only to make looping easier. The filled-in
-// rows are the only triplets you actually have to check
-// to find out if there's a loss.
-var losingTriplets = [][][]int{
-	{}, {},
-	{{0, 1, 2}, {1, 2, 3}, {2, 3, 4}, {2, 7, 12}, {2, 6, 10}, {14, 8, 2}},
-	{}, {}, {}, {},
-	{{5, 6, 7}, {6, 7, 8}, {7, 8, 9}, {2, 7, 12}, {7, 12, 17}, {3, 7, 11}, {7, 11, 15}, {1, 7, 13}, {7, 13, 19}},
-	{}, {},
-	{{10, 11, 12}, {0, 5, 10}, {5, 10, 15}, {10, 15, 20}, {2, 6, 10}, {10, 16, 22}},
-	{{10, 11, 12}, {11, 12, 13}, {1, 6, 11}, {6, 11, 16}, {11, 16, 21}, {3, 7, 11}, {7, 11, 15}, {5, 11, 17}, {11, 17, 23}},
-	{{10, 11, 12}, {11, 12, 13}, {12, 13, 14}, {2, 7, 12}, {7, 12, 17}, {12, 17, 22}, {0, 6, 12}, {6, 12, 18}, {12, 18, 24}, {4, 8, 12}, {8, 12, 16}, {12, 16, 20}},
-	{{11, 12, 13}, {12, 13, 14}, {3, 8, 13}, {8, 13, 18}, {13, 18, 23}, {1, 7, 13}, {7, 13, 19}, {21, 17, 13}, {17, 13, 9}},
-	{{12, 13, 14}, {4, 9, 14}, {9, 14, 19}, {14, 19, 24}, {22, 18, 14}, {14, 8, 2}},
-	{}, {},
-	{{15, 16, 17}, {16, 17, 18}, {17, 18, 19}, {7, 12, 17}, {12, 17, 22}, {5, 11, 17}, {11, 17, 23}, {21, 17, 13}, {17, 13, 9}},
-	{}, {}, {}, {},
-	{{20, 21, 22}, {21, 22, 23}, {22, 23, 24}, {12, 17, 22}, {10, 16, 22}, {22, 18, 14}},
-	{}, {},
-}
@@ -8,38 +8,52 @@ import (
 	"os"
 	"runtime"
 	"time"
-)
 
-// Board is internal representation of a 5x5 tictactoe style
-// game board.
-type Board [5][5]int
+	"squava/src/clock"
+	"squava/src/game"
+	"squava/src/score"
+)
 
 type gameState struct {
-	bd        Board
+	bd        game.Board
 	maxDepth  int
 	x, y      int
 	value     int
 	leafNodes int
+	timer     searchTimer
 	next      *gameState
 }
 
+// searchTimer lets a worker's search give up at a deadline.
+// A zero deadline means no time limit.
+type searchTimer struct {
+	deadline  time.Time
+	nodeCount int
+	stopped   bool
+}
+
+// up checks the clock every so often, and
+// stops the search after the deadline.
+func (t *searchTimer) up() bool {
+	if t.deadline.IsZero() {
+		return false
+	}
+	if t.stopped {
+		return true
+	}
+	t.nodeCount++
+	if t.nodeCount&1023 == 0 && time.Now().After(t.deadline) {
+		t.stopped = true
+	}
+	return t.stopped
+}
+
 var toDo chan *gameState
 var finished chan *gameState
 
-// Manifest constants used repeatedly.
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
-)
-
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
+// rules decides what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does
+var rules game.Rules
 
 func main() {
 
@@ -54,23 +68,22 @@ func main() {
 	threadCountPtr := flag.Int("N", runtime.NumCPU(), "Use this many threads")
 	randomizeScores := flag.Bool("r", false, "Randomize bias scores")
 	useBook := flag.Bool("B", false, "Use book start or defense")
+	perMove := flag.Duration("t", 0, "time per move, iterative deepening instead of -d")
+	gameTime := flag.Duration("T", 0, "total time for computer's moves, 0 for no game clock")
+	increment := flag.Duration("I", 0, "time added to game clock after each move")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
 	flag.Parse()
 
-	*printBoardPtr = !*printBoardPtr
-
-	// Set up for use by deltaValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
+	var err error
+	if rules, err = game.ParseRules(*rulesName); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 
+	computerClock := clock.New(*perMove, *gameTime, *increment)
+
+	*printBoardPtr = !*printBoardPtr
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	toDo = make(chan *gameState, 25)
@@ -88,14 +101,14 @@ func main() {
 	}
 
 	moveCounter := 0
-	var bd Board
+	var bd game.Board
 
 	if *useBook {
 		fmt.Printf("Using opening book\n")
 		*firstMovePtr = ""
 		if humanFirst {
 			l, m := readMove(&bd, *printBoardPtr)
-			bd[l][m] = MINIMIZER
+			bd[l][m] = game.MINIMIZER
 			moveCounter = 1 + bookDefend(&bd, l, m)
 			if moveCounter%2 == 1 {
 				humanFirst = false
@@ -113,8 +126,8 @@ func main() {
 		}
 		fmt.Printf("My move: %d %d\n", x1, y1)
 		humanFirst = true
-		bd[x1][y1] = MAXIMIZER
-		printBoard(&bd)
+		bd[x1][y1] = game.MAXIMIZER
+		bd.Print()
 	}
 
 	endOfGame := false
@@ -126,7 +139,7 @@ func main() {
 		var l, m int
 		if humanFirst {
 			l, m = readMove(&bd, *printBoardPtr)
-			bd[l][m] = MINIMIZER
+			bd[l][m] = game.MINIMIZER
 			endOfGame, _ = deltaValue(100, &bd, 0, l, m, 0)
 			moveCounter++
 		}
@@ -138,20 +151,26 @@ func main() {
 		humanFirst = true
 
 		start := time.Now()
-		a, b, score, leaves := chooseMove(&bd, *deterministic, maxDepth)
+		var a, b, value, leaves int
+		if computerClock.Enabled() {
+			a, b, value, leaves = timedChooseMove(&bd, *deterministic, computerClock.Budget(len(bd.Moves())))
+		} else {
+			a, b, value, leaves, _ = chooseMove(&bd, *deterministic, maxDepth, time.Time{})
+		}
 		end := time.Now()
 		elapsed := end.Sub(start)
+		computerClock.Used(elapsed)
 
 		if a < 0 {
 			break // Cat gets the game
 		}
 
-		bd[a][b] = MAXIMIZER
+		bd[a][b] = game.MAXIMIZER
 		moveCounter++
 
 		if *printBoardPtr {
-			fmt.Printf("My move: %d %d (%d) [%d] %v\n", a, b, score, leaves, elapsed)
-			printBoard(&bd)
+			fmt.Printf("My move: %d %d (%v) [%d] %v\n", a, b, score.Score(value), leaves, elapsed)
+			bd.Print()
 		} else {
 			fmt.Printf("%d %d\n", a, b)
 		}
@@ -162,17 +181,17 @@ func main() {
 
 	if *printBoardPtr {
 		var phrase string
-		switch findWinner(&bd) {
-		case MAXIMIZER:
+		switch rules.BoardWinner(&bd) {
+		case game.MAXIMIZER:
 			phrase = "\nX wins\n"
-		case UNSET:
+		case game.UNSET:
 			phrase = "\nCat wins\n"
-		case MINIMIZER:
+		case game.MINIMIZER:
 			phrase = "\nO wins\n"
 		}
 		fmt.Print(phrase)
 
-		printBoard(&bd)
+		bd.Print()
 	}
 
 	if toDo != nil {
@@ -207,7 +226,7 @@ func oldState(gs *gameState) {
 
 // newState either allocates a new *gameState, or pulls one off
 // the stack of unused *gameStates.
-func newState(bd *Board, maxDepth int, value int, x int, y int) *gameState {
+func newState(bd *game.Board, maxDepth int, value int, x int, y int, deadline time.Time) *gameState {
 	var s *gameState
 	if stateStack == nil {
 		s = new(gameState)
@@ -218,6 +237,7 @@ func newState(bd *Board, maxDepth int, value int, x int, y int) *gameState {
 
 	s.maxDepth = maxDepth
 	s.value = value
+	s.timer = searchTimer{deadline: deadline}
 
 	for i, row := range bd {
 		for j, mark := range row {
@@ -227,70 +247,83 @@ func newState(bd *Board, maxDepth int, value int, x int, y int) *gameState {
 
 	s.x = x
 	s.y = y
-	s.bd[x][y] = MAXIMIZER
+	s.bd[x][y] = game.MAXIMIZER
 
 	return s
 }
 
 // Choose computer's next move: return x,y coords of move and its score.
-func chooseMove(bd *Board, deterministic bool, maxDepth int) (xcoord int, ycoord int, value int, leafNodes int) {
+// Searches that run past a non-zero deadline give up, and
+// chooseMove returns stopped true and a meaningless move.
+func chooseMove(bd *game.Board, deterministic bool, maxDepth int, deadline time.Time) (xcoord int, ycoord int, value int, leafNodes int, stopped bool) {
 
-	var moves = moveKeeper{max: 2 * LOSS}
+	var moves = moveKeeper{max: 2 * game.LOSS}
 	maxDepth--
 
+	queued := 0
 	for i, row := range bd {
 		for j, mark := range row {
-			if mark == UNSET {
+			if mark == game.UNSET {
 				stopRecursing, val := deltaValue(maxDepth, bd, 0, i, j, 0)
 				if stopRecursing {
-					moves.setMove(i, j, val)
+					moves.setMove(i, j, firstPly(val))
 					leafNodes++
 				} else {
-					gs := newState(bd, maxDepth, val, i, j)
+					gs := newState(bd, maxDepth, val, i, j, deadline)
 					toDo <- gs
+					queued++
 				}
 			}
 		}
 	}
 
-	for _, row := range bd {
-		for _, mark := range row {
-			if mark == UNSET {
-				gs := <-finished
-				moves.setMove(gs.x, gs.y, gs.value)
-				leafNodes += gs.leafNodes
-				oldState(gs)
-			}
-		}
+	for ; queued > 0; queued-- {
+		gs := <-finished
+		moves.setMove(gs.x, gs.y, gs.value)
+		leafNodes += gs.leafNodes
+		stopped = stopped || gs.timer.stopped
+		oldState(gs)
 	}
 
 	xcoord, ycoord, value = moves.chooseMove(deterministic)
-	return xcoord, ycoord, value, leafNodes
+	return xcoord, ycoord, value, leafNodes, stopped
 }
 
-func findWinner(bd *Board) int {
-	for _, quad := range winningQuads {
-		sum := bd[quad[0][0]][quad[0][1]]
-		sum += bd[quad[1][0]][quad[1][1]]
-		sum += bd[quad[2][0]][quad[2][1]]
-		sum += bd[quad[3][0]][quad[3][1]]
+// timedChooseMove deepens the search one ply at a time until
+// limit runs out, and returns the move found by the deepest
+// search that finished.
+func timedChooseMove(bd *game.Board, deterministic bool, limit time.Duration) (xcoord int, ycoord int, value int, leafNodes int) {
+	start := time.Now()
+	deadline := start.Add(limit)
 
-		if sum == 4 || sum == -4 {
-			return bd[quad[0][0]][quad[0][1]]
+	empty := 0
+	for _, row := range bd {
+		for _, mark := range row {
+			if mark == game.UNSET {
+				empty++
+			}
 		}
 	}
 
-	for _, triplet := range losingTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -bd[triplet[0][0]][triplet[0][1]]
+	// Depth 1 only values the computer's own moves, and
+	// never runs out of time, so there's always a move.
+	xcoord, ycoord = -1, -1
+	for depth := 1; depth <= empty; depth++ {
+		a, b, v, leaves, stopped := chooseMove(bd, deterministic, depth, deadline)
+		leafNodes += leaves
+		if stopped {
+			break
+		}
+		xcoord, ycoord, value = a, b, v
+		if score.Score(value).Decided() {
+			break // forced win or loss, deeper won't change it
+		}
+		if time.Since(start) > limit/2 {
+			break // next depth would take longer than what's left
 		}
 	}
 
-	return 0
+	return xcoord, ycoord, value, leafNodes
 }
 
 // 4-in-a-row where you don't want to have the middle 2
@@ -310,36 +343,39 @@ var no2 = [4][3][2]int{
 	{{4, 2}, {3, 1}, {2, 0}},
 }
 
+// firstPly fixes up the value deltaValue gives the computer's own
+// move. deltaValue calls that move ply 0, so that depth 0 searches
+// stop there, but a win or loss on it comes on the first ply.
+func firstPly(value int) int {
+	switch s := score.Score(value); {
+	case s.IsWin():
+		return int(score.Win(1))
+	case s.IsLoss():
+		return int(score.Loss(1))
+	}
+	return value
+}
+
 // Calculates and returns the value of the move (x,y)
 // Only considers value gained or lost from the cell (x,y)
-func deltaValue(maxDepth int, bd *Board, ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
+func deltaValue(maxDepth int, bd *game.Board, ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
 
-	relevantQuads := indexedWinningQuads[x][y]
+	if winner := rules.BoardMoveWinner(bd, x, y); winner != game.UNSET {
+		return true, winner * int(score.Win(ply))
+	}
+
+	relevantQuads := game.IndexedWinningQuads[x][y]
 	for _, quad := range relevantQuads {
 		sum := bd[quad[0][0]][quad[0][1]]
 		sum += bd[quad[1][0]][quad[1][1]]
 		sum += bd[quad[2][0]][quad[2][1]]
 		sum += bd[quad[3][0]][quad[3][1]]
 
-		if sum == 4 || sum == -4 {
-			return true, bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-		}
 		if sum == 3 || sum == -3 {
 			value += sum * 10
 		}
 	}
 
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := bd[triplet[0][0]][triplet[0][1]]
-		sum += bd[triplet[1][0]][triplet[1][1]]
-		sum += bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return true, -sum / 3 * (WIN - ply)
-		}
-	}
-
 	for _, triplet := range no2 {
 		for _, pair := range triplet {
 			if x == pair[0] && y == pair[1] {
@@ -386,10 +422,14 @@ func deltaValue(maxDepth int, bd *Board, ply int, x, y int, currentValue int) (s
 	return stopRecursing, value
 }
 
-func alphaBeta(maxDepth int, bd *Board, ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int, leafNodes int) {
+func alphaBeta(maxDepth int, bd *game.Board, ply int, player int, alpha int, beta int, x int, y int, boardValue int, timer *searchTimer) (value int, leafNodes int) {
 
 	leafNodes = 0
 
+	if timer.up() {
+		return 0, 0
+	}
+
 	stopRecursing, delta := deltaValue(maxDepth, bd, ply, x, y, boardValue)
 	if stopRecursing {
 		return delta, 1
@@ -398,14 +438,14 @@ func alphaBeta(maxDepth int, bd *Board, ply int, player int, alpha int, beta int
 	boardValue += delta
 
 	switch player {
-	case MAXIMIZER:
-		value = 2 * LOSS // Possible to score less than LOSS
+	case game.MAXIMIZER:
+		value = 2 * game.LOSS // Possible to score less than LOSS
 		for i, row := range bd {
 			for j, marker := range row {
-				if marker == UNSET {
-					bd[i][j] = MAXIMIZER
-					n, leaves := alphaBeta(maxDepth, bd, ply+1, MINIMIZER, alpha, beta, i, j, boardValue)
-					bd[i][j] = UNSET
+				if marker == game.UNSET {
+					bd[i][j] = game.MAXIMIZER
+					n, leaves := alphaBeta(maxDepth, bd, ply+1, game.MINIMIZER, alpha, beta, i, j, boardValue, timer)
+					bd[i][j] = game.UNSET
 					leafNodes += leaves
 					if n > value {
 						value = n
@@ -419,14 +459,14 @@ func alphaBeta(maxDepth int, bd *Board, ply int, player int, alpha int, beta int
 				}
 			}
 		}
-	case MINIMIZER:
-		value = 2 * WIN // You can score greater than WIN
+	case game.MINIMIZER:
+		value = 2 * game.WIN // You can score greater than WIN
 		for i, row := range bd {
 			for j, marker := range row {
-				if marker == UNSET {
+				if marker == game.UNSET {
 					bd[i][j] = player
-					n, leaves := alphaBeta(maxDepth, bd, ply+1, -player, alpha, beta, i, j, boardValue)
-					bd[i][j] = UNSET
+					n, leaves := alphaBeta(maxDepth, bd, ply+1, -player, alpha, beta, i, j, boardValue, timer)
+					bd[i][j] = game.UNSET
 					leafNodes += leaves
 					if n < value {
 						value = n
@@ -452,12 +492,13 @@ func worker(sn int, from chan *gameState, to chan *gameState) {
 				curr.maxDepth,
 				&(curr.bd),
 				1,
-				MINIMIZER,
-				2*LOSS,
-				2*WIN,
+				game.MINIMIZER,
+				2*game.LOSS,
+				2*game.WIN,
 				curr.x,
 				curr.y,
-				curr.value)
+				curr.value,
+				&curr.timer)
 			to <- curr
 		} else {
 			return
@@ -465,101 +506,9 @@ func worker(sn int, from chan *gameState, to chan *gameState) {
 	}
 }
 
-func printBoard(bd *Board) {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			fmt.Printf("%s ", string("O_X"[v+1]))
-		}
-		fmt.Printf("\n")
-	}
-}
-
-var losingTriplets = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}},
-	{{0, 0}, {0, 1}, {0, 2}},
-	{{0, 0}, {1, 1}, {2, 2}},
-	{{1, 0}, {2, 0}, {3, 0}},
-	{{1, 0}, {1, 1}, {1, 2}},
-	{{1, 0}, {2, 1}, {3, 2}},
-	{{2, 0}, {3, 0}, {4, 0}},
-	{{2, 0}, {2, 1}, {2, 2}},
-	{{2, 0}, {1, 1}, {0, 2}},
-	{{2, 0}, {3, 1}, {4, 2}},
-	{{3, 0}, {3, 1}, {3, 2}},
-	{{3, 0}, {2, 1}, {1, 2}},
-	{{4, 0}, {4, 1}, {4, 2}},
-	{{4, 0}, {3, 1}, {2, 2}},
-	{{0, 1}, {1, 1}, {2, 1}},
-	{{0, 1}, {0, 2}, {0, 3}},
-	{{0, 1}, {1, 2}, {2, 3}},
-	{{1, 1}, {2, 1}, {3, 1}},
-	{{1, 1}, {1, 2}, {1, 3}},
-	{{1, 1}, {2, 2}, {3, 3}},
-	{{2, 1}, {3, 1}, {4, 1}},
-	{{2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {1, 2}, {0, 3}},
-	{{2, 1}, {3, 2}, {4, 3}},
-	{{3, 1}, {3, 2}, {3, 3}},
-	{{3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}},
-	{{4, 1}, {3, 2}, {2, 3}},
-	{{0, 2}, {1, 2}, {2, 2}},
-	{{0, 2}, {0, 3}, {0, 4}},
-	{{0, 2}, {1, 3}, {2, 4}},
-	{{1, 2}, {2, 2}, {3, 2}},
-	{{1, 2}, {1, 3}, {1, 4}},
-	{{1, 2}, {2, 3}, {3, 4}},
-	{{2, 2}, {3, 2}, {4, 2}},
-	{{2, 2}, {2, 3}, {2, 4}},
-	{{2, 2}, {1, 3}, {0, 4}},
-	{{2, 2}, {3, 3}, {4, 4}},
-	{{3, 2}, {3, 3}, {3, 4}},
-	{{3, 2}, {2, 3}, {1, 4}},
-	{{4, 2}, {4, 3}, {4, 4}},
-	{{4, 2}, {3, 3}, {2, 4}},
-	{{0, 3}, {1, 3}, {2, 3}},
-	{{1, 3}, {2, 3}, {3, 3}},
-	{{2, 3}, {3, 3}, {4, 3}},
-	{{0, 4}, {1, 4}, {2, 4}},
-	{{1, 4}, {2, 4}, {3, 4}},
-	{{2, 4}, {3, 4}, {4, 4}},
-}
-var winningQuads = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
-	{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
-	{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
-	{{0, 1}, {1, 1}, {2, 1}, {3, 1}},
-	{{0, 1}, {0, 2}, {0, 3}, {0, 4}},
-	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
-	{{0, 2}, {1, 2}, {2, 2}, {3, 2}},
-	{{0, 3}, {1, 3}, {2, 3}, {3, 3}},
-	{{0, 4}, {1, 4}, {2, 4}, {3, 4}},
-	{{1, 0}, {2, 0}, {3, 0}, {4, 0}},
-	{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
-	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
-	{{1, 1}, {2, 1}, {3, 1}, {4, 1}},
-	{{1, 1}, {1, 2}, {1, 3}, {1, 4}},
-	{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
-	{{1, 2}, {2, 2}, {3, 2}, {4, 2}},
-	{{1, 3}, {2, 3}, {3, 3}, {4, 3}},
-	{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
-	{{2, 0}, {2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {2, 2}, {2, 3}, {2, 4}},
-	{{3, 0}, {3, 1}, {3, 2}, {3, 3}},
-	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
-	{{3, 1}, {3, 2}, {3, 3}, {3, 4}},
-	{{3, 1}, {2, 2}, {1, 3}, {0, 4}},
-	{{4, 0}, {4, 1}, {4, 2}, {4, 3}},
-	{{4, 0}, {3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}, {4, 4}},
-	{{4, 1}, {3, 2}, {2, 3}, {1, 4}},
-}
-
 var scores [5][5]int
 
-func readMove(bd *Board, printit bool) (x, y int) {
+func readMove(bd *game.Board, printit bool) (x, y int) {
 	readMove := false
 	for !readMove {
 		if printit {
@@ -571,7 +520,7 @@ func readMove(bd *Board, printit bool) (x, y int) {
 	return x, y
 }
 
-func checkMove(bd *Board, x, y int, printit bool) bool {
+func checkMove(bd *game.Board, x, y int, printit bool) bool {
 	r := false
 	switch {
 	case x < 0 || x > 4 || y < 0 || y > 4:
@@ -659,7 +608,7 @@ var firstMoves = [4][2]int{
 	{1, 1},
 }
 
-func bookDefend(bd *Board, firstX int, firstY int) int {
+func bookDefend(bd *game.Board, firstX int, firstY int) int {
 	state := FIRST
 	moveCount := 0
 
@@ -676,7 +625,7 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 					if a >= 0 && a <= 4 && b >= 0 && b <= 4 {
 						// Since <firstX, firstY> have an X, <a,b> must be empty
 						cX, cY = a, b
-						bd[cX][cY] = MAXIMIZER
+						bd[cX][cY] = game.MAXIMIZER
 						moveCount++
 						break OUTERFIRST
 					}
@@ -685,9 +634,9 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 			state = DIAGONAL
 
 			fmt.Printf("My move: %d %d\n", cX, cY)
-			printBoard(bd)
+			bd.Print()
 			l, m := readMove(bd, true)
-			bd[l][m] = MINIMIZER
+			bd[l][m] = game.MINIMIZER
 			moveCount++
 
 		case DIAGONAL:
@@ -696,7 +645,7 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 		OUTERDIAGONAL:
 			for i, row := range bd {
 				for j, mark := range row {
-					if !(i == firstX && j == firstY) && mark == MINIMIZER {
+					if !(i == firstX && j == firstY) && mark == game.MINIMIZER {
 						lastx = i
 						lasty = j
 						break OUTERDIAGONAL
@@ -711,8 +660,8 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 				for j := -3; j < 4; j += 6 {
 					b := lasty + j
 					if a >= 0 && a <= 4 && b >= 0 && b <= 4 {
-						if bd[a][b] == UNSET {
-							bd[a][b] = MAXIMIZER
+						if bd[a][b] == game.UNSET {
+							bd[a][b] = game.MAXIMIZER
 							moveCount++
 							cX, cY = a, b
 							break FOUNDMOVE
@@ -726,14 +675,14 @@ func bookDefend(bd *Board, firstX int, firstY int) int {
 				break // out of for-loop over state
 			}
 			fmt.Printf("My move: %d %d\n", cX, cY)
-			printBoard(bd)
+			bd.Print()
 		}
 	}
 
 	return moveCount
 }
 
-func bookStart(bd *Board) int {
+func bookStart(bd *game.Board) int {
 
 	state := FIRST
 	moveCount := 0
@@ -745,15 +694,15 @@ func bookStart(bd *Board) int {
 		case FIRST:
 			c := firstMoves[rand.Intn(4)]
 			cX, cY = c[0], c[1]
-			bd[cX][cY] = MAXIMIZER
+			bd[cX][cY] = game.MAXIMIZER
 			state = DIAGONAL
 			moveCount++
 		case DIAGONAL:
 			pX, pY := cX+3, cY+3
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cX += 3
 				cY += 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 				state = CORNER
 			} else {
@@ -761,15 +710,15 @@ func bookStart(bd *Board) int {
 			}
 		case CORNER:
 			pX, pY := cX-3, cY
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cX -= 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 			} else {
 				pX, pY = cX, cY-3
-				if bd[pX][pY] == UNSET {
+				if bd[pX][pY] == game.UNSET {
 					cY -= 3
-					bd[cX][cY] = MAXIMIZER
+					bd[cX][cY] = game.MAXIMIZER
 					moveCount++
 				}
 			}
@@ -777,31 +726,31 @@ func bookStart(bd *Board) int {
 		case OTHERCORNER:
 			// Didn't get desired diagonal
 			pX, pY := cX, cY+3
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cY += 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 				state = OTHERDIAGONAL
 			} else {
 				fmt.Printf("Unreachable state in OTHERCORNER\n")
-				printBoard(bd)
+				bd.Print()
 				os.Exit(99)
 			}
 		case OTHERDIAGONAL:
 			pX, pY := cX+3, cY-3
-			if bd[pX][pY] == UNSET {
+			if bd[pX][pY] == game.UNSET {
 				cX += 3
 				cY -= 3
-				bd[cX][cY] = MAXIMIZER
+				bd[cX][cY] = game.MAXIMIZER
 				moveCount++
 			}
 			state = LAST
 		}
 		if (moveCount % 2) == 1 {
 			fmt.Printf("My move: %d %d\n", cX, cY)
-			printBoard(bd)
+			bd.Print()
 			l, m := readMove(bd, true)
-			bd[l][m] = MINIMIZER
+			bd[l][m] = game.MINIMIZER
 			moveCount++
 		}
 	}
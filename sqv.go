@@ -1,18 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	"squava/src/alphabeta"
+	"squava/src/engine"
+	"squava/src/game"
 	"squava/src/mcts"
-	"squava/src/mcts2"
 	"squava/src/mcts3"
+	"squava/src/tablebase"
 )
 
 const (
@@ -20,72 +24,145 @@ const (
 	COMPUTER = 1
 )
 
-type Player interface {
-	Name() string
-	MakeMove(int, int, int) // x,y coords, type of player (HUMAN, COMPUTER)
-	SetDepth(int)
-	ChooseMove() (int, int, int, int) // x,y coords of move, value, leaf node count
-	PrintBoard()
-	SetScores(bool)
-	FindWinner() int
-}
-
 func main() {
 
 	computerFirstPtr := flag.Bool("C", false, "Computer takes first move (default false)")
 	randomizeScores := flag.Bool("r", false, "Randomize bias scores")
 	maxDepthPtr := flag.Int("d", 10, "maximum lookahead depth (alpha/beta)")
-	typ := flag.String("t", "A", "first player type, A: alphabeta, G: A/B+avoid bad positions, M: MCTS")
+	typ := flag.String("t", "A", "first player type, A: alphabeta, G: A/B+avoid bad positions, M: MCTS, P: plain MCTS")
 	u := flag.Float64("u", 0.50, "UCTK coefficient, player 1 (MCTS)")
 	i := flag.Int("i", 500000, "MCTS iterations, player 1")
+	tbFile := flag.String("tb", "", "tablebase file, from tbgen, for perfect play late in the game")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	xMarks := flag.String("x", "", "X's marks already on the board, like \"2,2 1,3\"")
+	oMarks := flag.String("o", "", "O's marks already on the board, like \"2,2 1,3\"")
+	forbid := flag.String("forbid", "", "cells nobody can mark, like \"2,2 1,3\"")
+	swap := flag.Bool("swap", false, "after the first move, the second player can take over the first player's side")
 	flag.Parse()
 
+	rules, err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	shape, start, err := game.ParseStart(game.Standard, *xMarks, *oMarks, *forbid, *swap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// X moves first, whoever that is
+	xPlayer := HUMAN
+	if *computerFirstPtr {
+		xPlayer = COMPUTER
+	}
+
+	// computerPlayer keeps track of the board internally,
+	// but we'll keep track too, so the human can be informed
+	// that an input move has already been taken, and so
+	// that the game package decides who won.
+	bd := start.Board(shape, xPlayer)
+	if rules.Winner(&bd) != game.UNSET {
+		fmt.Fprintf(os.Stderr, "The marks already on the board decide the game\n")
+		os.Exit(1)
+	}
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	var winner int
 
-	moveCounter := 0
-
 	computerPlayer := createPlayer(*typ, *maxDepthPtr, *u, *i)
 
+	computerPlayer.SetRules(rules)
+
+	if shape != game.Standard {
+		s, ok := computerPlayer.(engine.Shaper)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s only plays on the standard 5x5 board\n", computerPlayer.Name())
+			os.Exit(1)
+		}
+		s.SetShape(shape)
+	}
+	// After SetShape, which clears the biases
 	computerPlayer.SetScores(*randomizeScores)
+	if _, ok := computerPlayer.(engine.Swapper); start.Swap && !ok {
+		fmt.Fprintf(os.Stderr, "%s can't play by the swap rule\n", computerPlayer.Name())
+		os.Exit(1)
+	}
+	engine.SetStart(computerPlayer, shape, start, xPlayer)
 
-	next := HUMAN
-	if *computerFirstPtr {
-		next = COMPUTER
+	if *tbFile != "" {
+		tb, err := tablebase.Load(*tbFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *tbFile, err)
+			os.Exit(1)
+		}
+		if tb.Rules() != rules {
+			fmt.Fprintf(os.Stderr, "%s: tablebase for -rule %v, not %v\n", *tbFile, tb.Rules(), rules)
+			os.Exit(1)
+		}
+		if shape != game.Standard {
+			fmt.Fprintf(os.Stderr, "%s: tablebases are only for the standard 5x5 board\n", *tbFile)
+			os.Exit(1)
+		}
+		if t, ok := computerPlayer.(interface{ SetTablebase(*tablebase.Table) }); ok {
+			t.SetTablebase(tb)
+		}
 	}
 
-	// computerPlayer keeps track of the board internally,
-	// but we'll keep track too, so the human can be informed
-	// that an input move has already been taken.
-	bd := new(Board)
+	next := xPlayer
 
-	for moveCounter < 25 {
+	// Under the swap rule, the second player can take
+	// over the first player's side after the first move.
+	firstMove := bd.Count() + 1
+
+	for bd.Empty() != 0 {
 
 		switch next {
 
 		case HUMAN:
-			l, m := bd.readMove()
+			l, m := readMove(&bd, shape)
 			computerPlayer.MakeMove(l, m, HUMAN)
 			next = COMPUTER
 
+			if start.Swap && bd.Count() == firstMove {
+				computerPlayer.SetDepth(bd.Count())
+				if engine.WantSwap(context.Background(), computerPlayer, shape, engine.Move{X: l, Y: m}) {
+					computerPlayer.(engine.Swapper).SwapSides()
+					bd = bd.Swapped()
+					fmt.Printf("%s swaps, your first move is mine\n", computerPlayer.Name())
+					next = HUMAN
+				}
+			}
+
 		case COMPUTER:
-			computerPlayer.SetDepth(moveCounter)
+			computerPlayer.SetDepth(bd.Count())
 
-			before := time.Now()
-			i, j, value, leafCount := computerPlayer.ChooseMove()
-			et := time.Since(before)
+			// Interrupting the search makes the computer
+			// move now, with the best move it's found so far.
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			move, info, err := computerPlayer.ChooseMove(ctx)
+			stop()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", computerPlayer.Name(), err)
+				os.Exit(1)
+			}
 
-			fmt.Printf("X (%s) <%d,%d> (%d) [%d] %v\n", computerPlayer.Name(), i, j, value, leafCount, et)
+			fmt.Printf("X (%s) %v (%v) [%d] %v pv %v\n", computerPlayer.Name(), move, info.Value, info.LeafCount, info.Elapsed, info.PV)
 
-			bd.makeMove(i, j, COMPUTER)
+			bd.MakeMove(shape.Cell(move.X, move.Y), COMPUTER)
 			next = HUMAN
+
+			if start.Swap && bd.Count() == firstMove && rules.Winner(&bd) == game.UNSET && offerSwap() {
+				computerPlayer.(engine.Swapper).SwapSides()
+				bd = bd.Swapped()
+				next = COMPUTER
+			}
 		}
 
-		moveCounter++
-		winner = computerPlayer.FindWinner()
+		winner = rules.Winner(&bd)
 
-		if winner != 0 || moveCounter >= 25 {
+		if winner != game.UNSET || bd.Empty() == 0 {
 			break
 		}
 
@@ -93,9 +170,9 @@ func main() {
 	}
 
 	switch winner {
-	case 1:
+	case COMPUTER:
 		fmt.Printf("player 1 X (%s) wins\n", computerPlayer.Name())
-	case -1:
+	case HUMAN:
 		fmt.Printf("player 2 O (human) wins\n")
 	default:
 		fmt.Printf("Cat wins\n")
@@ -104,11 +181,11 @@ func main() {
 	computerPlayer.PrintBoard()
 }
 
-func createPlayer(typ string, maxDepth int, factor float64, iterations int) Player {
+func createPlayer(typ string, maxDepth int, factor float64, iterations int) engine.Engine {
 
 	typ = strings.ToUpper(typ)
 
-	var computerPlayer Player
+	var computerPlayer engine.Engine
 
 	switch typ {
 	case "A":
@@ -120,10 +197,6 @@ func createPlayer(typ string, maxDepth int, factor float64, iterations int) Play
 		computerPlayer = mcts.New(false, maxDepth)
 		computerPlayer.(*mcts.MCTS).SetUCTK(factor)
 		computerPlayer.(*mcts.MCTS).SetIterations(iterations)
-	case "L":
-		computerPlayer = mcts2.New(false, maxDepth)
-		computerPlayer.(*mcts2.MCTS2).SetUCTK(factor)
-		computerPlayer.(*mcts2.MCTS2).SetIterations(iterations)
 	case "P":
 		computerPlayer = mcts3.New(false, maxDepth)
 		computerPlayer.(*mcts3.MCTS3).SetIterations(iterations)
@@ -132,13 +205,7 @@ func createPlayer(typ string, maxDepth int, factor float64, iterations int) Play
 	return computerPlayer
 }
 
-type Board [5][5]int
-
-func (bd *Board) makeMove(x, y, player int) {
-	bd[x][y] = player
-}
-
-func (bd *Board) readMove() (x, y int) {
+func readMove(bd *game.Bitboard, shape *game.Shape) (x, y int) {
 	readMove := false
 	for !readMove {
 		fmt.Printf("Your move: ")
@@ -151,14 +218,27 @@ func (bd *Board) readMove() (x, y int) {
 			os.Exit(1)
 		}
 		switch {
-		case x < 0 || x > 4 || y < 0 || y > 4:
+		case !shape.OnBoard(x, y):
 			fmt.Printf("Choose two numbers between 0 and 4, try again\n")
-		case bd[x][y] == 0:
+		case shape.AllCells()&game.Bit(shape.Cell(x, y)) == 0:
+			fmt.Printf("Cell (%d, %d) is out of play, try again\n", x, y)
+		case bd.At(shape.Cell(x, y)) == game.UNSET:
 			readMove = true
-		case bd[x][y] != 0:
+		case bd.At(shape.Cell(x, y)) != game.UNSET:
 			fmt.Printf("Cell (%d, %d) already occupied, try again\n", x, y)
 		}
 	}
-	bd.makeMove(x, y, HUMAN)
+	bd.MakeMove(shape.Cell(x, y), HUMAN)
 	return x, y
 }
+
+// offerSwap asks the human whether to take over the
+// computer's first move, under the swap rule
+func offerSwap() bool {
+	fmt.Printf("Swap, and take my move as yours? (y/n) ")
+	var answer string
+	if _, err := fmt.Scanf("%s\n", &answer); err == io.EOF {
+		os.Exit(0)
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y")
+}
@@ -1,28 +1,22 @@
 package abbook
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
 
-	"squava/src/movekeeper"
-)
-
-type board [5][5]int
-
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
+	"squava/src/alphabeta"
+	"squava/src/engine"
+	"squava/src/game"
 )
 
+// AlphaBetaBook plays an opening book for the first few
+// moves, then an alphabeta.AlphaBeta search. It keeps its own
+// board for the book to look at.
 type AlphaBetaBook struct {
-	bd             *board
-	leafNodeCount  int
-	maxDepth       int
-	deterministic  bool
+	*alphabeta.AlphaBeta
+	bd             *game.Board
 	moveCount      int
 	state          int
 	c_x            int
@@ -32,37 +26,10 @@ type AlphaBetaBook struct {
 	bookInProgress bool
 }
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
-var indexedCalcs bool = false
-
-func calculateIndexedMatrices() {
-	// Set up for use by deltaValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
-	}
-}
-
 func New(deterministic bool, maxdepth int) *AlphaBetaBook {
-	if !indexedCalcs {
-		calculateIndexedMatrices()
-		indexedCalcs = true
-	}
 	var r AlphaBetaBook
-	r.bd = new(board)
-	r.maxDepth = maxdepth
-	r.deterministic = deterministic
+	r.AlphaBeta = alphabeta.New(deterministic, maxdepth)
+	r.bd = new(game.Board)
 	r.state = FIRST
 	r.bookInProgress = true
 	return &r
@@ -74,23 +41,52 @@ func (p *AlphaBetaBook) Name() string {
 
 func (p *AlphaBetaBook) MakeMove(x, y int, player int) {
 	p.moveCount++
-	p.bd[x][y] = player
+	if p.bookInProgress {
+		p.bd.MakeMove(x, y, player)
+	}
+	p.AlphaBeta.MakeMove(x, y, player)
+}
+
+// SetShape starts over with an empty board of shape. The
+// book only knows 5x5 openings, so other boards go without.
+func (p *AlphaBetaBook) SetShape(shape *game.Shape) {
+	p.AlphaBeta.SetShape(shape)
+	p.bd = new(game.Board)
+	p.moveCount = 0
+	p.state = FIRST
+	p.bookInProgress = shape == game.Standard
+}
+
+// SetStart leaves the book for any start but the usual one.
+// The book only knows openings from an empty board.
+func (p *AlphaBetaBook) SetStart(start game.Start) {
+	if !start.Usual() {
+		p.bookInProgress = false
+	}
+}
+
+// SwapSides trades the player's marks for its opponent's,
+// for the swap rule
+func (p *AlphaBetaBook) SwapSides() {
+	p.AlphaBeta.SwapSides()
+	p.bookInProgress = false
 }
 
 func (p *AlphaBetaBook) SetDepth(moveCounter int) {
 	if moveCounter < 4 {
-		p.maxDepth = 6
+		p.SetMaxDepth(6)
 	}
 	if moveCounter > 3 {
-		p.maxDepth = 8
+		p.SetMaxDepth(8)
 	}
 	if moveCounter > 10 {
-		p.maxDepth = 10
+		p.SetMaxDepth(10)
 	}
 }
 
-// Choose computer's next move: return x,y coords of move and its score.
-func (p *AlphaBetaBook) ChooseMove() (xcoord int, ycoord int, value int, leafcount int) {
+// ChooseMove - choose computer's next move from the book,
+// or from the embedded AlphaBeta once out of the book.
+func (p *AlphaBetaBook) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
 
 	if p.bookInProgress {
 		if (p.moveCount % 2) == 0 {
@@ -99,327 +95,23 @@ func (p *AlphaBetaBook) ChooseMove() (xcoord int, ycoord int, value int, leafcou
 			p.bookDefend()
 		}
 		if p.c_x != -1 && p.c_y != -1 {
-			p.MakeMove(p.c_x, p.c_y, MAXIMIZER)
-			return p.c_x, p.c_y, 0, 0
-		}
-	}
-
-	moves := movekeeper.New(2*LOSS, p.deterministic)
-
-	p.leafNodeCount = 0
-
-	for i, row := range p.bd {
-		for j, mark := range row {
-			if mark == UNSET {
-				p.bd[i][j] = MAXIMIZER
-				stop, value := p.deltaValue(0, i, j, 0)
-				if !stop {
-					value = p.alphaBeta(1, MINIMIZER, 2*LOSS, 2*WIN, i, j, value)
-				}
-				p.bd[i][j] = UNSET
-				moves.SetMove(i, j, value)
-			}
-		}
-	}
-
-	a, b, v := moves.ChooseMove()
-
-	p.MakeMove(a, b, MAXIMIZER)
-
-	return a, b, v, p.leafNodeCount
-}
-
-func (p *AlphaBetaBook) findWinner() int {
-	for _, quad := range winningQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return p.bd[quad[0][0]][quad[0][1]]
-		}
-	}
-
-	for _, triplet := range losingTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -p.bd[triplet[0][0]][triplet[0][1]]
+			p.MakeMove(p.c_x, p.c_y, game.MAXIMIZER)
+			move := engine.Move{X: p.c_x, Y: p.c_y}
+			return move, engine.SearchInfo{PV: []engine.Move{move}}, nil
 		}
 	}
 
-	return 0
-}
-
-// It turns out that you only have to look at
-// the 4-in-a-rows that contain these 9 cells
-// to check every 4-in-a-row. Similarly, you
-// only need to check these 9 cells to check
-// all the losing 3-in-a-row combos. You don't
-// have to look at each and every cell.
-var checkableCells [9][2]int = [9][2]int{
-	{0, 2}, {1, 2}, {2, 0},
-	{2, 1}, {2, 2}, {2, 3},
-	{2, 4}, {3, 2}, {4, 2},
-}
-
-// Calculates and returns the value of the move (x,y)
-// Only considers value gained or lost from the cell (x,y)
-func (p *AlphaBetaBook) deltaValue(ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
-
-	relevantQuads := indexedWinningQuads[x][y]
-	for _, quad := range relevantQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return true, p.bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-		}
-		if sum == 3 || sum == -3 {
-			value += sum * 10
-		}
+	// The embedded AlphaBeta makes the move on its own board
+	move, info, err := p.AlphaBeta.ChooseMove(ctx)
+	if err != nil || move == engine.NoMove {
+		return move, info, err
 	}
-
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return true, sum / 3 * (LOSS + ply)
-		}
-	}
-
-	// Give it a slight bias for those early
-	// moves when all losing-triplets and winning-quads
-	// are beyond the horizon.
-	value += p.bd[x][y] * scores[x][y]
-
-	// If squava has a "cat game", then this is wrong. Cat
-	// games could stop recursing here.
-	stopRecursing = false
-	if ply >= p.maxDepth {
-		stopRecursing = true
-		value += currentValue
-	}
-
-	return stopRecursing, value
-}
-
-func (p *AlphaBetaBook) alphaBeta(ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int) {
-
-	switch player {
-	case MAXIMIZER:
-		value = 2 * LOSS
-		for i, row := range p.bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					p.bd[i][j] = MAXIMIZER
-					stopRecursing, delta := p.deltaValue(ply, x, y, boardValue)
-					if stopRecursing {
-						p.bd[i][j] = UNSET
-						p.leafNodeCount++
-						return delta
-					}
-					n := p.alphaBeta(ply+1, MINIMIZER, alpha, beta,
-						i, j, boardValue+delta)
-					p.bd[i][j] = UNSET
-					if n > value {
-						value = n
-					}
-					if value > alpha {
-						alpha = value
-					}
-					if beta <= alpha {
-						return value
-					}
-				}
-			}
-		}
-	case MINIMIZER:
-		value = 2 * WIN
-		for i, row := range p.bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					p.bd[i][j] = player
-					stopRecursing, delta := p.deltaValue(ply, x, y, boardValue)
-					if stopRecursing {
-						p.bd[i][j] = UNSET
-						p.leafNodeCount++
-						return delta
-					}
-					n := p.alphaBeta(ply+1, -player, alpha, beta,
-						i, j, boardValue+delta)
-					p.bd[i][j] = UNSET
-					if n < value {
-						value = n
-					}
-					if value < beta {
-						beta = value
-					}
-					if beta <= alpha {
-						return value
-					}
-				}
-			}
-		}
-	}
-
-	return value
-}
-
-func (p *AlphaBetaBook) PrintBoard() {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range p.bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
-		}
-		fmt.Printf("\n")
-	}
-	fmt.Printf("\n")
-}
-
-var losingTriplets [][][]int = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}},
-	{{0, 0}, {0, 1}, {0, 2}},
-	{{0, 0}, {1, 1}, {2, 2}},
-	{{1, 0}, {2, 0}, {3, 0}},
-	{{1, 0}, {1, 1}, {1, 2}},
-	{{1, 0}, {2, 1}, {3, 2}},
-	{{2, 0}, {3, 0}, {4, 0}},
-	{{2, 0}, {2, 1}, {2, 2}},
-	{{2, 0}, {1, 1}, {0, 2}},
-	{{2, 0}, {3, 1}, {4, 2}},
-	{{3, 0}, {3, 1}, {3, 2}},
-	{{3, 0}, {2, 1}, {1, 2}},
-	{{4, 0}, {4, 1}, {4, 2}},
-	{{4, 0}, {3, 1}, {2, 2}},
-	{{0, 1}, {1, 1}, {2, 1}},
-	{{0, 1}, {0, 2}, {0, 3}},
-	{{0, 1}, {1, 2}, {2, 3}},
-	{{1, 1}, {2, 1}, {3, 1}},
-	{{1, 1}, {1, 2}, {1, 3}},
-	{{1, 1}, {2, 2}, {3, 3}},
-	{{2, 1}, {3, 1}, {4, 1}},
-	{{2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {1, 2}, {0, 3}},
-	{{2, 1}, {3, 2}, {4, 3}},
-	{{3, 1}, {3, 2}, {3, 3}},
-	{{3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}},
-	{{4, 1}, {3, 2}, {2, 3}},
-	{{0, 2}, {1, 2}, {2, 2}},
-	{{0, 2}, {0, 3}, {0, 4}},
-	{{0, 2}, {1, 3}, {2, 4}},
-	{{1, 2}, {2, 2}, {3, 2}},
-	{{1, 2}, {1, 3}, {1, 4}},
-	{{1, 2}, {2, 3}, {3, 4}},
-	{{2, 2}, {3, 2}, {4, 2}},
-	{{2, 2}, {2, 3}, {2, 4}},
-	{{2, 2}, {1, 3}, {0, 4}},
-	{{2, 2}, {3, 3}, {4, 4}},
-	{{3, 2}, {3, 3}, {3, 4}},
-	{{3, 2}, {2, 3}, {1, 4}},
-	{{4, 2}, {4, 3}, {4, 4}},
-	{{4, 2}, {3, 3}, {2, 4}},
-	{{0, 3}, {1, 3}, {2, 3}},
-	{{1, 3}, {2, 3}, {3, 3}},
-	{{2, 3}, {3, 3}, {4, 3}},
-	{{0, 4}, {1, 4}, {2, 4}},
-	{{1, 4}, {2, 4}, {3, 4}},
-	{{2, 4}, {3, 4}, {4, 4}},
-}
-var winningQuads [][][]int = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
-	{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
-	{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
-	{{0, 1}, {1, 1}, {2, 1}, {3, 1}},
-	{{0, 1}, {0, 2}, {0, 3}, {0, 4}},
-	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
-	{{0, 2}, {1, 2}, {2, 2}, {3, 2}},
-	{{0, 3}, {1, 3}, {2, 3}, {3, 3}},
-	{{0, 4}, {1, 4}, {2, 4}, {3, 4}},
-	{{1, 0}, {2, 0}, {3, 0}, {4, 0}},
-	{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
-	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
-	{{1, 1}, {2, 1}, {3, 1}, {4, 1}},
-	{{1, 1}, {1, 2}, {1, 3}, {1, 4}},
-	{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
-	{{1, 2}, {2, 2}, {3, 2}, {4, 2}},
-	{{1, 3}, {2, 3}, {3, 3}, {4, 3}},
-	{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
-	{{2, 0}, {2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {2, 2}, {2, 3}, {2, 4}},
-	{{3, 0}, {3, 1}, {3, 2}, {3, 3}},
-	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
-	{{3, 1}, {3, 2}, {3, 3}, {3, 4}},
-	{{3, 1}, {2, 2}, {1, 3}, {0, 4}},
-	{{4, 0}, {4, 1}, {4, 2}, {4, 3}},
-	{{4, 0}, {3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}, {4, 4}},
-	{{4, 1}, {3, 2}, {2, 3}, {1, 4}},
-}
-
-var scores [5][5]int
-
-func (p *AlphaBetaBook) SetScores(randomize bool) {
-	if randomize {
-		var vals [11]int = [11]int{-5, -4, -3 - 2, -1, 0, 1, 2, 3, 4, 5}
-		for i, row := range scores {
-			for j := range row {
-				scores[i][j] = vals[rand.Intn(11)]
-			}
-		}
-	} else {
-		scores = [5][5]int{
-			{3, 3, 0, 3, 3},
-			{3, 4, 1, 4, 3},
-			{0, 1, 0, 1, 0},
-			{3, 4, 1, 4, 3},
-			{3, 3, 0, 3, 3},
-		}
-	}
-}
-
-func (p *AlphaBetaBook) FindWinner() int {
-	for _, quad := range winningQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return p.bd[quad[0][0]][quad[0][1]]
-		}
-	}
-
-	for _, triplet := range losingTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -p.bd[triplet[0][0]][triplet[0][1]]
-		}
+	p.moveCount++
+	if p.bookInProgress {
+		p.bd.MakeMove(move.X, move.Y, game.MAXIMIZER)
 	}
 
-	return 0 // Cat got the game
+	return move, info, nil
 }
 
 // Implement an opening "book". Make the first move in
@@ -491,7 +183,7 @@ func (p *AlphaBetaBook) bookDefend() {
 	OUTER2:
 		for i, row := range p.bd {
 			for j, mark := range row {
-				if !(i == p.firstX && j == p.firstY) && mark == MINIMIZER {
+				if !(i == p.firstX && j == p.firstY) && mark == game.MINIMIZER {
 					lastx = i
 					lasty = j
 					break OUTER2
@@ -528,7 +220,7 @@ func (p *AlphaBetaBook) bookStart() {
 			p.state = DIAGONAL
 		case DIAGONAL:
 			p_x, p_y := p.c_x+3, p.c_y+3
-			if p.bd[p_x][p_y] == UNSET {
+			if p.bd[p_x][p_y] == game.UNSET {
 				p.c_x += 3
 				p.c_y += 3
 				p.state = CORNER
@@ -538,11 +230,11 @@ func (p *AlphaBetaBook) bookStart() {
 			}
 		case CORNER:
 			p_x, p_y := p.c_x-3, p.c_y
-			if p.bd[p_x][p_y] == UNSET {
+			if p.bd[p_x][p_y] == game.UNSET {
 				p.c_x -= 3
 			} else {
 				p_x, p_y = p.c_x, p.c_y-3
-				if p.bd[p_x][p_y] == UNSET {
+				if p.bd[p_x][p_y] == game.UNSET {
 					p.c_y -= 3
 				}
 			}
@@ -551,7 +243,7 @@ func (p *AlphaBetaBook) bookStart() {
 		case OTHERCORNER:
 			// Didn't get desired diagonal
 			p_x, p_y := p.c_x, p.c_y+3
-			if p.bd[p_x][p_y] == UNSET {
+			if p.bd[p_x][p_y] == game.UNSET {
 				p.c_y += 3
 				p.state = OTHERDIAGONAL
 			} else {
@@ -561,7 +253,7 @@ func (p *AlphaBetaBook) bookStart() {
 			}
 		case OTHERDIAGONAL:
 			p_x, p_y := p.c_x+3, p.c_y-3
-			if p.bd[p_x][p_y] == UNSET {
+			if p.bd[p_x][p_y] == game.UNSET {
 				p.c_x += 3
 				p.c_y -= 3
 			} else {
@@ -1,65 +1,63 @@
 package alphabeta
 
 import (
+	"context"
 	"fmt"
+	"math/bits"
 	"math/rand"
+	"sort"
+	"time"
 
+	"squava/src/engine"
+	"squava/src/game"
 	"squava/src/movekeeper"
-)
-
-type board [5][5]int
-
-// Semantically meaningful constant names
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
+	"squava/src/score"
+	"squava/src/tablebase"
+	"squava/src/threats"
+	"squava/src/ttable"
 )
 
 type AlphaBeta struct {
-	bd            *board
+	bd            game.Bitboard
 	name          string
 	leafNodeCount int
 	maxDepth      int
+	deeper        int // plies ahead a helper's iterative deepening starts
 	deterministic bool
-	boardValue    func(*AlphaBeta, int, int, int, int) (bool, int)
-}
-
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
-var indexedCalcs = false
-
-func calculateIndexedMatrices() {
-	// Set up for use by deltaValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
-	}
+	boardValue    func(*AlphaBeta, int, int, int) (bool, int)
+	scores        []int // bias for each cell
+	symmetries    []int // transforms of the board that leave scores unchanged
+	rules         game.Rules
+	table         *ttable.Table
+	tablebase     *tablebase.Table
+	timeLimit     time.Duration
+	ctx           context.Context // nil when nothing can cancel the search
+	stopped       bool            // ctx was cancelled mid-search
+	nodeCount     int
+	pv            engine.PVTable
+	ordering      bool                      // killer moves and history order moves
+	scouting      bool                      // ChooseMove's root search narrows windows
+	threatPlies   int                       // how far to look for forced wins by threats
+	quiescence    int                       // most forced moves past the horizon
+	extensions    int                       // forced moves past the horizon, last search
+	killers       [game.MaxCells + 2][2]int // by ply, -1 for none
+	history       [2][game.MaxCells]int     // by side, then cell
+	cutoffs       Cutoffs
 }
 
 func New(deterministic bool, maxdepth int) *AlphaBeta {
-	if !indexedCalcs {
-		calculateIndexedMatrices()
-		indexedCalcs = true
-	}
 	return &AlphaBeta{
-		bd:            new(board),
 		name:          "AlphaBeta",
 		maxDepth:      maxdepth,
 		deterministic: deterministic,
 		boardValue:    deltaValue,
+		scores:        make([]int, game.Standard.Cells()),
+		symmetries:    game.Standard.Symmetries(nil),
+		table:         ttable.New(ttable.DefaultMB, ttable.DEPTH),
+		ordering:      true,
+		scouting:      true,
+		threatPlies:   threats.DefaultPlies,
+		quiescence:    threats.DefaultQuiescence,
 	}
 }
 
@@ -71,7 +69,7 @@ func (p *AlphaBeta) Name() string {
 // MakeMove changes internal board representation,
 // making opposing player's move
 func (p *AlphaBeta) MakeMove(x, y int, player int) {
-	p.bd[x][y] = player
+	p.bd.MakeMove(p.bd.Shape().Cell(x, y), player)
 }
 
 // SetDepth changes the max recursion depth based
@@ -88,350 +86,648 @@ func (p *AlphaBeta) SetDepth(moveCounter int) {
 	}
 }
 
-// ChooseMove - choose computer's next move: return x,y coords of move and its score.
-func (p *AlphaBeta) ChooseMove() (xcoord int, ycoord int, value int, leafcount int) {
+// SetMaxDepth sets the max recursion depth directly, for
+// players that wrap an AlphaBeta and have their own idea
+// of how deep to look.
+func (p *AlphaBeta) SetMaxDepth(maxDepth int) {
+	p.maxDepth = maxDepth
+}
+
+// SetScouting turns aspiration windows and null-window scouting
+// of ChooseMove's root moves on or off. Off, every root move gets
+// the full window, and an exact value.
+func (p *AlphaBeta) SetScouting(on bool) {
+	p.scouting = on
+}
+
+// SetThreatDepth sets how many plies ahead ChooseMove looks
+// for a forced win by threats, before it searches. 0 doesn't look.
+func (p *AlphaBeta) SetThreatDepth(plies int) {
+	p.threatPlies = plies
+}
+
+// Board returns a copy of the internal board representation
+func (p *AlphaBeta) Board() game.Bitboard {
+	return p.bd
+}
+
+// SetTimeLimit makes ChooseMove deepen its search one move at a
+// time until limit runs out, instead of searching to the depth
+// SetDepth sets. A zero limit goes back to a fixed depth.
+func (p *AlphaBeta) SetTimeLimit(limit time.Duration) {
+	p.timeLimit = limit
+}
+
+// ChooseMove - choose computer's next move, and make it on the internal
+// board. A search that ctx can cancel deepens one move at a time, so
+// that it has a move from the deepest completed search to return.
+func (p *AlphaBeta) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
+
+	lines, info := p.search(ctx, true)
+	if info.Stopped && info.Depth == 0 {
+		return engine.NoMove, info, engine.ErrNoMove
+	}
+
+	moves := movekeeper.New(2*game.LOSS, p.deterministic)
+	for _, line := range lines {
+		moves.SetMove(line.Move.X, line.Move.Y, int(line.Value))
+	}
+	a, b, v := moves.ChooseMove()
+
+	move := engine.Move{X: a, Y: b}
+	info.Value = score.Score(v)
+	for _, line := range lines {
+		if line.Move == move {
+			info.PV = line.PV
+		}
+	}
+
+	if move != engine.NoMove {
+		p.MakeMove(move.X, move.Y, game.MAXIMIZER)
+	}
+
+	return move, info, nil
+}
+
+// Search searches the way ChooseMove does, but doesn't make a move.
+// A helper in a parallel search runs it, to fill in the table it
+// shares with the search whose move gets made.
+func (p *AlphaBeta) Search(ctx context.Context) engine.SearchInfo {
+	_, info := p.search(ctx, true)
+	return info
+}
+
+// Helper returns a copy of the player for a parallel search: the
+// same position, valuation, rules and depth, sharing the player's
+// transposition table, with its own killer moves and history, and
+// no threat search of its own. Deepening iteratively, it starts
+// deeper plies deeper, so that it stays ahead of the player's search.
+func (p *AlphaBeta) Helper(deeper int) *AlphaBeta {
+	h := *p
+	h.deeper = deeper
+	h.threatPlies = 0
+	if p.table != nil {
+		h.table = p.table.Share()
+	}
+	return &h
+}
+
+// Analyze values MAXIMIZER's moves the way ChooseMove does, but
+// doesn't make a move. It returns the count best moves, best first,
+// each with its value and principal variation, or all of them if
+// count is 0. Moves that are rotations or reflections of another
+// move on a symmetric board, with symmetric biases, don't appear.
+func (p *AlphaBeta) Analyze(ctx context.Context, count int) ([]engine.Line, engine.SearchInfo, error) {
 
-	moves := movekeeper.New(2*LOSS, p.deterministic)
+	lines, info := p.search(ctx, false)
+	if info.Stopped && info.Depth == 0 {
+		return nil, info, engine.ErrNoMove
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Value > lines[j].Value
+	})
+	if count > 0 && count < len(lines) {
+		lines = lines[:count]
+	}
+	if len(lines) > 0 {
+		info.Value, info.PV = lines[0].Value, lines[0].PV
+	}
+
+	return lines, info, nil
+}
 
+// search values MAXIMIZER's moves to p.maxDepth, or deeper and
+// deeper until the time limit runs out, if there is one. When ctx
+// can cancel the search, it deepens one move at a time to p.maxDepth,
+// so there's always a completed search's values to return. If choosing,
+// as ChooseMove is, only the best moves need exact values, so the root
+// search can scout, and deepening stops early once the best move
+// is a forced win or loss.
+func (p *AlphaBeta) search(ctx context.Context, choosing bool) ([]engine.Line, engine.SearchInfo) {
+
+	start := time.Now()
 	p.leafNodeCount = 0
+	p.extensions = 0
+	p.clearOrdering()
+	if p.table != nil {
+		p.table.NewSearch()
+	}
+
+	maxDepth := p.maxDepth
+	if p.timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeLimit)
+		defer cancel()
+		maxDepth = bits.OnesCount64(p.bd.Empty())
+	}
 
-	for i, row := range p.bd {
-		for j, mark := range row {
-			if mark == UNSET {
-				p.bd[i][j] = MAXIMIZER
-				stop, value := p.boardValue(p, 0, i, j, 0)
-				if !stop {
-					value = p.alphaBeta(1, MINIMIZER, 2*LOSS, 2*WIN, i, j, value)
+	var lines []engine.Line
+	var info engine.SearchInfo
+
+	if p.inTablebase() {
+		// Perfect play, no need to search
+		lines, info.Depth = p.tablebaseRoot(), bits.OnesCount64(p.bd.Empty())
+	} else if win := p.threatWin(ctx, choosing); win != nil {
+		// A forced win, no need to search
+		lines, info.Depth = win, len(win[0].PV)
+	} else if ctx.Done() == nil {
+		// Nothing can stop this search, no need to deepen
+		lines, info.Depth = p.searchRoot(-1, 0, false, choosing), maxDepth
+	} else {
+		// Iterative deepening: search 1 move deep, then 2 moves
+		// deep, and so on. Keep the values from the deepest search
+		// that finished. A 1-move-deep search makes no recursive
+		// calls, so it always finishes. A helper starts deeper.
+		p.ctx = ctx
+		p.nodeCount = 0
+		best, guess := -1, 0
+		first := 1
+		if 1+p.deeper <= maxDepth {
+			first += p.deeper
+		}
+		for depth := first; depth <= maxDepth; depth++ {
+			p.maxDepth = depth
+			values := p.searchRoot(best, guess, best >= 0, choosing)
+			if p.stopped {
+				break
+			}
+			lines, info.Depth = values, depth
+			if len(lines) == 0 {
+				break // full board
+			}
+			top := lines[0]
+			for _, line := range lines {
+				if line.Value > top.Value {
+					top = line
 				}
-				p.bd[i][j] = UNSET
-				moves.SetMove(i, j, value)
+			}
+			best, guess = p.bd.Shape().Cell(top.Move.X, top.Move.Y), int(top.Value)
+			if choosing && top.Value.Decided() {
+				break // found a forced win or loss, deeper won't change it
+			}
+			if !engine.Deepen(ctx, start) {
+				break // next, deeper, search won't finish in time
 			}
 		}
+		info.Stopped = p.stopped
+		p.maxDepth = maxDepth
+		p.ctx = nil
+		p.stopped = false
 	}
 
-	a, b, v := moves.ChooseMove()
+	info.LeafCount = p.leafNodeCount
+	info.Elapsed = time.Since(start)
 
-	p.MakeMove(a, b, MAXIMIZER)
-
-	return a, b, v, p.leafNodeCount
+	return lines, info
 }
 
-// deltaValue calculates the value of the board,
-// including value change from move (x,y).
-func deltaValue(p *AlphaBeta, ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
-
-	relevantQuads := indexedWinningQuads[x][y]
-	for _, quad := range relevantQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return true, p.bd[quad[0][0]][quad[0][1]] * (WIN - ply)
+// aspiration is how far from the last iteration's value the
+// window of the first root move's search starts out, about a
+// 3-of-a-4-in-a-row's worth.
+const aspiration = 30
+
+// searchRoot values all of MAXIMIZER's moves to p.maxDepth, trying
+// cell number first before the others, if it's not -1, and returns
+// each move with its value and principal variation.
+//
+// If scout, and the player is scouting, only the best moves get exact
+// values. The first move gets searched with a window around guess, if
+// guessed, and again with the full window if its value falls outside.
+// The rest get a null window just under the best value so far. A move
+// that fails high ties or beats it, and gets searched again for its
+// exact value. A move that fails low has an upper bound for a value.
+func (p *AlphaBeta) searchRoot(first int, guess int, guessed bool, scout bool) []engine.Line {
+
+	// Moves that are rotations or reflections of other moves
+	// on a symmetric board have the same value, skip them.
+	unique := p.bd.UniqueMovesUnder(p.symmetries)
+	var ordered [game.MaxCells]int
+	count := 0
+	if first >= 0 && unique&game.Bit(first) != 0 {
+		ordered[count] = first
+		count++
+		unique &^= game.Bit(first)
+	}
+	for ; unique != 0; unique &= unique - 1 {
+		ordered[count] = bits.TrailingZeros64(unique)
+		count++
+	}
+
+	lines := make([]engine.Line, 0, count)
+	scout = scout && p.scouting
+	best := 2 * game.LOSS
+
+	for n, cell := range ordered[:count] {
+		p.bd.MakeMove(cell, game.MAXIMIZER)
+		stop, value := p.boardValue(p, 1, cell, 0)
+		switch {
+		case stop:
+			p.leafNodeCount++
+			p.pv.Clear(2)
+			value = p.quiesce(2, game.MINIMIZER, value, 0)
+		case !scout:
+			value = p.alphaBeta(2, game.MINIMIZER, 2*game.LOSS, 2*game.WIN, value)
+		case n == 0:
+			if guessed {
+				alpha, beta := guess-aspiration, guess+aspiration
+				v := p.alphaBeta(2, game.MINIMIZER, alpha, beta, value)
+				if v > alpha && v < beta {
+					value = v
+					break
+				}
+			}
+			if !p.stopped {
+				value = p.alphaBeta(2, game.MINIMIZER, 2*game.LOSS, 2*game.WIN, value)
+			}
+		default:
+			v := p.alphaBeta(2, game.MINIMIZER, best-1, best, value)
+			if v >= best && !p.stopped {
+				v = p.alphaBeta(2, game.MINIMIZER, best-1, 2*game.WIN, value)
+			}
+			value = v
 		}
-		if sum == 3 || sum == -3 {
-			value += sum * 10
+		p.bd.UnmakeMove(cell, game.MAXIMIZER)
+		if p.stopped {
+			break
 		}
+		if value > best {
+			best = value
+		}
+		p.pv.Update(1, cell)
+		i, j := p.bd.Shape().Coords(cell)
+		lines = append(lines, engine.Line{
+			Move:  engine.Move{X: i, Y: j},
+			Value: score.Score(value),
+			PV:    p.pv.Line(p.bd.Shape(), 1),
+		})
 	}
 
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
+	return lines
+}
 
-		if sum == 3 || sum == -3 {
-			return true, sum / 3 * (LOSS + ply)
+// inTablebase returns true if the player has a tablebase
+// with the position on its board in it.
+func (p *AlphaBeta) inTablebase() bool {
+	if p.tablebase == nil {
+		return false
+	}
+	_, ok := p.tablebase.Probe(&p.bd, game.MAXIMIZER)
+	return ok
+}
+
+// tablebaseRoot values all of MAXIMIZER's moves from the
+// tablebase, each with the line of perfect play after it.
+func (p *AlphaBeta) tablebaseRoot() []engine.Line {
+	var lines []engine.Line
+	for unique := p.bd.UniqueMoves(); unique != 0; unique &= unique - 1 {
+		cell := bits.TrailingZeros64(unique)
+		p.bd.MakeMove(cell, game.MAXIMIZER)
+		line := []int{cell}
+		var value score.Score
+		switch p.rules.MoveWinner(&p.bd, cell) {
+		case game.MAXIMIZER:
+			value = score.Win(1)
+		case game.MINIMIZER:
+			value = score.Loss(1)
+		default:
+			if v, ok := p.tablebase.Probe(&p.bd, game.MINIMIZER); ok {
+				value = -v.Later(1)
+				line = append(line, p.tablebase.Line(&p.bd, game.MINIMIZER)...)
+			}
+		}
+		p.bd.UnmakeMove(cell, game.MAXIMIZER)
+		i, j := p.bd.Shape().Coords(cell)
+		lines = append(lines, engine.Line{
+			Move:  engine.Move{X: i, Y: j},
+			Value: value,
+			PV:    engine.Moves(p.bd.Shape(), line),
+		})
+	}
+	return lines
+}
+
+// threatWin looks for a forced win by threats, if choosing and
+// the player looks for them, and returns it as the only line.
+func (p *AlphaBeta) threatWin(ctx context.Context, choosing bool) []engine.Line {
+	if !choosing || p.threatPlies == 0 {
+		return nil
+	}
+	line, nodes := threats.Search(ctx, &p.bd, p.rules, game.MAXIMIZER, p.threatPlies)
+	p.leafNodeCount += nodes
+	if line == nil {
+		return nil
+	}
+	pv := engine.Moves(p.bd.Shape(), line)
+	return []engine.Line{{Move: pv[0], Value: score.Win(threats.Plies(line)), PV: pv}}
+}
+
+// cancelled returns true once the context of a search that can
+// be cancelled is done. It only looks every so often.
+func (p *AlphaBeta) cancelled() bool {
+	if p.ctx != nil && !p.stopped {
+		p.nodeCount++
+		if p.nodeCount&1023 == 0 && engine.Cancelled(p.ctx) {
+			p.stopped = true
 		}
 	}
+	return p.stopped
+}
+
+// deltaValue calculates the value of the board,
+// including value change from the move just made in cell.
+func deltaValue(p *AlphaBeta, ply int, cell int, currentValue int) (stopRecursing bool, value int) {
+
+	if winner := p.rules.MoveWinner(&p.bd, cell); winner != game.UNSET {
+		return true, winner * int(score.Win(ply))
+	}
+
+	player := p.bd.At(cell)
+	value = currentValue + p.bd.ThreeOfFour(cell, player)*player*30
 
 	// Give it a slight bias for those early
 	// moves when all losing-triplets and winning-quads
 	// are beyond the horizon.
-	value += p.bd[x][y] * scores[x][y]
+	value += player * p.scores[cell]
 
 	// If squava has a "cat game", then this is wrong. Cat
 	// games could stop recursing here.
-	stopRecursing = false
-	if ply >= p.maxDepth {
-		stopRecursing = true
-		value += currentValue
+	return ply >= p.maxDepth, value
+}
+
+// alphaBeta makes each of player's possible moves at ply,
+// valuing each one as it gets made, and recursing until
+// boardValue decides to stop. boardValue argument is the
+// accumulated value of the moves made so far.
+func (p *AlphaBeta) alphaBeta(ply int, player int, alpha int, beta int, boardValue int) (value int) {
+
+	if p.cancelled() {
+		return 0 // caller throws this away
 	}
 
-	return stopRecursing, value
-}
+	p.pv.Clear(ply)
 
-func (p *AlphaBeta) alphaBeta(ply int, player int, alpha int, beta int, x int, y int, boardValue int) (value int) {
+	empty := p.bd.Empty()
+	if empty == 0 {
+		p.leafNodeCount++
+		return boardValue // Cat game
+	}
 
-	switch player {
-	case MAXIMIZER:
-		value = 2 * LOSS // Possible to score less than LOSS
-		for i, row := range p.bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					p.bd[i][j] = MAXIMIZER
-					stopRecursing, delta := p.boardValue(p, ply, x, y, boardValue)
-					if stopRecursing {
-						p.bd[i][j] = UNSET
-						p.leafNodeCount++
-						return delta
-					}
-					n := p.alphaBeta(ply+1, MINIMIZER, alpha, beta, i, j, delta)
-					p.bd[i][j] = UNSET
-					if n > value {
-						value = n
-					}
-					if value > alpha {
-						alpha = value
-					}
-					if beta <= alpha {
-						return value
-					}
-				}
+	if p.tablebase != nil {
+		if v, ok := p.tablebase.Probe(&p.bd, player); ok {
+			p.leafNodeCount++
+			if !v.Decided() {
+				return boardValue // Cat game
 			}
+			// v counts plies from this one, values count from the root
+			return player * int(v.Later(ply-1))
 		}
-	case MINIMIZER:
-		value = 2 * WIN // You can score greater than WIN
-		for i, row := range p.bd {
-			for j, marker := range row {
-				if marker == UNSET {
-					p.bd[i][j] = player
-					stopRecursing, delta := p.boardValue(p, ply, x, y, boardValue)
-					if stopRecursing {
-						p.bd[i][j] = UNSET
-						p.leafNodeCount++
-						return delta
-					}
-					n := p.alphaBeta(ply+1, -player, alpha, beta, i, j, delta)
-					p.bd[i][j] = UNSET
-					if n < value {
-						value = n
-					}
-					if value < beta {
-						beta = value
+	}
+
+	// Stored values don't include boardValue, since different
+	// orders of the same moves can accumulate different values.
+	depth := p.maxDepth - ply + 1
+	var key uint64
+	var transform int
+	ttMove := -1
+	if p.table != nil {
+		key, transform = ttable.KeyUnder(&p.bd, player, p.symmetries)
+		if e, ok := p.table.Probe(key); ok {
+			if e.Move >= 0 {
+				shape := p.bd.Shape()
+				ttMove = shape.TransformedCell(shape.InverseTransform(transform), int(e.Move))
+			}
+			if int(e.Depth) >= depth {
+				v := ttable.FromTable(int(e.Value), ply)
+				if !score.Score(v).Decided() {
+					v += boardValue // wins and losses don't accumulate
+				}
+				switch int(e.Bound) {
+				case ttable.EXACT:
+					return v
+				case ttable.LOWER:
+					if v > alpha {
+						alpha = v
 					}
-					if beta <= alpha {
-						return value
+				case ttable.UPPER:
+					if v < beta {
+						beta = v
 					}
 				}
+				if beta <= alpha {
+					return v
+				}
+			}
+		}
+	}
+	alphaOrig, betaOrig := alpha, beta
+
+	// Best move from an earlier search of this position goes first
+	var moves [game.MaxCells]int
+	count := p.orderMoves(moves[:], empty, ttMove, ply, player)
+
+	bestMove := -1
+
+	switch player {
+	case game.MAXIMIZER:
+		value = 2 * game.LOSS // Possible to score less than LOSS
+		for k, cell := range moves[:count] {
+			p.bd.MakeMove(cell, player)
+			stopRecursing, n := p.boardValue(p, ply, cell, boardValue)
+			if stopRecursing {
+				p.leafNodeCount++
+				p.pv.Clear(ply + 1)
+				n = p.quiesce(ply+1, game.MINIMIZER, n, 0)
+			} else {
+				n = p.alphaBeta(ply+1, game.MINIMIZER, alpha, beta, n)
+			}
+			p.bd.UnmakeMove(cell, player)
+			if p.stopped {
+				return 0
+			}
+			if n > value {
+				value = n
+				bestMove = cell
+				p.pv.Update(ply, cell)
+			}
+			if value > alpha {
+				alpha = value
+			}
+			if beta <= alpha {
+				p.cutoff(ply, player, cell, depth, k == 0)
+				break
+			}
+		}
+	case game.MINIMIZER:
+		value = 2 * game.WIN // You can score greater than WIN
+		for k, cell := range moves[:count] {
+			p.bd.MakeMove(cell, player)
+			stopRecursing, n := p.boardValue(p, ply, cell, boardValue)
+			if stopRecursing {
+				p.leafNodeCount++
+				p.pv.Clear(ply + 1)
+				n = p.quiesce(ply+1, game.MAXIMIZER, n, 0)
+			} else {
+				n = p.alphaBeta(ply+1, game.MAXIMIZER, alpha, beta, n)
+			}
+			p.bd.UnmakeMove(cell, player)
+			if p.stopped {
+				return 0
+			}
+			if n < value {
+				value = n
+				bestMove = cell
+				p.pv.Update(ply, cell)
+			}
+			if value < beta {
+				beta = value
+			}
+			if beta <= alpha {
+				p.cutoff(ply, player, cell, depth, k == 0)
+				break
 			}
 		}
 	}
 
+	if p.table != nil && !p.stopped {
+		bound := ttable.EXACT
+		if value <= alphaOrig {
+			bound = ttable.UPPER
+		} else if value >= betaOrig {
+			bound = ttable.LOWER
+		}
+		stored := value
+		if !score.Score(value).Decided() {
+			stored -= boardValue
+		}
+		if bestMove >= 0 {
+			bestMove = p.bd.Shape().TransformedCell(transform, bestMove)
+		}
+		p.table.Store(key, depth, ttable.ToTable(stored, ply), bound, bestMove)
+	}
+
 	return value
 }
 
+// SetTable replaces the player's transposition table.
+// A nil table turns off the transposition table.
+func (p *AlphaBeta) SetTable(table *ttable.Table) {
+	p.table = table
+}
+
+// SetTablebase gives the player a tablebase, for perfect play
+// in positions with few enough empty cells. A nil tablebase
+// turns it off.
+func (p *AlphaBeta) SetTablebase(tb *tablebase.Table) {
+	p.tablebase = tb
+}
+
+// Table returns the player's transposition table, so
+// that its hit rate can get reported.
+func (p *AlphaBeta) Table() *ttable.Table {
+	return p.table
+}
+
 // PrintBoard prints the board in a human-readable fashion.
 // Necessary to encapsulate the internal representation of
 // a 5x5 board
 func (p *AlphaBeta) PrintBoard() {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range p.bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
-		}
-		fmt.Printf("\n")
-	}
+	fmt.Printf("%v", &p.bd)
 	fmt.Printf("\n")
 }
 
-var losingTriplets = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}},
-	{{0, 0}, {0, 1}, {0, 2}},
-	{{0, 0}, {1, 1}, {2, 2}},
-	{{1, 0}, {2, 0}, {3, 0}},
-	{{1, 0}, {1, 1}, {1, 2}},
-	{{1, 0}, {2, 1}, {3, 2}},
-	{{2, 0}, {3, 0}, {4, 0}},
-	{{2, 0}, {2, 1}, {2, 2}},
-	{{2, 0}, {1, 1}, {0, 2}},
-	{{2, 0}, {3, 1}, {4, 2}},
-	{{3, 0}, {3, 1}, {3, 2}},
-	{{3, 0}, {2, 1}, {1, 2}},
-	{{4, 0}, {4, 1}, {4, 2}},
-	{{4, 0}, {3, 1}, {2, 2}},
-	{{0, 1}, {1, 1}, {2, 1}},
-	{{0, 1}, {0, 2}, {0, 3}},
-	{{0, 1}, {1, 2}, {2, 3}},
-	{{1, 1}, {2, 1}, {3, 1}},
-	{{1, 1}, {1, 2}, {1, 3}},
-	{{1, 1}, {2, 2}, {3, 3}},
-	{{2, 1}, {3, 1}, {4, 1}},
-	{{2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {1, 2}, {0, 3}},
-	{{2, 1}, {3, 2}, {4, 3}},
-	{{3, 1}, {3, 2}, {3, 3}},
-	{{3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}},
-	{{4, 1}, {3, 2}, {2, 3}},
-	{{0, 2}, {1, 2}, {2, 2}},
-	{{0, 2}, {0, 3}, {0, 4}},
-	{{0, 2}, {1, 3}, {2, 4}},
-	{{1, 2}, {2, 2}, {3, 2}},
-	{{1, 2}, {1, 3}, {1, 4}},
-	{{1, 2}, {2, 3}, {3, 4}},
-	{{2, 2}, {3, 2}, {4, 2}},
-	{{2, 2}, {2, 3}, {2, 4}},
-	{{2, 2}, {1, 3}, {0, 4}},
-	{{2, 2}, {3, 3}, {4, 4}},
-	{{3, 2}, {3, 3}, {3, 4}},
-	{{3, 2}, {2, 3}, {1, 4}},
-	{{4, 2}, {4, 3}, {4, 4}},
-	{{4, 2}, {3, 3}, {2, 4}},
-	{{0, 3}, {1, 3}, {2, 3}},
-	{{1, 3}, {2, 3}, {3, 3}},
-	{{2, 3}, {3, 3}, {4, 3}},
-	{{0, 4}, {1, 4}, {2, 4}},
-	{{1, 4}, {2, 4}, {3, 4}},
-	{{2, 4}, {3, 4}, {4, 4}},
-}
-var winningQuads = [][][]int{
-	{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
-	{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
-	{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
-	{{0, 1}, {1, 1}, {2, 1}, {3, 1}},
-	{{0, 1}, {0, 2}, {0, 3}, {0, 4}},
-	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
-	{{0, 2}, {1, 2}, {2, 2}, {3, 2}},
-	{{0, 3}, {1, 3}, {2, 3}, {3, 3}},
-	{{0, 4}, {1, 4}, {2, 4}, {3, 4}},
-	{{1, 0}, {2, 0}, {3, 0}, {4, 0}},
-	{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
-	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
-	{{1, 1}, {2, 1}, {3, 1}, {4, 1}},
-	{{1, 1}, {1, 2}, {1, 3}, {1, 4}},
-	{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
-	{{1, 2}, {2, 2}, {3, 2}, {4, 2}},
-	{{1, 3}, {2, 3}, {3, 3}, {4, 3}},
-	{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
-	{{2, 0}, {2, 1}, {2, 2}, {2, 3}},
-	{{2, 1}, {2, 2}, {2, 3}, {2, 4}},
-	{{3, 0}, {3, 1}, {3, 2}, {3, 3}},
-	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
-	{{3, 1}, {3, 2}, {3, 3}, {3, 4}},
-	{{3, 1}, {2, 2}, {1, 3}, {0, 4}},
-	{{4, 0}, {4, 1}, {4, 2}, {4, 3}},
-	{{4, 0}, {3, 1}, {2, 2}, {1, 3}},
-	{{4, 1}, {4, 2}, {4, 3}, {4, 4}},
-	{{4, 1}, {3, 2}, {2, 3}, {1, 4}},
+// SetShape starts over with an empty board of shape,
+// and no bias on any cell until SetScores.
+func (p *AlphaBeta) SetShape(shape *game.Shape) {
+	p.bd = shape.Bitboard()
+	p.scores = make([]int, shape.Cells())
+	p.symmetries = shape.Symmetries(p.scores)
+	if p.table != nil {
+		p.table.Clear()
+	}
+}
+
+// SwapSides trades the player's marks for its opponent's,
+// for the swap rule
+func (p *AlphaBeta) SwapSides() {
+	p.bd = p.bd.Swapped()
+	if p.table != nil {
+		p.table.Clear()
+	}
 }
 
-var scores [5][5]int
+var standardScores = [25]int{
+	3, 3, 0, 3, 3,
+	3, 4, 1, 4, 3,
+	0, 1, 0, 1, 0,
+	3, 4, 1, 4, 3,
+	3, 3, 0, 3, 3,
+}
 
 // SetScores does any prep on a new board, like
-// initializing a small bias on each cell
+// initializing a small bias on each cell. Boards
+// other than 5x5 get no bias unless it's random,
+// and on a torus no cell is a corner or on an edge.
+// Random biases usually leave the valuation with no
+// symmetry, and then the table keys and the root
+// moves take none into account.
 func (p *AlphaBeta) SetScores(randomize bool) {
 	if randomize {
 		var vals = [11]int{-5, -4, -3 - 2, -1, 0, 1, 2, 3, 4, 5}
-		for i, row := range scores {
-			for j := range row {
-				scores[i][j] = vals[rand.Intn(11)]
-			}
+		for cell := range p.scores {
+			p.scores[cell] = vals[rand.Intn(11)]
 		}
+	} else if p.bd.Shape() == game.Standard {
+		copy(p.scores, standardScores[:])
 	} else {
-		scores = [5][5]int{
-			{3, 3, 0, 3, 3},
-			{3, 4, 1, 4, 3},
-			{0, 1, 0, 1, 0},
-			{3, 4, 1, 4, 3},
-			{3, 3, 0, 3, 3},
+		for cell := range p.scores {
+			p.scores[cell] = 0
 		}
 	}
+	p.symmetries = p.bd.Shape().Symmetries(p.scores)
+	if p.table != nil {
+		p.table.Clear()
+	}
 }
 
 // FindWinner returns the winner of the current game,
 // if any, based on internal board representation
 func (p *AlphaBeta) FindWinner() int {
-	for _, quad := range winningQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return p.bd[quad[0][0]][quad[0][1]]
-		}
-	}
-
-	for _, triplet := range losingTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
-
-		if sum == 3 || sum == -3 {
-			return -p.bd[triplet[0][0]][triplet[0][1]]
-		}
-	}
-
-	return 0 // Cat got the game
+	return p.rules.Winner(&p.bd)
 }
 
-// Calculates and returns the value of the move (x,y)
-// Only considers value gained or lost from the cell (x,y)
-func deltaValue2(p *AlphaBeta, ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
+// SetRules changes what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does.
+func (p *AlphaBeta) SetRules(rules game.Rules) {
+	p.rules = rules
+}
 
-	relevantQuads := indexedWinningQuads[x][y]
-	for _, quad := range relevantQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
+// deltaValue2 calculates the value of the board like
+// deltaValue, but also penalizes 2-in-a-rows that can't
+// ever amount to a 4-in-a-row.
+func deltaValue2(p *AlphaBeta, ply int, cell int, currentValue int) (stopRecursing bool, value int) {
 
-		if sum == 4 || sum == -4 {
-			return true, p.bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-		}
-		if sum == 3 || sum == -3 {
-			value += sum * 10
-		}
+	if winner := p.rules.MoveWinner(&p.bd, cell); winner != game.UNSET {
+		return true, winner * int(score.Win(ply))
 	}
 
-	relevantTriplets := indexedLosingTriplets[x][y]
-	for _, triplet := range relevantTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
+	player := p.bd.At(cell)
+	mine := p.bd.Marks(player)
+	theirs := p.bd.Marks(-player)
+	bit := game.Bit(cell)
 
-		if sum == 3 || sum == -3 {
-			return true, sum / 3 * (LOSS + ply)
-		}
-	}
+	value = currentValue + p.bd.ThreeOfFour(cell, player)*player*30
 
-	for _, triplet := range no2 {
-		for _, pair := range triplet {
-			if x == pair[0] && y == pair[1] {
-				sum := p.bd[triplet[0][0]][triplet[0][1]]
-				sum += p.bd[triplet[1][0]][triplet[1][1]]
-				sum += p.bd[triplet[2][0]][triplet[2][1]]
-				if sum == 2 || sum == -2 {
-					value += p.bd[x][y] * -100
-				}
-				break
+	// The hopeless 2-in-a-rows are those of a 5x5 board
+	if p.bd.Shape() == game.Standard {
+		for _, triplet := range no2Masks {
+			if triplet&bit != 0 && bits.OnesCount64(mine&triplet) == 2 && theirs&triplet == 0 {
+				value += player * -100
 			}
 		}
-	}
-
-	for _, quad := range noMiddle2 {
-		player := p.bd[x][y]
-		if (x == quad[1][0] && y == quad[1][1] && player == p.bd[quad[2][0]][quad[2][1]]) ||
-			(x == quad[2][0] && y == quad[2][1] && player == p.bd[quad[1][0]][quad[1][1]]) {
 
-			sum := p.bd[quad[0][0]][quad[0][1]]
-			sum += p.bd[quad[1][0]][quad[1][1]]
-			sum += p.bd[quad[2][0]][quad[2][1]]
-			sum += p.bd[quad[3][0]][quad[3][1]]
-
-			if sum == 2 || sum == -2 {
+		for _, m := range noMiddle2Masks {
+			if m.middle&bit != 0 && mine&m.middle == m.middle && p.bd.Sum(m.ends) == 0 {
 				value += player * -100
 			}
 		}
@@ -440,17 +736,9 @@ func deltaValue2(p *AlphaBeta, ply int, x, y int, currentValue int) (stopRecursi
 	// Give it a slight bias for those early
 	// moves when all losing-triplets and winning-quads
 	// are beyond the horizon.
-	value += p.bd[x][y] * scores[x][y]
-
-	// If squava has a "cat game", then this is wrong. Cat
-	// games could stop recursing here.
-	stopRecursing = false
-	if ply >= p.maxDepth {
-		stopRecursing = true
-		value += currentValue
-	}
+	value += player * p.scores[cell]
 
-	return stopRecursing, value
+	return ply >= p.maxDepth, value
 }
 
 // 4-in-a-row where you don't want to have the middle 2
@@ -469,6 +757,30 @@ var no2 = [4][3][2]int{
 	{{4, 2}, {3, 1}, {2, 0}},
 }
 
+// Bitboard masks of noMiddle2 and no2
+var noMiddle2Masks [4]struct{ middle, ends uint64 }
+var no2Masks [4]uint64
+
+func init() {
+	for n, quad := range noMiddle2 {
+		for k, pair := range quad {
+			bit := game.Bit(game.Cell(pair[0], pair[1]))
+			if k == 1 || k == 2 {
+				noMiddle2Masks[n].middle |= bit
+			} else {
+				noMiddle2Masks[n].ends |= bit
+			}
+		}
+	}
+	for n, triplet := range no2 {
+		for _, pair := range triplet {
+			no2Masks[n] |= game.Bit(game.Cell(pair[0], pair[1]))
+		}
+	}
+}
+
+// SetAvoid makes the player penalize 2-in-a-rows that
+// can't ever become a winning 4-in-a-row.
 func (p *AlphaBeta) SetAvoid() {
 	p.name = "A/B+Avoid"
 	p.boardValue = deltaValue2
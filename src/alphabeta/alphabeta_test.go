@@ -0,0 +1,292 @@
+package alphabeta
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"squava/src/game"
+	"squava/src/threats"
+	"squava/src/ttable"
+)
+
+// Positions to search, as moves, X first. None of them is
+// symmetric, so AlphaBeta values every move, as arraySearch does.
+var positions = [][][2]int{
+	{{1, 1}, {2, 3}},
+	{{1, 1}, {3, 3}, {1, 3}, {2, 2}},
+	{{1, 1}, {1, 2}, {2, 2}, {3, 3}, {4, 4}, {0, 0}},
+	{{0, 1}, {1, 1}, {3, 4}, {2, 2}, {1, 3}, {3, 1}, {0, 2}, {2, 0}},
+}
+
+// arraySearch is the alpha/beta search as it was before the
+// bitboard, on a [5][5]int board, summing the cells of every quad
+// through the last move. It values moves the way AlphaBeta does, so
+// it does the same work as an AlphaBeta with move ordering, root
+// scouting, quiescence, threat search and the table all off.
+type arraySearch struct {
+	bd       game.Board
+	leaves   int
+	maxDepth int
+}
+
+// newArraySearch sets up moves, X first, and gives
+// MAXIMIZER the next move by flipping the marks if needed.
+func newArraySearch(maxDepth int, moves [][2]int) *arraySearch {
+	p := &arraySearch{maxDepth: maxDepth}
+	player := firstPlayer(moves)
+	for _, move := range moves {
+		p.bd.MakeMove(move[0], move[1], player)
+		player = -player
+	}
+	return p
+}
+
+// values values each of MAXIMIZER's moves with a full window
+func (p *arraySearch) values() (values [5][5]int) {
+	for i, row := range p.bd {
+		for j, mark := range row {
+			if mark != game.UNSET {
+				continue
+			}
+			p.bd[i][j] = game.MAXIMIZER
+			stop, value := p.deltaValue(1, i, j, 0)
+			if stop {
+				p.leaves++
+			} else {
+				value = p.alphaBeta(2, game.MINIMIZER, 2*game.LOSS, 2*game.WIN, value)
+			}
+			p.bd[i][j] = game.UNSET
+			values[i][j] = value
+		}
+	}
+	return values
+}
+
+func (p *arraySearch) deltaValue(ply int, x, y int, currentValue int) (stopRecursing bool, value int) {
+
+	if winner := p.bd.MoveWinner(x, y); winner != game.UNSET {
+		return true, winner * (game.WIN - ply)
+	}
+
+	value = currentValue
+	for _, quad := range game.IndexedWinningQuads[x][y] {
+		sum := p.bd[quad[0][0]][quad[0][1]]
+		sum += p.bd[quad[1][0]][quad[1][1]]
+		sum += p.bd[quad[2][0]][quad[2][1]]
+		sum += p.bd[quad[3][0]][quad[3][1]]
+
+		if sum == 3 || sum == -3 {
+			value += sum * 10
+		}
+	}
+
+	value += p.bd[x][y] * standardScores[game.Cell(x, y)]
+
+	return ply >= p.maxDepth, value
+}
+
+func (p *arraySearch) alphaBeta(ply int, player int, alpha int, beta int, boardValue int) (value int) {
+
+	if p.bd.Full() {
+		p.leaves++
+		return boardValue // Cat game
+	}
+
+	value = 2 * game.WIN
+	if player == game.MAXIMIZER {
+		value = 2 * game.LOSS
+	}
+	for i, row := range p.bd {
+		for j, mark := range row {
+			if mark != game.UNSET {
+				continue
+			}
+			p.bd[i][j] = player
+			stopRecursing, n := p.deltaValue(ply, i, j, boardValue)
+			if stopRecursing {
+				p.leaves++
+			} else {
+				n = p.alphaBeta(ply+1, -player, alpha, beta, n)
+			}
+			p.bd[i][j] = game.UNSET
+			if player == game.MAXIMIZER {
+				if n > value {
+					value = n
+				}
+				if value > alpha {
+					alpha = value
+				}
+			} else {
+				if n < value {
+					value = n
+				}
+				if value < beta {
+					beta = value
+				}
+			}
+			if beta <= alpha {
+				return value
+			}
+		}
+	}
+
+	return value
+}
+
+// firstPlayer returns who makes the first of moves,
+// so that MAXIMIZER has the move after them.
+func firstPlayer(moves [][2]int) int {
+	if len(moves)%2 == 1 {
+		return game.MINIMIZER
+	}
+	return game.MAXIMIZER
+}
+
+// newPlain returns an AlphaBeta with moves made, and with
+// everything that arraySearch doesn't do turned off.
+func newPlain(maxDepth int, moves [][2]int) *AlphaBeta {
+	p := New(true, maxDepth)
+	p.SetTable(nil)
+	p.SetScores(false)
+	p.SetOrdering(false)
+	p.SetScouting(false)
+	p.SetThreatDepth(0)
+	p.SetQuiescence(0)
+	player := firstPlayer(moves)
+	for _, move := range moves {
+		p.MakeMove(move[0], move[1], player)
+		player = -player
+	}
+	return p
+}
+
+func TestArrayAndBitboardAgree(t *testing.T) {
+	for _, moves := range positions {
+		array := newArraySearch(5, moves)
+		bitboard := newPlain(5, moves)
+		if unique := bitboard.bd.UniqueMoves(); unique != bitboard.bd.Empty() {
+			t.Fatalf("%v: position is symmetric, AlphaBeta won't value every move", moves)
+		}
+
+		want := array.values()
+		lines, info, err := bitboard.Analyze(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("%v: %v", moves, err)
+		}
+		for _, line := range lines {
+			if got := int(line.Value); got != want[line.Move.X][line.Move.Y] {
+				t.Errorf("%v: move %v worth %d, array search says %d",
+					moves, line.Move, got, want[line.Move.X][line.Move.Y])
+			}
+		}
+		if info.LeafCount != array.leaves {
+			t.Errorf("%v: %d leaves, array search had %d", moves, info.LeafCount, array.leaves)
+		}
+	}
+}
+
+// With random biases on the cells, the mirror images of a position
+// aren't worth the same, so AlphaBeta has to value every move of the
+// empty board, and its table can't hand one image's value to another.
+func TestRandomScoresBreakSymmetry(t *testing.T) {
+	rand.Seed(1)
+	p := New(true, 4)
+	p.SetScores(true)
+	if len(p.symmetries) != 1 {
+		t.Fatalf("random biases %v have symmetries %v", p.scores, p.symmetries)
+	}
+	lines, _, err := p.Analyze(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != game.Standard.Cells() {
+		t.Fatalf("valued %d moves of the empty board, want all %d", len(lines), game.Standard.Cells())
+	}
+
+	q := New(true, 4)
+	q.SetTable(nil)
+	copy(q.scores, p.scores)
+	q.symmetries = p.symmetries
+	want, _, _ := q.Analyze(context.Background(), 0)
+	for k := range lines {
+		if lines[k].Move != want[k].Move || lines[k].Value != want[k].Value {
+			t.Errorf("with the table, move %v worth %v, without it %v worth %v",
+				lines[k].Move, lines[k].Value, want[k].Move, want[k].Value)
+		}
+	}
+}
+
+// benchDepth is deep enough that searching
+// takes much longer than setting up a search.
+const benchDepth = 6
+
+func BenchmarkArray(b *testing.B) {
+	leaves := 0
+	for i := 0; i < b.N; i++ {
+		for _, moves := range positions {
+			p := newArraySearch(benchDepth, moves)
+			p.values()
+			leaves += p.leaves
+		}
+	}
+	b.ReportMetric(float64(leaves)/float64(b.N), "leaves/op")
+}
+
+func BenchmarkBitboard(b *testing.B) {
+	leaves := 0
+	for i := 0; i < b.N; i++ {
+		for _, moves := range positions {
+			p := newPlain(benchDepth, moves)
+			_, info, _ := p.Analyze(context.Background(), 0)
+			leaves += info.LeafCount
+		}
+	}
+	b.ReportMetric(float64(leaves)/float64(b.N), "leaves/op")
+}
+
+// BenchmarkChooseMove chooses moves with each of AlphaBeta's
+// ways of cutting the search down turned on, one at a time, and
+// then all together. The searches differ, so their leaf counts
+// and times do too, but they should all choose moves as good.
+func BenchmarkChooseMove(b *testing.B) {
+	configs := []struct {
+		name  string
+		setup func(*AlphaBeta)
+	}{
+		{"plain", func(p *AlphaBeta) {}},
+		{"ordering", func(p *AlphaBeta) { p.SetOrdering(true) }},
+		{"scouting", func(p *AlphaBeta) { p.SetScouting(true) }},
+		{"quiescence", func(p *AlphaBeta) { p.SetQuiescence(threats.DefaultQuiescence) }},
+		{"table", func(p *AlphaBeta) { p.SetTable(ttable.New(ttable.DefaultMB, ttable.DEPTH)) }},
+		{"all", func(p *AlphaBeta) {
+			p.SetOrdering(true)
+			p.SetScouting(true)
+			p.SetQuiescence(threats.DefaultQuiescence)
+			p.SetTable(ttable.New(ttable.DefaultMB, ttable.DEPTH))
+		}},
+	}
+	for _, config := range configs {
+		b.Run(config.name, func(b *testing.B) {
+			leaves := 0
+			var cutoffs Cutoffs
+			for i := 0; i < b.N; i++ {
+				for _, moves := range positions {
+					p := newPlain(benchDepth, moves)
+					config.setup(p)
+					_, info, err := p.ChooseMove(context.Background())
+					if err != nil {
+						b.Fatalf("%v: %v", moves, err)
+					}
+					leaves += info.LeafCount
+					cutoffs.Cutoffs += p.Cutoffs().Cutoffs
+					cutoffs.FirstMove += p.Cutoffs().FirstMove
+				}
+			}
+			b.ReportMetric(float64(leaves)/float64(b.N), "leaves/op")
+			if cutoffs.Cutoffs > 0 {
+				b.ReportMetric(100*cutoffs.FirstMoveRate(), "%first")
+			}
+		})
+	}
+}
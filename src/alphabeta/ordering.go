@@ -0,0 +1,121 @@
+package alphabeta
+
+/* Move ordering. Alpha/beta prunes the most when it tries the
+ * best move first. After the transposition table's move come the
+ * killer moves, the last two moves that caused a cutoff at the same
+ * ply, then the rest of the empty cells, most history first. Each
+ * cutoff adds depth*depth to the mover's history of its cell, so
+ * cells that cut off deep searches, at any ply, go early.
+ */
+
+import (
+	"math/bits"
+
+	"squava/src/game"
+)
+
+// Cutoffs counts the cutoffs of the last search, and how
+// many of them came from the first move tried. The better
+// the move ordering, the closer those are.
+type Cutoffs struct {
+	Cutoffs   int
+	FirstMove int
+}
+
+// FirstMoveRate returns the fraction of cutoffs
+// that came from the first move tried
+func (c Cutoffs) FirstMoveRate() float64 {
+	if c.Cutoffs == 0 {
+		return 0.0
+	}
+	return float64(c.FirstMove) / float64(c.Cutoffs)
+}
+
+// SetOrdering turns killer moves and the history table on or
+// off. Off, moves go in cell order after the transposition
+// table's move, the way they used to.
+func (p *AlphaBeta) SetOrdering(on bool) {
+	p.ordering = on
+}
+
+// Cutoffs returns the cutoff counts of the player's last search
+func (p *AlphaBeta) Cutoffs() Cutoffs {
+	return p.cutoffs
+}
+
+// clearOrdering forgets killer moves, history and
+// cutoff counts, before a new search
+func (p *AlphaBeta) clearOrdering() {
+	p.cutoffs = Cutoffs{}
+	for ply := range p.killers {
+		p.killers[ply] = [2]int{-1, -1}
+	}
+	p.history = [2][game.MaxCells]int{}
+}
+
+// orderMoves fills in moves with the cells of empty in the order
+// player should try them at ply, ttMove first if it's not -1,
+// and returns how many there are.
+func (p *AlphaBeta) orderMoves(moves []int, empty uint64, ttMove int, ply int, player int) int {
+	count := 0
+	if ttMove >= 0 && empty&game.Bit(ttMove) != 0 {
+		moves[count] = ttMove
+		count++
+		empty &^= game.Bit(ttMove)
+	}
+
+	if !p.ordering {
+		for ; empty != 0; empty &= empty - 1 {
+			moves[count] = bits.TrailingZeros64(empty)
+			count++
+		}
+		return count
+	}
+
+	for _, cell := range p.killers[ply] {
+		if cell >= 0 && empty&game.Bit(cell) != 0 {
+			moves[count] = cell
+			count++
+			empty &^= game.Bit(cell)
+		}
+	}
+
+	// Insertion sort the rest, most history first,
+	// equal histories in cell order
+	history := &p.history[side(player)]
+	sorted := count
+	for ; empty != 0; empty &= empty - 1 {
+		cell := bits.TrailingZeros64(empty)
+		k := count
+		for ; k > sorted && history[moves[k-1]] < history[cell]; k-- {
+			moves[k] = moves[k-1]
+		}
+		moves[k] = cell
+		count++
+	}
+
+	return count
+}
+
+// cutoff counts a cutoff caused by player's move in cell at ply,
+// depth plies from the search horizon, and remembers the move
+// for ordering other positions' moves.
+func (p *AlphaBeta) cutoff(ply int, player int, cell int, depth int, first bool) {
+	p.cutoffs.Cutoffs++
+	if first {
+		p.cutoffs.FirstMove++
+	}
+	if !p.ordering {
+		return
+	}
+	if p.killers[ply][0] != cell {
+		p.killers[ply][1] = p.killers[ply][0]
+		p.killers[ply][0] = cell
+	}
+	p.history[side(player)][cell] += depth * depth
+}
+
+// side returns 0 for MAXIMIZER, 1 for MINIMIZER
+func side(player int) int {
+	return (1 - player) / 2
+}
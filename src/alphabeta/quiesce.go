@@ -0,0 +1,60 @@
+package alphabeta
+
+/* Quiescence extension. A position at the search horizon where
+ * somebody has a hole, an empty cell that wins with the next mark,
+ * isn't quiet: its static value misses the win, or the forced move
+ * that blocks it, which can be a losing 3-in-a-row. Past the horizon,
+ * the search keeps making forced moves, and only forced moves,
+ * until the position is quiet, or until it's gone the cap.
+ */
+
+import (
+	"squava/src/score"
+	"squava/src/threats"
+)
+
+// SetQuiescence sets how many forced moves past the
+// horizon the search makes, at most. 0 makes none.
+func (p *AlphaBeta) SetQuiescence(plies int) {
+	p.quiescence = plies
+}
+
+// Extensions returns how many forced moves past
+// the horizon the player's last search made
+func (p *AlphaBeta) Extensions() int {
+	return p.extensions
+}
+
+// quiesce returns the value of the position at the horizon, where
+// player makes the move at ply, and the moves so far are worth
+// value. It makes forced moves, extended of them made already,
+// until the position is quiet, or the game is decided.
+func (p *AlphaBeta) quiesce(ply int, player int, value int, extended int) int {
+	if extended >= p.quiescence || score.Score(value).Decided() {
+		return value
+	}
+
+	cell, winner := threats.Reply(&p.bd, p.rules, player)
+	switch {
+	case winner == player:
+		return player * int(score.Win(ply))
+	case winner == -player:
+		return -player * int(score.Win(ply+1))
+	case cell < 0:
+		return value // quiet
+	}
+
+	p.extensions++
+	p.bd.MakeMove(cell, player)
+	_, value = p.boardValue(p, ply, cell, value)
+	if score.Score(value).Decided() {
+		// Filling the hole lost. Not filling it
+		// loses too, a move later.
+		value = -player * int(score.Win(ply+1))
+	} else {
+		value = p.quiesce(ply+1, -player, value, extended+1)
+	}
+	p.bd.UnmakeMove(cell, player)
+
+	return value
+}
@@ -0,0 +1,80 @@
+package clock
+
+/* clock - time control for a squava player: a fixed budget
+ * of time per move, a total game clock with an increment added
+ * after every move, or both. The budget for each move goes to
+ * an iteratively deepening search as its time limit.
+ */
+
+import (
+	"time"
+)
+
+// Clock keeps track of one player's time
+type Clock struct {
+	perMove   time.Duration // 0 means no per-move limit
+	remaining time.Duration
+	increment time.Duration
+	gameClock bool
+}
+
+// New creates a Clock. A zero perMove means no per-move budget,
+// a zero total means no game clock, just per-move budgets.
+func New(perMove, total, increment time.Duration) *Clock {
+	return &Clock{
+		perMove:   perMove,
+		remaining: total,
+		increment: increment,
+		gameClock: total > 0,
+	}
+}
+
+// Enabled returns true if the clock limits time at all
+func (c *Clock) Enabled() bool {
+	return c.perMove > 0 || c.gameClock
+}
+
+// Budget returns the time to spend on the next move, with
+// empty cells left on the board. Zero means no limit.
+func (c *Clock) Budget(empty int) time.Duration {
+	budget := c.perMove
+	if c.gameClock {
+		// Assume the game goes to a full board, which
+		// spreads the remaining time over too many moves,
+		// rather than too few.
+		movesLeft := time.Duration((empty + 1) / 2)
+		if movesLeft < 1 {
+			movesLeft = 1
+		}
+		share := c.remaining/movesLeft + c.increment
+		// A search can run over its limit a little
+		if max := c.remaining / 2; share > max {
+			share = max
+		}
+		if share < time.Millisecond {
+			share = time.Millisecond
+		}
+		if budget == 0 || share < budget {
+			budget = share
+		}
+	}
+	return budget
+}
+
+// Used charges the game clock with the time a move took,
+// and adds the increment.
+func (c *Clock) Used(elapsed time.Duration) {
+	if c.gameClock {
+		c.remaining += c.increment - elapsed
+	}
+}
+
+// Flagged returns true if the game clock has run out
+func (c *Clock) Flagged() bool {
+	return c.gameClock && c.remaining < 0
+}
+
+// Remaining returns the time left on the game clock
+func (c *Clock) Remaining() time.Duration {
+	return c.remaining
+}
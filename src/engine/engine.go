@@ -0,0 +1,110 @@
+package engine
+
+/* engine - the interface that every squava player (alpha/beta,
+ * negascout, MCTS) implements, so that a program that plays
+ * games, a GUI or a tournament runner can use any of them,
+ * and stop any of them in the middle of a search.
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"squava/src/game"
+	"squava/src/score"
+)
+
+// Version of the Engine interface. It goes up
+// by one every time Engine's methods change.
+const Version = 2
+
+// Move is a cell on the board, by x,y coords. A full board
+// has no move to make, and ChooseMove returns NoMove.
+type Move struct {
+	X, Y int
+}
+
+// NoMove is the Move returned when there is no move to make
+var NoMove = Move{-1, -1}
+
+func (m Move) String() string {
+	return fmt.Sprintf("<%d,%d>", m.X, m.Y)
+}
+
+// SearchInfo describes the search that chose a move
+type SearchInfo struct {
+	Value     score.Score // score of the move, bigger is better
+	LeafCount int         // leaf nodes or playouts the search looked at
+	Depth     int         // deepest completed minimax search, 0 for MCTS
+	Stopped   bool        // a cancelled context cut the search short
+	Elapsed   time.Duration
+	PV        []Move // moves the engine expects, starting with this one
+}
+
+// Line is one of the moves an analysis looked at, with its
+// value and the principal variation that led to the value.
+type Line struct {
+	Move  Move
+	Value score.Score
+	PV    []Move
+}
+
+// Analyzer is an Engine that can value all of its possible
+// moves, not just pick the best one, without making a move.
+type Analyzer interface {
+	Analyze(ctx context.Context, count int) ([]Line, SearchInfo, error)
+}
+
+// Shaper is an Engine that can play on a board of any
+// game.Shape, not just the 5x5 game.Standard board.
+type Shaper interface {
+	// SetShape starts over with an empty board of shape.
+	// Call it before SetScores, which depends on the shape.
+	SetShape(shape *game.Shape)
+}
+
+// ErrNoMove comes back from ChooseMove when its context was
+// cancelled before the engine had found any move at all.
+var ErrNoMove = errors.New("search cancelled before finding a move")
+
+// Engine is a squava player. Engines keep their own board,
+// MakeMove tells an engine about the opponent's moves, and
+// ChooseMove makes the engine's own move on its board.
+type Engine interface {
+	Name() string
+	MakeMove(x, y int, player int) // player is MINIMIZER or MAXIMIZER
+	SetDepth(moveCounter int)
+	// ChooseMove searches until it's done, or until ctx is
+	// cancelled, whichever comes first. A cancelled search
+	// returns the best move found so far, with info.Stopped set.
+	ChooseMove(ctx context.Context) (Move, SearchInfo, error)
+	PrintBoard()
+	SetScores(randomize bool)
+	// SetRules decides what a move that fills in both a
+	// 4-in-a-row and a 3-in-a-row does, game.FourWins by default.
+	SetRules(rules game.Rules)
+}
+
+// Cancelled returns true once ctx is done. Searches call it
+// every so often, it's too slow to call at every node.
+func Cancelled(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Deepen reports whether an iteratively deepening search that
+// started at start, and just finished an iteration, has time
+// for a deeper iteration before ctx's deadline. Each iteration
+// takes longer than all the ones before it put together.
+func Deepen(ctx context.Context, start time.Time) bool {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Since(start) < deadline.Sub(start)/2
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"squava/src/game"
+)
+
+// PVTable is a "triangular" table of principal variations for a
+// minimax search. Row ply holds the best line of moves found from
+// ply on, as cell numbers. A search clears row ply on the way into
+// a node at ply, and updates it from row ply+1 when a move at ply
+// improves the node's value, so row 0 or 1 ends up holding the line
+// the search expects both players to follow from the root.
+type PVTable struct {
+	moves  [game.MaxCells + 2][game.MaxCells + 2]int // a move per cell, plies can start at 0 or 1, and ply+1
+	length [game.MaxCells + 2]int
+}
+
+// Clear empties the line at ply
+func (t *PVTable) Clear(ply int) {
+	t.length[ply] = ply
+}
+
+// Update makes cell followed by the line at ply+1 the line at ply
+func (t *PVTable) Update(ply int, cell int) {
+	t.moves[ply][ply] = cell
+	n := copy(t.moves[ply][ply+1:], t.moves[ply+1][ply+1:t.length[ply+1]])
+	t.length[ply] = ply + 1 + n
+}
+
+// Line returns a copy of the line at ply, as x,y coords
+// of a board of shape
+func (t *PVTable) Line(shape *game.Shape, ply int) []Move {
+	return Moves(shape, t.moves[ply][ply:t.length[ply]])
+}
+
+// Moves converts a line of cell numbers of a board
+// of shape to x,y coords
+func Moves(shape *game.Shape, cells []int) []Move {
+	line := make([]Move, 0, len(cells))
+	for _, cell := range cells {
+		x, y := shape.Coords(cell)
+		line = append(line, Move{X: x, Y: y})
+	}
+	return line
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+
+	"squava/src/game"
+)
+
+// Starter is an Engine that plays differently from a game.Start
+// than from an empty board, like one with an opening book
+type Starter interface {
+	SetStart(start game.Start)
+}
+
+// Swapper is an Engine that can play by the swap rule.
+// SwapSides trades the engine's marks for its opponent's,
+// when the second player takes over the first move.
+type Swapper interface {
+	SwapSides()
+}
+
+// SetStart puts start's marks on e's board of shape with MakeMove,
+// X's marks as player's. Call it after SetShape and SetScores.
+func SetStart(e Engine, shape *game.Shape, start game.Start, player int) {
+	if s, ok := e.(Starter); ok {
+		s.SetStart(start)
+	}
+	for _, cell := range start.X {
+		x, y := shape.Coords(cell)
+		e.MakeMove(x, y, player)
+	}
+	for _, cell := range start.O {
+		x, y := shape.Coords(cell)
+		e.MakeMove(x, y, -player)
+	}
+}
+
+// WantSwap decides for e, the second player, whether to take over
+// the first move, first, under the swap rule. An Analyzer swaps if
+// its best reply is worth less than nothing. Other engines swap
+// any first move that isn't on the edge of the board of shape,
+// and any first move at all on a torus, which has no edges.
+func WantSwap(ctx context.Context, e Engine, shape *game.Shape, first Move) bool {
+	if a, ok := e.(Analyzer); ok {
+		lines, _, err := a.Analyze(ctx, 1)
+		if err == nil && len(lines) > 0 {
+			return lines[0].Value < 0
+		}
+	}
+	return shape.Torus() || first.X > 0 && first.X < shape.Height()-1 &&
+		first.Y > 0 && first.Y < shape.Width()-1
+}
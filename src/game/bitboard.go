@@ -0,0 +1,240 @@
+package game
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Bitboard represents a board as one bit per cell for each
+// player, cell <x,y> at bit 5*x+y. Only the low 25 bits of
+// each side get used. Checking a 4-in-a-row or 3-in-a-row
+// is a mask and a compare, instead of summing cells. A Bitboard
+// of another Shape numbers cells the way its Shape does.
+type Bitboard struct {
+	marks [2]uint64 // [0] MAXIMIZER's marks, [1] MINIMIZER's marks
+	shape *Shape    // nil for Standard
+}
+
+// AllCells has a bit set for every cell on the Standard board
+const AllCells uint64 = 1<<25 - 1
+
+// Masks of the winning quads and losing triplets, in the
+// same order as WinningQuads and LosingTriplets, and indexed
+// by cell number for incremental valuation of a single move.
+var QuadMasks [28]uint64
+var TripletMasks [48]uint64
+var CellQuadMasks [25][]uint64
+var CellTripletMasks [25][]uint64
+
+func calculateMasks() {
+	for n, quad := range WinningQuads {
+		QuadMasks[n] = mask(quad)
+		for _, pair := range quad {
+			cell := Cell(pair[0], pair[1])
+			CellQuadMasks[cell] = append(CellQuadMasks[cell], QuadMasks[n])
+		}
+	}
+	for n, triplet := range LosingTriplets {
+		TripletMasks[n] = mask(triplet)
+		for _, pair := range triplet {
+			cell := Cell(pair[0], pair[1])
+			CellTripletMasks[cell] = append(CellTripletMasks[cell], TripletMasks[n])
+		}
+	}
+}
+
+func mask(cells [][]int) (m uint64) {
+	for _, pair := range cells {
+		m |= Bit(Cell(pair[0], pair[1]))
+	}
+	return m
+}
+
+// Bit returns the Bitboard bit for a cell number
+func Bit(cell int) uint64 {
+	return 1 << uint(cell)
+}
+
+func side(player int) int {
+	if player == MAXIMIZER {
+		return 0
+	}
+	return 1
+}
+
+// NewBitboard converts a Board to a Bitboard
+func NewBitboard(bd *Board) Bitboard {
+	var b Bitboard
+	for i, row := range bd {
+		for j, mark := range row {
+			if mark != UNSET {
+				b.MakeMove(Cell(i, j), mark)
+			}
+		}
+	}
+	return b
+}
+
+// FromMarks makes a Bitboard out of MAXIMIZER's
+// and MINIMIZER's marks, one bit per cell.
+func FromMarks(maximizer, minimizer uint64) Bitboard {
+	return Bitboard{marks: [2]uint64{maximizer & AllCells, minimizer & AllCells}}
+}
+
+// Shape returns the board's Shape
+func (b *Bitboard) Shape() *Shape {
+	if b.shape == nil {
+		return Standard
+	}
+	return b.shape
+}
+
+// Board converts a Standard Bitboard to a Board
+func (b *Bitboard) Board() Board {
+	var bd Board
+	for cell := 0; cell < 25; cell++ {
+		x, y := Coords(cell)
+		bd[x][y] = b.At(cell)
+	}
+	return bd
+}
+
+// MakeMove puts player's mark on cell number cell
+func (b *Bitboard) MakeMove(cell int, player int) {
+	b.marks[side(player)] |= Bit(cell)
+}
+
+// UnmakeMove takes player's mark off cell number cell
+func (b *Bitboard) UnmakeMove(cell int, player int) {
+	b.marks[side(player)] &^= Bit(cell)
+}
+
+// Swapped returns the board with MAXIMIZER's and MINIMIZER's
+// marks traded, as when a player takes over the other's side
+func (b *Bitboard) Swapped() Bitboard {
+	return Bitboard{marks: [2]uint64{b.marks[1], b.marks[0]}, shape: b.shape}
+}
+
+// Marks returns the bits of all of player's marks
+func (b *Bitboard) Marks(player int) uint64 {
+	return b.marks[side(player)]
+}
+
+// Empty returns the bits of all UNSET cells
+func (b *Bitboard) Empty() uint64 {
+	return b.Shape().allCells &^ (b.marks[0] | b.marks[1])
+}
+
+// At returns MAXIMIZER, MINIMIZER or UNSET, the contents of cell
+func (b *Bitboard) At(cell int) int {
+	bit := Bit(cell)
+	switch {
+	case b.marks[0]&bit != 0:
+		return MAXIMIZER
+	case b.marks[1]&bit != 0:
+		return MINIMIZER
+	}
+	return UNSET
+}
+
+// Count returns the number of marks on the board
+func (b *Bitboard) Count() int {
+	return bits.OnesCount64(b.marks[0] | b.marks[1])
+}
+
+// MoveWinner decides the game based only on the lines that
+// include cell, same as Board.MoveWinner.
+func (b *Bitboard) MoveWinner(cell int) int {
+	player := b.At(cell)
+	if player == UNSET {
+		return UNSET
+	}
+	mine := b.marks[side(player)]
+	s := b.Shape()
+
+	won := false
+	for _, quad := range s.cellQuads[cell] {
+		if mine&quad == quad {
+			won = true
+			break
+		}
+	}
+
+	lost := false
+	for _, triplet := range s.cellTriplets[cell] {
+		if mine&triplet == triplet {
+			lost = true
+			break
+		}
+	}
+
+	return Decide(player, won, lost)
+}
+
+// Winner returns the winner of the game, MAXIMIZER or
+// MINIMIZER, or UNSET if nobody has won yet, same as Board.Winner.
+func (b *Bitboard) Winner() int {
+	s := b.Shape()
+	for _, quad := range s.quads {
+		if b.marks[0]&quad == quad {
+			return Decide(MAXIMIZER, true, false)
+		}
+		if b.marks[1]&quad == quad {
+			return Decide(MINIMIZER, true, false)
+		}
+	}
+	for _, triplet := range s.triplets {
+		if b.marks[0]&triplet == triplet {
+			return Decide(MAXIMIZER, false, true)
+		}
+		if b.marks[1]&triplet == triplet {
+			return Decide(MINIMIZER, false, true)
+		}
+	}
+	return UNSET
+}
+
+// ThreeOfFour returns the number of quads through cell that have
+// 3 of player's marks and an empty 4th cell, or on a board of
+// another Shape, winning lines that are one mark short.
+func (b *Bitboard) ThreeOfFour(cell int, player int) (count int) {
+	mine := b.marks[side(player)]
+	theirs := b.marks[1-side(player)]
+	s := b.Shape()
+	for _, quad := range s.cellQuads[cell] {
+		if bits.OnesCount64(mine&quad) == s.win-1 && theirs&quad == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Sum returns the sum of the cells under mask m, the way
+// code that uses a Board would sum up a quad or triplet.
+func (b *Bitboard) Sum(m uint64) int {
+	return bits.OnesCount64(b.marks[0]&m) - bits.OnesCount64(b.marks[1]&m)
+}
+
+// String renders the board the same way Board.String does,
+// with a * in each cell that's out of play
+func (b *Bitboard) String() string {
+	s := b.Shape()
+	str := "  "
+	for y := 0; y < s.width; y++ {
+		str += fmt.Sprintf("%2d", y)
+	}
+	str += "\n"
+	for x := 0; x < s.height; x++ {
+		str += fmt.Sprintf("%-2d ", x)
+		for y := 0; y < s.width; y++ {
+			cell := s.Cell(x, y)
+			if s.allCells&Bit(cell) == 0 {
+				str += "* "
+				continue
+			}
+			str += string("O_X"[b.At(cell)+1]) + " "
+		}
+		str += "\n"
+	}
+	return str
+}
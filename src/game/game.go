@@ -0,0 +1,193 @@
+package game
+
+/* game - the one canonical squava board, the tables of winning
+ * 4-in-a-rows and losing 3-in-a-rows, and the rules for deciding
+ * who won. Every engine and every command uses this, so that no
+ * two of them can disagree about who won a game.
+ */
+
+import (
+	"fmt"
+)
+
+// Semantically meaningful constant names
+const (
+	WIN       = 10000
+	LOSS      = -10000
+	MAXIMIZER = 1
+	MINIMIZER = -1
+	UNSET     = 0
+)
+
+// Board is internal representation of a 5x5 tictactoe style
+// game board. Cells hold MAXIMIZER, MINIMIZER or UNSET.
+type Board [5][5]int
+
+// Arrays of losing triplets and winning quads, indexed
+// by <x,y> coords of all pairs composing each of the quads
+// or triplets. Makes incremental valuation of a single
+// move a lot more efficient
+var IndexedLosingTriplets [5][5][][][]int
+var IndexedWinningQuads [5][5][][][]int
+
+func init() {
+	calculateIndexedMatrices()
+}
+
+func calculateIndexedMatrices() {
+	for _, triplet := range LosingTriplets {
+		for _, pair := range triplet {
+			IndexedLosingTriplets[pair[0]][pair[1]] = append(IndexedLosingTriplets[pair[0]][pair[1]], triplet)
+		}
+	}
+	for _, quad := range WinningQuads {
+		for _, pair := range quad {
+			IndexedWinningQuads[pair[0]][pair[1]] = append(
+				IndexedWinningQuads[pair[0]][pair[1]], quad)
+		}
+	}
+}
+
+// Cell converts <x,y> coords to the 0-24 cell number
+// that flat, [25]int style code uses.
+func Cell(x, y int) int {
+	return 5*x + y
+}
+
+// Coords converts a 0-24 cell number to <x,y> coords.
+func Coords(cell int) (x, y int) {
+	return cell / 5, cell % 5
+}
+
+// MakeMove puts player's mark in cell <x,y>
+func (bd *Board) MakeMove(x, y int, player int) {
+	bd[x][y] = player
+}
+
+// UnmakeMove clears cell <x,y>, undoing MakeMove
+func (bd *Board) UnmakeMove(x, y int) {
+	bd[x][y] = UNSET
+}
+
+// Moves returns the cell numbers of all legal moves, in
+// raster order. A board that has a winner has no legal moves.
+func (bd *Board) Moves() []int {
+	if bd.Winner() != UNSET {
+		return nil
+	}
+	moves := make([]int, 0, 25)
+	for i, row := range bd {
+		for j, mark := range row {
+			if mark == UNSET {
+				moves = append(moves, Cell(i, j))
+			}
+		}
+	}
+	return moves
+}
+
+// Full returns true if no cell is UNSET.
+func (bd *Board) Full() bool {
+	for _, row := range bd {
+		for _, mark := range row {
+			if mark == UNSET {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Decide is the FourWins rule for when a single mark both
+// fills in a 4-in-a-row and a 3-in-a-row: the 4-in-a-row
+// wins. Returns the winner, MAXIMIZER or MINIMIZER, or
+// UNSET if neither won nor lost is true.
+func Decide(player int, won bool, lost bool) int {
+	if won {
+		return player
+	}
+	if lost {
+		return -player
+	}
+	return UNSET
+}
+
+// MoveWinner decides the game based only on the 4-in-a-rows
+// and 3-in-a-rows that include the mark at <x,y>. Only the
+// last mark made can end the game, so this is all a search
+// has to check after making a move.
+func (bd *Board) MoveWinner(x, y int) int {
+	player := bd[x][y]
+	if player == UNSET {
+		return UNSET
+	}
+
+	won := false
+	for _, quad := range IndexedWinningQuads[x][y] {
+		if bd.sum(quad) == 4*player {
+			won = true
+			break
+		}
+	}
+
+	lost := false
+	for _, triplet := range IndexedLosingTriplets[x][y] {
+		if bd.sum(triplet) == 3*player {
+			lost = true
+			break
+		}
+	}
+
+	return Decide(player, won, lost)
+}
+
+// Winner returns the winner of the game, MAXIMIZER or
+// MINIMIZER, or UNSET if nobody has won yet.
+func (bd *Board) Winner() int {
+	// Only have to check the quads and triplets that
+	// pass through CheckableCells to check them all.
+	for _, cell := range CheckableCells {
+		for _, quad := range IndexedWinningQuads[cell[0]][cell[1]] {
+			sum := bd.sum(quad)
+			if sum == 4 || sum == -4 {
+				return Decide(sum/4, true, false)
+			}
+		}
+	}
+	for _, cell := range CheckableCells {
+		for _, triplet := range IndexedLosingTriplets[cell[0]][cell[1]] {
+			sum := bd.sum(triplet)
+			if sum == 3 || sum == -3 {
+				return Decide(sum/3, false, true)
+			}
+		}
+	}
+	return UNSET // Cat got the game, or game not over
+}
+
+func (bd *Board) sum(cells [][]int) int {
+	sum := 0
+	for _, pair := range cells {
+		sum += bd[pair[0]][pair[1]]
+	}
+	return sum
+}
+
+// String renders the board in a human-readable fashion,
+// 'X' for MAXIMIZER, 'O' for MINIMIZER.
+func (bd *Board) String() string {
+	s := "   0 1 2 3 4\n"
+	for i, row := range bd {
+		s += fmt.Sprintf("%d  ", i)
+		for _, v := range row {
+			s += string("O_X"[v+1]) + " "
+		}
+		s += "\n"
+	}
+	return s
+}
+
+// Print writes the board to stdout.
+func (bd *Board) Print() {
+	fmt.Print(bd.String())
+}
@@ -0,0 +1,97 @@
+package game
+
+// It turns out that you only have to look at
+// the 4-in-a-rows that contain these 9 cells
+// to check every 4-in-a-row. Similarly, you
+// only need to check these 9 cells to check
+// all the losing 3-in-a-row combos. You don't
+// have to look at each and every cell.
+var CheckableCells = [9][2]int{
+	{0, 2}, {1, 2}, {2, 0},
+	{2, 1}, {2, 2}, {2, 3},
+	{2, 4}, {3, 2}, {4, 2},
+}
+
+// LosingTriplets lists <x,y> coords of every 3-in-a-row
+var LosingTriplets = [][][]int{
+	{{0, 0}, {1, 0}, {2, 0}},
+	{{0, 0}, {0, 1}, {0, 2}},
+	{{0, 0}, {1, 1}, {2, 2}},
+	{{1, 0}, {2, 0}, {3, 0}},
+	{{1, 0}, {1, 1}, {1, 2}},
+	{{1, 0}, {2, 1}, {3, 2}},
+	{{2, 0}, {3, 0}, {4, 0}},
+	{{2, 0}, {2, 1}, {2, 2}},
+	{{2, 0}, {1, 1}, {0, 2}},
+	{{2, 0}, {3, 1}, {4, 2}},
+	{{3, 0}, {3, 1}, {3, 2}},
+	{{3, 0}, {2, 1}, {1, 2}},
+	{{4, 0}, {4, 1}, {4, 2}},
+	{{4, 0}, {3, 1}, {2, 2}},
+	{{0, 1}, {1, 1}, {2, 1}},
+	{{0, 1}, {0, 2}, {0, 3}},
+	{{0, 1}, {1, 2}, {2, 3}},
+	{{1, 1}, {2, 1}, {3, 1}},
+	{{1, 1}, {1, 2}, {1, 3}},
+	{{1, 1}, {2, 2}, {3, 3}},
+	{{2, 1}, {3, 1}, {4, 1}},
+	{{2, 1}, {2, 2}, {2, 3}},
+	{{2, 1}, {1, 2}, {0, 3}},
+	{{2, 1}, {3, 2}, {4, 3}},
+	{{3, 1}, {3, 2}, {3, 3}},
+	{{3, 1}, {2, 2}, {1, 3}},
+	{{4, 1}, {4, 2}, {4, 3}},
+	{{4, 1}, {3, 2}, {2, 3}},
+	{{0, 2}, {1, 2}, {2, 2}},
+	{{0, 2}, {0, 3}, {0, 4}},
+	{{0, 2}, {1, 3}, {2, 4}},
+	{{1, 2}, {2, 2}, {3, 2}},
+	{{1, 2}, {1, 3}, {1, 4}},
+	{{1, 2}, {2, 3}, {3, 4}},
+	{{2, 2}, {3, 2}, {4, 2}},
+	{{2, 2}, {2, 3}, {2, 4}},
+	{{2, 2}, {1, 3}, {0, 4}},
+	{{2, 2}, {3, 3}, {4, 4}},
+	{{3, 2}, {3, 3}, {3, 4}},
+	{{3, 2}, {2, 3}, {1, 4}},
+	{{4, 2}, {4, 3}, {4, 4}},
+	{{4, 2}, {3, 3}, {2, 4}},
+	{{0, 3}, {1, 3}, {2, 3}},
+	{{1, 3}, {2, 3}, {3, 3}},
+	{{2, 3}, {3, 3}, {4, 3}},
+	{{0, 4}, {1, 4}, {2, 4}},
+	{{1, 4}, {2, 4}, {3, 4}},
+	{{2, 4}, {3, 4}, {4, 4}},
+}
+
+// WinningQuads lists <x,y> coords of every 4-in-a-row
+var WinningQuads = [][][]int{
+	{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
+	{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
+	{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
+	{{0, 1}, {1, 1}, {2, 1}, {3, 1}},
+	{{0, 1}, {0, 2}, {0, 3}, {0, 4}},
+	{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
+	{{0, 2}, {1, 2}, {2, 2}, {3, 2}},
+	{{0, 3}, {1, 3}, {2, 3}, {3, 3}},
+	{{0, 4}, {1, 4}, {2, 4}, {3, 4}},
+	{{1, 0}, {2, 0}, {3, 0}, {4, 0}},
+	{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
+	{{1, 0}, {2, 1}, {3, 2}, {4, 3}},
+	{{1, 1}, {2, 1}, {3, 1}, {4, 1}},
+	{{1, 1}, {1, 2}, {1, 3}, {1, 4}},
+	{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
+	{{1, 2}, {2, 2}, {3, 2}, {4, 2}},
+	{{1, 3}, {2, 3}, {3, 3}, {4, 3}},
+	{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
+	{{2, 0}, {2, 1}, {2, 2}, {2, 3}},
+	{{2, 1}, {2, 2}, {2, 3}, {2, 4}},
+	{{3, 0}, {3, 1}, {3, 2}, {3, 3}},
+	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
+	{{3, 1}, {3, 2}, {3, 3}, {3, 4}},
+	{{3, 1}, {2, 2}, {1, 3}, {0, 4}},
+	{{4, 0}, {4, 1}, {4, 2}, {4, 3}},
+	{{4, 0}, {3, 1}, {2, 2}, {1, 3}},
+	{{4, 1}, {4, 2}, {4, 3}, {4, 4}},
+	{{4, 1}, {3, 2}, {2, 3}, {1, 4}},
+}
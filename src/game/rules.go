@@ -0,0 +1,157 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rules decides a move that fills in both a 4-in-a-row and a
+// 3-in-a-row. Every 4-in-a-row has 3-in-a-rows inside it, so
+// this comes up with every 4-in-a-row.
+type Rules int
+
+const (
+	// FourWins: any 4-in-a-row wins, whatever 3-in-a-rows
+	// come with it. The usual rule, and what Decide does.
+	FourWins Rules = iota
+	// ThreeLoses: any 3-in-a-row loses, so a 4-in-a-row,
+	// with 3-in-a-rows inside it, loses too. Nobody wins,
+	// somebody loses.
+	ThreeLoses
+	// FourContainsThree: a 4-in-a-row wins, unless the same
+	// mark also makes a 3-in-a-row that isn't inside it.
+	FourContainsThree
+)
+
+var rulesNames = [...]string{"win", "lose", "contained"}
+
+func (r Rules) String() string {
+	if r >= 0 && int(r) < len(rulesNames) {
+		return rulesNames[r]
+	}
+	return fmt.Sprintf("Rules(%d)", int(r))
+}
+
+// ParseRules converts "win", "lose" or "contained", as in
+// a command line flag, to Rules.
+func ParseRules(name string) (Rules, error) {
+	for r, n := range rulesNames {
+		if strings.ToLower(name) == n {
+			return Rules(r), nil
+		}
+	}
+	return FourWins, fmt.Errorf("unknown rules %q, want win, lose or contained", name)
+}
+
+// decide returns the winner, MAXIMIZER or MINIMIZER, if player
+// filled in the 4-in-a-rows quads and the 3-in-a-rows triplets,
+// or UNSET if player has neither.
+func (r Rules) decide(player int, quads, triplets []uint64) int {
+	won, lost := len(quads) > 0, len(triplets) > 0
+	switch r {
+	case ThreeLoses:
+		won = false
+	case FourContainsThree:
+		for _, triplet := range triplets {
+			inside := false
+			for _, quad := range quads {
+				if quad&triplet == triplet {
+					inside = true
+					break
+				}
+			}
+			if !inside {
+				won = false
+				break
+			}
+		}
+	}
+	return Decide(player, won, lost)
+}
+
+// MoveWinner decides the game from the lines through the mark
+// on cell of b, the way Bitboard.MoveWinner does for FourWins.
+func (r Rules) MoveWinner(b *Bitboard, cell int) int {
+	if r == FourWins {
+		return b.MoveWinner(cell)
+	}
+	player := b.At(cell)
+	if player == UNSET {
+		return UNSET
+	}
+	s := b.Shape()
+	return r.lines(player, b.marks[side(player)], s.cellQuads[cell], s.cellTriplets[cell])
+}
+
+// MoveLines decides the lines through cell on a board of shape
+// for the player whose marks, mine, include cell: won if they
+// win, lost if they lose. It's for games with more than two
+// players, where a Bitboard doesn't fit.
+func (r Rules) MoveLines(shape *Shape, mine uint64, cell int) (won, lost bool) {
+	switch r.lines(MAXIMIZER, mine, shape.cellQuads[cell], shape.cellTriplets[cell]) {
+	case MAXIMIZER:
+		return true, false
+	case MINIMIZER:
+		return false, true
+	}
+	return false, false
+}
+
+// lines decides the game for player, whose marks are mine,
+// from the 4-in-a-rows and 3-in-a-rows in quads and triplets.
+func (r Rules) lines(player int, mine uint64, quads, triplets []uint64) int {
+	var buf [len(QuadMasks) + len(TripletMasks)]uint64
+	filled := buf[:0]
+	for _, quad := range quads {
+		if mine&quad == quad {
+			filled = append(filled, quad)
+		}
+	}
+	q := len(filled)
+	for _, triplet := range triplets {
+		if mine&triplet == triplet {
+			filled = append(filled, triplet)
+		}
+	}
+	return r.decide(player, filled[:q], filled[q:])
+}
+
+// Winner returns the winner of the game on b, the way
+// Bitboard.Winner does for FourWins: a player who won
+// ahead of a player who lost, X ahead of O.
+func (r Rules) Winner(b *Bitboard) int {
+	if r == FourWins {
+		return b.Winner()
+	}
+	var decided [2]int
+	for s, player := range [2]int{MAXIMIZER, MINIMIZER} {
+		decided[s] = r.lines(player, b.marks[s], b.Shape().quads, b.Shape().triplets)
+		if decided[s] == player {
+			return player
+		}
+	}
+	for _, winner := range decided {
+		if winner != UNSET {
+			return winner
+		}
+	}
+	return UNSET
+}
+
+// BoardMoveWinner is MoveWinner for a Board
+func (r Rules) BoardMoveWinner(bd *Board, x, y int) int {
+	if r == FourWins {
+		return bd.MoveWinner(x, y)
+	}
+	b := NewBitboard(bd)
+	return r.MoveWinner(&b, Cell(x, y))
+}
+
+// BoardWinner is Winner for a Board
+func (r Rules) BoardWinner(bd *Board) int {
+	if r == FourWins {
+		return bd.Winner()
+	}
+	b := NewBitboard(bd)
+	return r.Winner(&b)
+}
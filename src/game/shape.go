@@ -0,0 +1,292 @@
+package game
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// MaxCells is the most cells a board can have: a
+// Bitboard side has one bit per cell in a uint64.
+const MaxCells = 64
+
+// Shape is the size of a board, and the lengths of the lines
+// that win and lose on it. Cell <x,y> is in row x, column y,
+// and its cell number is width*x+y. Squava is Standard, 5x5
+// with 4-in-a-row winning and 3-in-a-row losing. On a torus,
+// lines run off one edge and back on at the opposite edge.
+type Shape struct {
+	width, height int
+	win, lose     int
+	torus         bool
+	allCells      uint64
+
+	// Masks of every winning line and losing line,
+	// and of the lines through each cell
+	quads, triplets         []uint64
+	cellQuads, cellTriplets [][]uint64
+
+	// transformed[t][cell] is the cell number that transform t
+	// moves cell number cell to, nil for transforms that don't
+	// fit a board that isn't square. symmetries lists the others.
+	transformed   [8][]int
+	inverse       [8]int
+	symmetries    []int
+	rowTransforms [8][][]uint64
+}
+
+// Standard is the 5x5 squava board. Its lines are the ones
+// in WinningQuads and LosingTriplets, in the same order, so
+// that engines search moves in the order they always have.
+var Standard = standardShape()
+
+func standardShape() *Shape {
+	calculateMasks()
+	s := &Shape{
+		width: 5, height: 5, win: 4, lose: 3,
+		allCells:     AllCells,
+		quads:        QuadMasks[:],
+		triplets:     TripletMasks[:],
+		cellQuads:    CellQuadMasks[:],
+		cellTriplets: CellTripletMasks[:],
+	}
+	s.calculateTransforms()
+	for t := range TransformedCells {
+		copy(TransformedCells[t][:], s.transformed[t])
+	}
+	return s
+}
+
+// NewShape makes a width by height board, where win marks
+// in a row, across, down or diagonally, win, and lose marks
+// in a row lose. Every winning line has losing lines inside it.
+func NewShape(width, height, win, lose int) (*Shape, error) {
+	if width == 5 && height == 5 && win == 4 && lose == 3 {
+		return Standard, nil
+	}
+	return newShape(width, height, win, lose, false)
+}
+
+// NewTorus makes a board like NewShape does, but with its
+// lines wrapping around from each edge to the opposite edge.
+// No cell is a corner or on an edge.
+func NewTorus(width, height, win, lose int) (*Shape, error) {
+	return newShape(width, height, win, lose, true)
+}
+
+func newShape(width, height, win, lose int, torus bool) (*Shape, error) {
+	if width < 1 || height < 1 || width*height > MaxCells {
+		return nil, fmt.Errorf("%dx%d board: want at least 1 and at most %d cells", width, height, MaxCells)
+	}
+	if lose < 2 || win <= lose || (win > width && win > height) {
+		return nil, fmt.Errorf("%dx%d board, %d-in-a-row wins, %d-in-a-row loses: want 2 <= lose < win, and win to fit on the board",
+			width, height, win, lose)
+	}
+	s := &Shape{width: width, height: height, win: win, lose: lose, torus: torus}
+	s.allCells = 1<<uint(width*height) - 1
+	s.cellQuads = make([][]uint64, width*height)
+	s.cellTriplets = make([][]uint64, width*height)
+	s.quads = s.lines(win, s.cellQuads)
+	s.triplets = s.lines(lose, s.cellTriplets)
+	s.calculateTransforms()
+	return s, nil
+}
+
+// ParseShape makes a Shape from command line flags: size
+// is "WxH", or just "N" for an NxN board.
+func ParseShape(size string, win, lose int, torus bool) (*Shape, error) {
+	wh := strings.Split(strings.ToLower(size), "x")
+	if len(wh) == 1 {
+		wh = append(wh, wh[0])
+	}
+	if len(wh) != 2 {
+		return nil, fmt.Errorf("bad board size %q, want WxH or N", size)
+	}
+	width, err1 := strconv.Atoi(wh[0])
+	height, err2 := strconv.Atoi(wh[1])
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("bad board size %q, want WxH or N", size)
+	}
+	if torus {
+		return NewTorus(width, height, win, lose)
+	}
+	return NewShape(width, height, win, lose)
+}
+
+// lines returns the masks of all the length-in-a-rows on the
+// board, and appends each mask to byCell for each of its cells.
+// On a torus, a line that wraps around onto itself isn't a
+// line, and a line that goes all the way around only counts once.
+func (s *Shape) lines(length int, byCell [][]uint64) []uint64 {
+	var masks []uint64
+	seen := make(map[uint64]bool)
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for x := 0; x < s.height; x++ {
+		for y := 0; y < s.width; y++ {
+			for _, d := range directions {
+				endX, endY := x+(length-1)*d[0], y+(length-1)*d[1]
+				if !s.torus && (endX >= s.height || endY < 0 || endY >= s.width) {
+					continue
+				}
+				var m uint64
+				for k := 0; k < length; k++ {
+					m |= Bit(s.wrappedCell(x+k*d[0], y+k*d[1]))
+				}
+				if bits.OnesCount64(m) < length || seen[m] {
+					continue
+				}
+				seen[m] = true
+				masks = append(masks, m)
+				for c := m; c != 0; c &= c - 1 {
+					cell := bits.TrailingZeros64(c)
+					byCell[cell] = append(byCell[cell], m)
+				}
+			}
+		}
+	}
+	return masks
+}
+
+// wrappedCell returns the cell number of <x,y> with x and y
+// wrapped around onto the board, for a torus.
+func (s *Shape) wrappedCell(x, y int) int {
+	return s.Cell((x%s.height+s.height)%s.height, (y%s.width+s.width)%s.width)
+}
+
+// Width returns the number of columns
+func (s *Shape) Width() int {
+	return s.width
+}
+
+// Height returns the number of rows
+func (s *Shape) Height() int {
+	return s.height
+}
+
+// Cells returns the number of cells on the board
+func (s *Shape) Cells() int {
+	return s.width * s.height
+}
+
+// WinLength returns the length of a winning line
+func (s *Shape) WinLength() int {
+	return s.win
+}
+
+// LoseLength returns the length of a losing line
+func (s *Shape) LoseLength() int {
+	return s.lose
+}
+
+// Torus returns true if lines wrap around the edges of the board
+func (s *Shape) Torus() bool {
+	return s.torus
+}
+
+// AllCells returns a bit for every cell that can be marked
+func (s *Shape) AllCells() uint64 {
+	return s.allCells
+}
+
+// Forbid returns a Shape like s, but with cells taken out of
+// play: nobody can mark them, so no line through them counts.
+// Only the rotations and reflections that move the forbidden
+// cells onto each other stay symmetries of the board.
+func (s *Shape) Forbid(cells []int) (*Shape, error) {
+	if len(cells) == 0 {
+		return s, nil
+	}
+	var forbidden uint64
+	for _, cell := range cells {
+		if cell < 0 || cell >= s.Cells() || s.allCells&Bit(cell) == 0 {
+			return nil, fmt.Errorf("can't forbid cell %d of a %v board", cell, s)
+		}
+		forbidden |= Bit(cell)
+	}
+	f := *s
+	f.allCells &^= forbidden
+	f.quads = without(s.quads, forbidden)
+	f.triplets = without(s.triplets, forbidden)
+	f.cellQuads = make([][]uint64, s.Cells())
+	f.cellTriplets = make([][]uint64, s.Cells())
+	for cell := range f.cellQuads {
+		if f.allCells&Bit(cell) != 0 {
+			f.cellQuads[cell] = without(s.cellQuads[cell], forbidden)
+			f.cellTriplets[cell] = without(s.cellTriplets[cell], forbidden)
+		}
+	}
+	f.symmetries = nil
+	for _, t := range s.symmetries {
+		if s.transformSide(forbidden, t) == forbidden {
+			f.symmetries = append(f.symmetries, t)
+		}
+	}
+	return &f, nil
+}
+
+// without returns the masks in lines that miss every bit of forbidden
+func without(lines []uint64, forbidden uint64) []uint64 {
+	var kept []uint64
+	for _, line := range lines {
+		if line&forbidden == 0 {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// Quads returns the masks of every winning line
+func (s *Shape) Quads() []uint64 {
+	return s.quads
+}
+
+// Triplets returns the masks of every losing line
+func (s *Shape) Triplets() []uint64 {
+	return s.triplets
+}
+
+// CellQuads returns the masks of the winning lines through cell
+func (s *Shape) CellQuads(cell int) []uint64 {
+	return s.cellQuads[cell]
+}
+
+// CellTriplets returns the masks of the losing lines through cell
+func (s *Shape) CellTriplets(cell int) []uint64 {
+	return s.cellTriplets[cell]
+}
+
+// Cell converts <x,y> coords to a cell number
+func (s *Shape) Cell(x, y int) int {
+	return s.width*x + y
+}
+
+// Coords converts a cell number to <x,y> coords
+func (s *Shape) Coords(cell int) (x, y int) {
+	return cell / s.width, cell % s.width
+}
+
+// OnBoard returns true if <x,y> is a cell of the board
+func (s *Shape) OnBoard(x, y int) bool {
+	return x >= 0 && x < s.height && y >= 0 && y < s.width
+}
+
+// Bitboard returns an empty board of this shape
+func (s *Shape) Bitboard() Bitboard {
+	if s == Standard {
+		return Bitboard{}
+	}
+	return Bitboard{shape: s}
+}
+
+func (s *Shape) String() string {
+	torus := ""
+	if s.torus {
+		torus = " torus"
+	}
+	str := fmt.Sprintf("%dx%d%s, %d wins, %d loses", s.width, s.height, torus, s.win, s.lose)
+	if forbidden := s.Cells() - bits.OnesCount64(s.allCells); forbidden > 0 {
+		str += fmt.Sprintf(", %d cells forbidden", forbidden)
+	}
+	return str
+}
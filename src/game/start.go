@@ -0,0 +1,65 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Start is how a game begins, if not from an empty board: handicap
+// marks already on the board, and the swap rule. Under the swap
+// rule, right after the first move, the second player can take over
+// the first player's side, so the first player has reason to make
+// a fair first move. X moves first either way.
+type Start struct {
+	X, O []int // cell numbers of marks already on the board
+	Swap bool
+}
+
+// Usual returns true for the usual start: an empty
+// board, and no swap rule
+func (st Start) Usual() bool {
+	return len(st.X) == 0 && len(st.O) == 0 && !st.Swap
+}
+
+// Board returns a board of shape with start's marks on it,
+// X's marks as player's, O's marks as the other player's
+func (st Start) Board(shape *Shape, player int) Bitboard {
+	b := shape.Bitboard()
+	for _, cell := range st.X {
+		b.MakeMove(cell, player)
+	}
+	for _, cell := range st.O {
+		b.MakeMove(cell, -player)
+	}
+	return b
+}
+
+// ParseStart makes a Start, and shape with forbidden cells taken
+// out, from command line flags. x and o are X's and O's marks
+// already on the board, and forbid is cells nobody can mark,
+// each a list of <x,y> coords like "2,2 1,3".
+func ParseStart(shape *Shape, x, o, forbid string, swap bool) (*Shape, Start, error) {
+	st := Start{Swap: swap}
+	var lists [3][]int
+	var used uint64
+	for n, list := range [3]string{x, o, forbid} {
+		for _, field := range strings.Fields(list) {
+			var i, j int
+			if _, err := fmt.Sscanf(field, "%d,%d", &i, &j); err != nil {
+				return nil, st, fmt.Errorf("bad cell %q, want x,y", field)
+			}
+			if !shape.OnBoard(i, j) {
+				return nil, st, fmt.Errorf("cell <%d,%d> isn't on a %v board", i, j, shape)
+			}
+			cell := shape.Cell(i, j)
+			if used&Bit(cell) != 0 {
+				return nil, st, fmt.Errorf("cell <%d,%d> listed twice", i, j)
+			}
+			used |= Bit(cell)
+			lists[n] = append(lists[n], cell)
+		}
+	}
+	st.X, st.O = lists[0], lists[1]
+	shape, err := shape.Forbid(lists[2])
+	return shape, st, err
+}
@@ -0,0 +1,189 @@
+package game
+
+// The 8 rotations and reflections of a square board, the
+// dihedral group D4. Any position and all 7 of its images
+// under these transforms have the same minimax value, and the
+// same best moves, transformed. A board that isn't square only
+// has the identity, rotate 180 and the 2 reflections that
+// swap sides. lastX and lastY are the highest row and column numbers.
+var transforms = [8]func(x, y, lastX, lastY int) (int, int){
+	func(x, y, lastX, lastY int) (int, int) { return x, y },                 // identity
+	func(x, y, lastX, lastY int) (int, int) { return lastY - y, x },         // rotate 90
+	func(x, y, lastX, lastY int) (int, int) { return lastX - x, lastY - y }, // rotate 180
+	func(x, y, lastX, lastY int) (int, int) { return y, lastX - x },         // rotate 270
+	func(x, y, lastX, lastY int) (int, int) { return lastX - x, y },         // reflect top-to-bottom
+	func(x, y, lastX, lastY int) (int, int) { return x, lastY - y },         // reflect left-to-right
+	func(x, y, lastX, lastY int) (int, int) { return lastY - y, lastX - x }, // reflect across anti-diagonal
+	func(x, y, lastX, lastY int) (int, int) { return y, x },                 // reflect across diagonal
+}
+
+// The transforms that turn a board that isn't square on its side
+var squareOnly = [8]bool{1: true, 3: true, 6: true, 7: true}
+
+// TransformedCells[t][cell] is the cell number that transform
+// t moves cell number cell to, on the Standard board.
+var TransformedCells [8][25]int
+
+// calculateTransforms fills in the cell and row transform tables.
+// rowTransforms[t][row][bits] has the image under transform t of
+// the bits of one row of a Bitboard side, so that transforming
+// a whole side takes one lookup per row.
+func (s *Shape) calculateTransforms() {
+	for t, f := range transforms {
+		if squareOnly[t] && s.width != s.height {
+			continue
+		}
+		s.symmetries = append(s.symmetries, t)
+		s.transformed[t] = make([]int, s.Cells())
+		for cell := range s.transformed[t] {
+			x, y := s.Coords(cell)
+			s.transformed[t][cell] = s.Cell(f(x, y, s.height-1, s.width-1))
+		}
+	}
+	for _, t := range s.symmetries {
+		for _, u := range s.symmetries {
+			identity := true
+			for cell := range s.transformed[t] {
+				if s.transformed[u][s.transformed[t][cell]] != cell {
+					identity = false
+					break
+				}
+			}
+			if identity {
+				s.inverse[t] = u
+			}
+		}
+	}
+	for _, t := range s.symmetries {
+		s.rowTransforms[t] = make([][]uint64, s.height)
+		for row := range s.rowTransforms[t] {
+			s.rowTransforms[t][row] = make([]uint64, 1<<uint(s.width))
+			for bits := range s.rowTransforms[t][row] {
+				var image uint64
+				for col := 0; col < s.width; col++ {
+					if bits&(1<<uint(col)) != 0 {
+						image |= Bit(s.transformed[t][s.Cell(row, col)])
+					}
+				}
+				s.rowTransforms[t][row][bits] = image
+			}
+		}
+	}
+}
+
+// InverseTransform returns the transform that undoes
+// transform t on the Standard board
+func InverseTransform(t int) int {
+	return Standard.inverse[t]
+}
+
+// InverseTransform returns the transform that undoes transform t
+func (s *Shape) InverseTransform(t int) int {
+	return s.inverse[t]
+}
+
+// TransformedCell returns the cell number that transform
+// t moves cell number cell to
+func (s *Shape) TransformedCell(t int, cell int) int {
+	return s.transformed[t][cell]
+}
+
+func (s *Shape) transformSide(marks uint64, t int) (image uint64) {
+	rowMask := uint64(1)<<uint(s.width) - 1
+	for row := 0; row < s.height; row++ {
+		image |= s.rowTransforms[t][row][(marks>>uint(s.width*row))&rowMask]
+	}
+	return image
+}
+
+// Transform returns the image of the board under transform t,
+// which has to be one of the transforms of the board's Shape.
+func (b *Bitboard) Transform(t int) Bitboard {
+	s := b.Shape()
+	return Bitboard{
+		marks: [2]uint64{
+			s.transformSide(b.marks[0], t),
+			s.transformSide(b.marks[1], t),
+		},
+		shape: b.shape,
+	}
+}
+
+// Less orders Bitboards, MAXIMIZER's marks first
+func (b *Bitboard) Less(o *Bitboard) bool {
+	if b.marks[0] != o.marks[0] {
+		return b.marks[0] < o.marks[0]
+	}
+	return b.marks[1] < o.marks[1]
+}
+
+// Symmetries returns the transforms of the Shape that move each
+// cell in play to a cell with the same value in values, one value
+// for each cell: the identity first, and all of the Shape's
+// transforms if values are the same everywhere, or nil. A valuation
+// that gives each cell a value is only symmetric under these.
+func (s *Shape) Symmetries(values []int) []int {
+	var kept []int
+	for _, t := range s.symmetries {
+		same := true
+		for cell, image := range s.transformed[t] {
+			if values != nil && s.allCells&Bit(cell) != 0 && values[image] != values[cell] {
+				same = false
+				break
+			}
+		}
+		if same {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// Canonical returns the least of the images of the board under
+// rotation and reflection, 8 of them for a square board, and the
+// transform that makes it. All the images have the same canonical
+// board, so it works as a key for anything that stores values
+// of positions.
+func (b *Bitboard) Canonical() (Bitboard, int) {
+	return b.CanonicalUnder(b.Shape().symmetries)
+}
+
+// CanonicalUnder is Canonical, but only with the transforms
+// in symmetries, which Shape.Symmetries returns.
+func (b *Bitboard) CanonicalUnder(symmetries []int) (Bitboard, int) {
+	canonical, transform := *b, 0
+	for _, t := range symmetries[1:] {
+		image := b.Transform(t)
+		if image.Less(&canonical) {
+			canonical, transform = image, t
+		}
+	}
+	return canonical, transform
+}
+
+// UniqueMoves returns the empty cells of the board, less any
+// cell that a transform leaving the board unchanged would move
+// to a lower-numbered cell. Moves left out have the same value
+// as some move left in. A board with no symmetry returns all
+// empty cells.
+func (b *Bitboard) UniqueMoves() uint64 {
+	return b.UniqueMovesUnder(b.Shape().symmetries)
+}
+
+// UniqueMovesUnder is UniqueMoves, but only with the transforms
+// in symmetries, which Shape.Symmetries returns.
+func (b *Bitboard) UniqueMovesUnder(symmetries []int) uint64 {
+	s := b.Shape()
+	empty := b.Empty()
+	for _, t := range symmetries[1:] {
+		if b.Transform(t) != *b {
+			continue
+		}
+		for cell, image := range s.transformed[t] {
+			if image < cell {
+				empty &^= Bit(cell)
+			}
+		}
+	}
+	return empty
+}
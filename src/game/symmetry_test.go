@@ -0,0 +1,161 @@
+package game
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// randomBoard makes marks random moves on an empty
+// board of shape, alternating players, X first.
+func randomBoard(r *rand.Rand, shape *Shape, marks int) Bitboard {
+	b := shape.Bitboard()
+	player := MAXIMIZER
+	for ; marks > 0; marks-- {
+		empty := b.Empty()
+		n := r.Intn(bits.OnesCount64(empty))
+		for ; n > 0; n-- {
+			empty &= empty - 1
+		}
+		b.MakeMove(bits.TrailingZeros64(empty), player)
+		player = -player
+	}
+	return b
+}
+
+func TestCanonical(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		b := randomBoard(r, Standard, r.Intn(12))
+		canonical, transform := b.Canonical()
+		if b.Transform(transform) != canonical {
+			t.Fatalf("transform %d doesn't make the canonical board of\n%v", transform, &b)
+		}
+		for _, u := range Standard.symmetries {
+			image := b.Transform(u)
+			if c, _ := image.Canonical(); c != canonical {
+				t.Fatalf("image of\n%v\nunder transform %d has a different canonical board", &b, u)
+			}
+		}
+	}
+}
+
+func TestCanonicalUnder(t *testing.T) {
+	// Only reflecting left to right leaves these values unchanged
+	values := make([]int, Standard.Cells())
+	for cell := range values {
+		x, _ := Standard.Coords(cell)
+		values[cell] = x
+	}
+	symmetries := Standard.Symmetries(values)
+	if len(symmetries) != 2 || symmetries[0] != 0 || symmetries[1] != 5 {
+		t.Fatalf("values symmetric by row have symmetries %v, want [0 5]", symmetries)
+	}
+
+	r := rand.New(rand.NewSource(2))
+	for n := 0; n < 200; n++ {
+		b := randomBoard(r, Standard, r.Intn(12))
+		canonical, transform := b.CanonicalUnder(symmetries)
+		if transform != 0 && transform != 5 {
+			t.Fatalf("canonical board made by transform %d, not one of %v", transform, symmetries)
+		}
+		mirror := b.Transform(5)
+		if c, _ := mirror.CanonicalUnder(symmetries); c != canonical {
+			t.Fatalf("mirror image of\n%v\nhas a different canonical board", &b)
+		}
+		// Flipping top to bottom makes a different position, unless
+		// it or the flip's mirror image, rotate 180, is one of the two
+		flipped, rotated := b.Transform(4), b.Transform(2)
+		same := flipped == b || flipped == mirror || rotated == b || rotated == mirror
+		if c, _ := flipped.CanonicalUnder(symmetries); c == canonical && !same {
+			t.Fatalf("image of\n%v\nunder transform 4 has the same canonical board", &b)
+		}
+	}
+}
+
+func TestSymmetries(t *testing.T) {
+	standard := []int{
+		3, 3, 0, 3, 3,
+		3, 4, 1, 4, 3,
+		0, 1, 0, 1, 0,
+		3, 4, 1, 4, 3,
+		3, 3, 0, 3, 3,
+	}
+	if got := Standard.Symmetries(standard); len(got) != 8 {
+		t.Errorf("symmetric values have symmetries %v, want all 8", got)
+	}
+	if got := Standard.Symmetries(nil); len(got) != 8 {
+		t.Errorf("nil values have symmetries %v, want all 8", got)
+	}
+	lopsided := make([]int, Standard.Cells())
+	lopsided[Cell(0, 1)] = 1
+	if got := Standard.Symmetries(lopsided); len(got) != 1 || got[0] != 0 {
+		t.Errorf("values with one odd cell have symmetries %v, want [0]", got)
+	}
+	// Symmetric along the diagonal, like the board after a move at <1,1>
+	diagonal := make([]int, Standard.Cells())
+	diagonal[Cell(1, 1)] = 1
+	if got := Standard.Symmetries(diagonal); len(got) != 2 || got[1] != 7 {
+		t.Errorf("values symmetric along the diagonal have symmetries %v, want [0 7]", got)
+	}
+}
+
+func TestUniqueMoves(t *testing.T) {
+	center := Standard.Bitboard()
+	center.MakeMove(Cell(2, 2), MAXIMIZER)
+	diagonal := Standard.Bitboard()
+	diagonal.MakeMove(Cell(1, 1), MAXIMIZER)
+	diagonal.MakeMove(Cell(3, 3), MINIMIZER)
+	r := rand.New(rand.NewSource(3))
+	boards := []Bitboard{Standard.Bitboard(), center, diagonal}
+	for n := 0; n < 50; n++ {
+		boards = append(boards, randomBoard(r, Standard, r.Intn(12)))
+	}
+
+	for _, b := range boards {
+		var keep []int
+		for _, u := range Standard.symmetries {
+			if b.Transform(u) == b {
+				keep = append(keep, u)
+			}
+		}
+		unique := b.UniqueMoves()
+		if unique&^b.Empty() != 0 {
+			t.Fatalf("unique moves of\n%v\ninclude marked cells", &b)
+		}
+		// Every empty cell is the image of exactly
+		// one unique move, under some transform in keep
+		for empty := b.Empty(); empty != 0; empty &= empty - 1 {
+			cell := bits.TrailingZeros64(empty)
+			images := 0
+			for moves := unique; moves != 0; moves &= moves - 1 {
+				move := bits.TrailingZeros64(moves)
+				for _, u := range keep {
+					if Standard.TransformedCell(u, move) == cell {
+						images++
+						break
+					}
+				}
+			}
+			if images != 1 {
+				t.Fatalf("cell %d of\n%v\nis the image of %d unique moves, want 1", cell, &b, images)
+			}
+		}
+		if len(keep) == 1 && unique != b.Empty() {
+			t.Fatalf("unique moves of\n%v\nwhich has no symmetry, left some out", &b)
+		}
+	}
+
+	empty := Standard.Bitboard()
+	if got := bits.OnesCount64(empty.UniqueMoves()); got != 6 {
+		t.Errorf("empty board has %d unique moves, want 6", got)
+	}
+	if got := bits.OnesCount64(diagonal.UniqueMoves()); got != 13 {
+		t.Errorf("board with <1,1> and <3,3> has %d unique moves, want 13", got)
+	}
+	lopsided := make([]int, Standard.Cells())
+	lopsided[Cell(0, 1)] = 1
+	if got := empty.UniqueMovesUnder(Standard.Symmetries(lopsided)); got != empty.Empty() {
+		t.Errorf("empty board with no symmetric values leaves out moves")
+	}
+}
@@ -0,0 +1,79 @@
+package lazysmp
+
+/* lazysmp - lazy SMP parallel alpha/beta. While one
+ * alphabeta.AlphaBeta chooses a move, helper copies of it search
+ * the same position in other goroutines. The helpers share its
+ * transposition table, so the values and best moves they find
+ * there cut off the main search, and each other's. Half of the
+ * helpers start deepening a ply deeper than the main search, so
+ * that they don't all search the same positions in the same order.
+ * None search deeper than the main search, so its values are the
+ * ones a search on its own finds. Nothing but the table passes
+ * between goroutines, and the move made is always the main search's.
+ */
+
+import (
+	"context"
+	"sync"
+
+	"squava/src/alphabeta"
+	"squava/src/engine"
+)
+
+// DefaultThreads is how many goroutines search,
+// counting the main search
+const DefaultThreads = 4
+
+// LazySMP is an alphabeta.AlphaBeta with helpers
+type LazySMP struct {
+	*alphabeta.AlphaBeta
+	threads    int
+	mainLeaves int // the main search's own leaves, last ChooseMove
+}
+
+func New(deterministic bool, maxdepth int) *LazySMP {
+	return &LazySMP{
+		AlphaBeta: alphabeta.New(deterministic, maxdepth),
+		threads:   DefaultThreads,
+	}
+}
+
+func (p *LazySMP) Name() string {
+	return "LazySMP"
+}
+
+// SetThreads sets how many goroutines search,
+// counting the main search. 1 has no helpers.
+func (p *LazySMP) SetThreads(threads int) {
+	if threads < 1 {
+		threads = 1
+	}
+	p.threads = threads
+}
+
+// ChooseMove starts the helpers, chooses a move with the main search,
+// and stops the helpers. The leaf count includes the helpers' leaves.
+func (p *LazySMP) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
+
+	helperCtx, stop := context.WithCancel(ctx)
+	leaves := make([]int, p.threads)
+	var wg sync.WaitGroup
+	for i := 1; i < p.threads; i++ {
+		wg.Add(1)
+		go func(i int, helper *alphabeta.AlphaBeta) {
+			defer wg.Done()
+			leaves[i] = helper.Search(helperCtx).LeafCount
+		}(i, p.Helper(i%2))
+	}
+
+	move, info, err := p.AlphaBeta.ChooseMove(ctx)
+	stop()
+	wg.Wait()
+
+	p.mainLeaves = info.LeafCount
+	for _, n := range leaves {
+		info.LeafCount += n
+	}
+
+	return move, info, err
+}
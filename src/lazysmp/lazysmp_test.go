@@ -0,0 +1,99 @@
+package lazysmp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"squava/src/alphabeta"
+	"squava/src/game"
+)
+
+// Positions to search, as moves, X first
+var positions = [][][2]int{
+	{{1, 1}, {2, 3}},
+	{{1, 1}, {3, 3}, {1, 3}, {2, 2}},
+	{{1, 1}, {1, 2}, {2, 2}, {3, 3}, {4, 4}, {0, 0}},
+	{{0, 1}, {1, 1}, {3, 4}, {2, 2}, {1, 3}, {3, 1}, {0, 2}, {2, 0}},
+}
+
+// benchDepth is how deep the searches go. Helpers
+// deepen until the main search is done, some of them a ply ahead.
+const benchDepth = 8
+
+// BenchmarkThreads times choosing a move to a fixed depth with
+// more and more goroutines. main-leaves/op is the main search's
+// share of leaves/op.
+func BenchmarkThreads(b *testing.B) {
+	for _, threads := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("%d", threads), func(b *testing.B) {
+			leaves, mainLeaves := 0, 0
+			for i := 0; i < b.N; i++ {
+				for _, moves := range positions {
+					p := New(true, benchDepth)
+					p.SetThreads(threads)
+					player := game.MAXIMIZER
+					if len(moves)%2 == 1 {
+						player = game.MINIMIZER
+					}
+					for _, move := range moves {
+						p.MakeMove(move[0], move[1], player)
+						player = -player
+					}
+					_, info, err := p.ChooseMove(context.Background())
+					if err != nil {
+						b.Fatalf("%v: %v", moves, err)
+					}
+					leaves += info.LeafCount
+					mainLeaves += p.mainLeaves
+				}
+			}
+			b.ReportMetric(float64(leaves)/float64(b.N), "leaves/op")
+			b.ReportMetric(float64(mainLeaves)/float64(b.N), "main-leaves/op")
+		})
+	}
+}
+
+// newPlayers returns a LazySMP with threads goroutines
+// and an alphabeta.AlphaBeta, both depth deep and with
+// no threat search, that have made moves, X first
+func newPlayers(depth int, threads int, moves [][2]int) (*LazySMP, *alphabeta.AlphaBeta) {
+	p := New(true, depth)
+	p.SetThreads(threads)
+	p.SetThreatDepth(0)
+	q := alphabeta.New(true, depth)
+	q.SetThreatDepth(0)
+	player := game.MAXIMIZER
+	if len(moves)%2 == 1 {
+		player = game.MINIMIZER
+	}
+	for _, move := range moves {
+		p.MakeMove(move[0], move[1], player)
+		q.MakeMove(move[0], move[1], player)
+		player = -player
+	}
+	return p, q
+}
+
+// Helpers' table entries make the main search faster,
+// but don't change the values it finds.
+func TestSameValue(t *testing.T) {
+	for depth := 1; depth <= benchDepth; depth++ {
+		for _, moves := range positions {
+			for _, threads := range []int{2, 4} {
+				p, q := newPlayers(depth, threads, moves)
+				_, got, err := p.ChooseMove(context.Background())
+				if err != nil {
+					t.Fatalf("%v: %v", moves, err)
+				}
+				_, want, err := q.ChooseMove(context.Background())
+				if err != nil {
+					t.Fatalf("%v: %v", moves, err)
+				}
+				if got.Value != want.Value {
+					t.Errorf("%v, depth %d, %d threads: %v, alphabeta says %v", moves, depth, threads, got.Value, want.Value)
+				}
+			}
+		}
+	}
+}
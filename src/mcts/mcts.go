@@ -1,15 +1,25 @@
 package mcts
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"math/bits"
 	"math/rand"
+	"sync"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/score"
+	"squava/src/tablebase"
 )
 
 type GameState struct {
 	playerJustMoved int
-	board           [25]int
-	cachedResults   [3]float64
+	board           game.Bitboard
+	winner          int
+	rules           game.Rules
 }
 
 type Node struct {
@@ -18,26 +28,28 @@ type Node struct {
 	childNodes      []*Node
 	wins            float64
 	visits          float64
+	raveWins        float64 // AMAF wins and visits, for RAVE
+	raveVisits      float64
 	untriedMoves    []int
 	playerJustMoved int
+	proven          int        // provenWin or provenLoss, for playerJustMoved
+	plies           int        // until a proven result, counting the move to the node
+	mu              sync.Mutex // guards all of the above but move and parentNode, in treeUCT
 }
 
-// Manifest constants to improve understanding
-const (
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
-)
-
 type MCTS struct {
 	game       *GameState
 	iterations int
 	movesNode  *Node
 	UCTK       float64
+	raveK      float64 // RAVE equivalence parameter, 0 for none
+	tablebase  *tablebase.Table
+	parallel   Parallel
+	threads    int
 }
 
 func New(deterministic bool, maxdepth int) *MCTS {
-	return &MCTS{game: NewGameState(), iterations: 500000, UCTK: 1.0}
+	return &MCTS{game: NewGameState(), iterations: 500000, UCTK: 1.0, threads: 1}
 }
 
 func (p *MCTS) Name() string {
@@ -53,19 +65,65 @@ func (p *MCTS) SetIterations(iterations int) {
 }
 
 func (p *MCTS) MakeMove(x, y int, player int) {
-	p.game.board[5*x+y] = player
-	p.game.playerJustMoved = player
-	p.updateMoves(5*x + y)
+	cell := p.game.board.Shape().Cell(x, y)
+	p.game.playerJustMoved = -player
+	p.game.DoMove(cell)
+	p.updateMoves(cell)
+}
+
+// SetShape starts over with an empty board of shape
+func (p *MCTS) SetShape(shape *game.Shape) {
+	rules := p.game.rules
+	p.game = NewGameState()
+	p.game.board = shape.Bitboard()
+	p.game.rules = rules
+	p.movesNode = nil
+}
+
+// SwapSides trades the player's marks for its opponent's, for
+// the swap rule. The tree of moves so far is for the other side.
+func (p *MCTS) SwapSides() {
+	p.game.board = p.game.board.Swapped()
+	p.game.playerJustMoved = -p.game.playerJustMoved
+	p.movesNode = nil
 }
 
 func (p *MCTS) SetDepth(moveCounter int) {
 }
 
-// ChooseMove should choose computer's next move and
-// return x,y coords of move and its score.
-func (p *MCTS) ChooseMove() (xcoord int, ycoord int, value int, leafcount int) {
+// ChooseMove should choose computer's next move and return it, and
+// its score. Cancelling ctx cuts the iterations short, and ChooseMove
+// returns the best move of the iterations done so far.
+func (p *MCTS) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
+
+	start := time.Now()
+
+	if p.tablebase != nil {
+		player := -p.game.playerJustMoved
+		if cell, v, ok := p.tablebase.BestMove(&p.game.board, player); ok && cell >= 0 {
+			// Perfect play, no playouts needed. The
+			// tree doesn't have the rest of the game.
+			pv := engine.Moves(p.game.board.Shape(), p.tablebase.Line(&p.game.board, player))
+			p.game.DoMove(cell)
+			p.movesNode = nil
+			a, b := p.game.board.Shape().Coords(cell)
+			return engine.Move{X: a, Y: b}, engine.SearchInfo{
+				Value:   v,
+				Elapsed: time.Since(start),
+				PV:      pv,
+			}, nil
+		}
+	}
 
-	bestnode, leaves, value := UCT(p.game, p.iterations, p.UCTK, p.movesNode)
+	bestnode, leaves, value, stopped := p.search(ctx)
+	if bestnode == nil {
+		// The game is over, there's no move to make
+		return engine.NoMove, engine.SearchInfo{
+			LeafCount: leaves,
+			Stopped:   stopped,
+			Elapsed:   time.Since(start),
+		}, engine.ErrNoMove
+	}
 
 	p.movesNode = bestnode
 	p.movesNode.parentNode = nil
@@ -74,10 +132,27 @@ func (p *MCTS) ChooseMove() (xcoord int, ycoord int, value int, leafcount int) {
 
 	p.game.DoMove(move)
 
-	a := move / 5
-	b := move % 5
+	a, b := p.game.board.Shape().Coords(move)
 
-	return a, b, value, leaves
+	return engine.Move{X: a, Y: b}, engine.SearchInfo{
+		Value:     score.Score(value),
+		LeafCount: leaves,
+		Stopped:   stopped,
+		Elapsed:   time.Since(start),
+		PV:        bestnode.principalVariation(p.game.board.Shape()),
+	}, nil
+}
+
+// SetTablebase gives the player a tablebase, nil for none. Late
+// in the game, it moves from the tablebase instead of playing out.
+func (p *MCTS) SetTablebase(tb *tablebase.Table) {
+	p.tablebase = tb
+}
+
+// SetRules changes what a move that fills in both a 4-in-a-row
+// and a 3-in-a-row does, in playouts as well as real moves.
+func (p *MCTS) SetRules(rules game.Rules) {
+	p.game.rules = rules
 }
 
 func (p *MCTS) PrintBoard() {
@@ -90,37 +165,14 @@ func (p *MCTS) SetScores(randomize bool) {
 // FindWinner will return MAXIMIZER or MINIMIZER if somebody won,
 // UNSET if nobody wins based on current board.
 func (p *MCTS) FindWinner() int {
-	board := p.game.board
-	for _, i := range importantCells {
-		if board[i] != UNSET {
-			for _, quad := range winningQuads[i] {
-				sum := board[quad[0]] + board[quad[1]] + board[quad[2]] + board[quad[3]]
-				switch sum {
-				case 4:
-					return MAXIMIZER
-				case -4:
-					return MINIMIZER
-				}
-			}
-		}
-	}
-	for _, i := range importantCells {
-		if board[i] != UNSET {
-			for _, triplet := range losingTriplets[i] {
-				sum := board[triplet[0]] + board[triplet[1]] + board[triplet[2]]
-				switch sum {
-				case 3:
-					return MINIMIZER
-				case -3:
-					return MAXIMIZER
-				}
-			}
-		}
-	}
-	return UNSET
+	return p.game.rules.Winner(&p.game.board)
 }
 
-func UCT(rootstate *GameState, itermax int, UCTK float64, rootnode *Node) (*Node, int, int) {
+// UCT does itermax iterations of Monte Carlo tree search, or fewer
+// if ctx is cancelled first, and returns the node of the best move,
+// or nil if the game is over, the number of playouts, the move's
+// value, and whether ctx stopped it.
+func UCT(ctx context.Context, rootstate *GameState, itermax int, UCTK float64, raveK float64, rootnode *Node) (*Node, int, int, bool) {
 
 	leafNodeCount := 0
 
@@ -150,13 +202,26 @@ func UCT(rootstate *GameState, itermax int, UCTK float64, rootnode *Node) (*Node
 		rootnode.playerJustMoved = rootstate.playerJustMoved
 	}
 
+	stopped := false
+	rng := rand.New(rand.NewSource(rand.Int63()))
+
 	for i := 0; i < itermax; i++ {
 
+		// Check ctx every so often, but only after the first
+		// iteration, so that rootnode always has a child to choose.
+		if i&63 == 1 && engine.Cancelled(ctx) {
+			stopped = true
+			break
+		}
+		if rootnode.proven != unproven {
+			break // the move to make is decided
+		}
+
 		node := rootnode           // reset node to root of tree of nodes
 		state := rootstate.Clone() // start at rootstate, rootnode's GameState
 
 		for len(node.untriedMoves) == 0 && len(node.childNodes) > 0 {
-			node = node.UCTSelectChild(UCTK) // updates node
+			node = node.UCTSelectChild(UCTK, raveK) // updates node
 			state.DoMove(node.move)
 		}
 
@@ -164,21 +229,16 @@ func UCT(rootstate *GameState, itermax int, UCTK float64, rootnode *Node) (*Node
 		// (if any exist), makes the move in state, and makes node
 		// the child node.
 		if len(node.untriedMoves) > 0 {
-			m := node.untriedMoves[rand.Intn(len(node.untriedMoves))]
+			m := node.expand(state, rng)
 			state.DoMove(m)
 			node = node.AddChild(m, state)
 			// node now represents m, the previously-untried move.
+			node.prove(false)
 		}
 
-		moves, terminalNode := state.GetMoves()
-
 		// starting with current state, pick a random
 		// branch of the game tree, all the way to a win/loss.
-		for !terminalNode {
-			m := moves[rand.Intn(len(moves))]
-			state.DoMove(m)
-			moves, terminalNode = state.GetMoves()
-		}
+		state.playout(rng)
 
 		leafNodeCount++
 
@@ -186,16 +246,21 @@ func UCT(rootstate *GameState, itermax int, UCTK float64, rootnode *Node) (*Node
 		// and the other lost. Trace back up the tree, updating
 		// each node's wins and visit count.
 
-		state.resetCachedResults()
 		for ; node != nil; node = node.parentNode {
 			node.Update(state.GetResult(node.playerJustMoved))
+			if raveK > 0 {
+				node.amaf(state, false)
+			}
 		}
 	}
 
 	// The "value" of this move is somewhat fictitious, and
 	// not related to Negascout or any minimax value function.
-	moveChoice := rootnode.bestMove(UCTK)
-	return moveChoice, leafNodeCount, int(1000. * moveChoice.UCB1(UCTK))
+	moveChoice := rootnode.bestMove()
+	if moveChoice == nil {
+		return nil, leafNodeCount, 0, stopped
+	}
+	return moveChoice, leafNodeCount, moveChoice.value(), stopped
 }
 
 func NewNode(move int, parent *Node, state *GameState) *Node {
@@ -204,10 +269,17 @@ func NewNode(move int, parent *Node, state *GameState) *Node {
 	n.parentNode = parent
 	n.untriedMoves, _ = state.GetMoves()
 	n.playerJustMoved = state.playerJustMoved
+	switch state.winner {
+	case game.UNSET:
+	case n.playerJustMoved:
+		n.proven, n.plies = provenWin, 1
+	default:
+		n.proven, n.plies = provenLoss, 1
+	}
 	return &n
 }
 
-// Since there's at most 25 moves to consider,
+// Since there's at most one move per cell to consider,
 // just look through them rather than incur
 // sorting overhead. It seems like maybe caching
 // the best child node might help performance. Have
@@ -215,24 +287,42 @@ func NewNode(move int, parent *Node, state *GameState) *Node {
 // because a change to that array invalidates the
 // choice of "best" move. Also, does UCB1() score for
 // a given child node stay the same? I don't think it does.
-func (p *Node) bestMove(UCTK float64) *Node {
+// A proven win comes first, the quickest one, and a proven
+// loss last, the slowest one, only if every move loses.
+// With raveK more than 0, scores blend in AMAF win rates.
+// If locked, it locks each child node while it reads the
+// child's statistics, and the caller holds p's lock.
+func (p *Node) selectChild(UCTK float64, raveK float64, locked bool) *Node {
 	bestscore := math.SmallestNonzeroFloat64
-	var bestmove *Node
+	var bestmove, win, loss *Node
+	winPlies, lossPlies := 0, 0
 	for _, c := range p.childNodes {
-		ucb1 := c.UCB1(UCTK)
+		c.lock(locked)
+		proven, plies := c.proven, c.plies
+		ucb1 := c.raveUCB1(UCTK, raveK)
+		c.unlock(locked)
+		switch proven {
+		case provenWin:
+			if win == nil || plies < winPlies {
+				win, winPlies = c, plies
+			}
+			continue
+		case provenLoss:
+			if loss == nil || plies > lossPlies {
+				loss, lossPlies = c, plies
+			}
+			continue
+		}
 		if ucb1 > bestscore {
 			bestscore = ucb1
 			bestmove = c
 		}
 	}
+	if win != nil {
+		return win
+	}
 	if bestmove == nil {
-		fmt.Printf("Node has %d children\n", len(p.childNodes))
-		fmt.Printf("player just moved %d\n", p.playerJustMoved)
-		fmt.Printf("Move %d, wins %v visits %v\n", p.move, p.wins, p.visits)
-		for i, c := range p.childNodes {
-			ucb1 := c.UCB1(UCTK)
-			fmt.Printf("Child %d score %v\n", i, ucb1)
-		}
+		return loss // nil if p has no children
 	}
 	return bestmove
 }
@@ -241,8 +331,74 @@ func (p *Node) String() string {
 	return fmt.Sprintf("Move %d, parent %p, childNodes %v, wins %f, visits %f, %d untried, %d moved", p.move, p.parentNode, p.childNodes, p.wins, p.visits, len(p.untriedMoves), p.playerJustMoved)
 }
 
-func (p *Node) UCTSelectChild(UCTK float64) *Node {
-	return p.bestMove(UCTK)
+// principalVariation follows the most-visited child nodes
+// from p on down, which is the line of moves that the tree
+// search expects both players to make on a board of shape.
+func (p *Node) principalVariation(shape *game.Shape) []engine.Move {
+	var pv []engine.Move
+	for node := p; node != nil; node = node.mostVisited() {
+		x, y := shape.Coords(node.move)
+		pv = append(pv, engine.Move{X: x, Y: y})
+	}
+	return pv
+}
+
+func (p *Node) mostVisited() *Node {
+	var best *Node
+	for _, c := range p.childNodes {
+		if best == nil || c.visits > best.visits {
+			best = c
+		}
+	}
+	return best
+}
+
+// lock locks p, if locked, for tree parallel search
+func (p *Node) lock(locked bool) {
+	if locked {
+		p.mu.Lock()
+	}
+}
+
+// unlock unlocks p, if locked
+func (p *Node) unlock(locked bool) {
+	if locked {
+		p.mu.Unlock()
+	}
+}
+
+func (p *Node) UCTSelectChild(UCTK float64, raveK float64) *Node {
+	return p.selectChild(UCTK, raveK, false)
+}
+
+// bestMove returns the child node of the move to make once the
+// search is done: a proven win, the quickest, or else the unproven
+// move with the most visits, or else, if every move loses, the
+// slowest proven loss. Exploration only decides what to search,
+// so UCB1 has no say. nil means p has no children.
+func (p *Node) bestMove() *Node {
+	var bestmove, win, loss *Node
+	for _, c := range p.childNodes {
+		switch {
+		case c.proven == provenWin:
+			if win == nil || c.plies < win.plies {
+				win = c
+			}
+		case c.proven == provenLoss:
+			if loss == nil || c.plies > loss.plies {
+				loss = c
+			}
+		case bestmove == nil || c.visits > bestmove.visits:
+			bestmove = c
+		}
+	}
+	switch {
+	case win != nil:
+		return win
+	case bestmove != nil:
+		return bestmove
+	}
+	return loss
 }
 
 func (p *Node) UCB1(UCTK float64) float64 {
@@ -279,19 +435,34 @@ func NewGameState() *GameState {
 func (p *GameState) Clone() *GameState {
 	var st GameState
 	st.playerJustMoved = p.playerJustMoved
-	st.board = p.board // copy since board has type [25]int
+	st.board = p.board // copy since board has type game.Bitboard
+	st.winner = p.winner
+	st.rules = p.rules
 	return &st
 }
 
-func (p *GameState) resetCachedResults() {
-	p.cachedResults[0] = -1
-	p.cachedResults[1] = -1
-	p.cachedResults[2] = -1
-}
-
+// DoMove makes the next player's move in cell number move.
+// Only the last move can end the game, so checking the lines
+// through it keeps the winner up to date.
 func (p *GameState) DoMove(move int) {
 	p.playerJustMoved = -p.playerJustMoved
-	p.board[move] = p.playerJustMoved
+	p.board.MakeMove(move, p.playerJustMoved)
+	p.winner = p.rules.MoveWinner(&p.board, move)
+}
+
+// playout makes random moves, chosen by rng, until
+// somebody wins, or the board fills up.
+func (p *GameState) playout(rng *rand.Rand) {
+	for p.winner == game.UNSET {
+		empty := p.board.Empty()
+		if empty == 0 {
+			return
+		}
+		for k := rng.Intn(bits.OnesCount64(empty)); k > 0; k-- {
+			empty &= empty - 1
+		}
+		p.DoMove(bits.TrailingZeros64(empty))
+	}
 }
 
 func (p *MCTS) updateMoves(m int) {
@@ -308,139 +479,26 @@ func (p *MCTS) updateMoves(m int) {
 }
 
 func (p GameState) GetMoves() ([]int, bool) {
-
-	// Only have to check the 9 cells in importantCells[]
-	// for 4 or 3 in a row configs.
-	for _, m := range importantCells {
-		if p.board[m] != UNSET {
-			for _, quad := range winningQuads[m] {
-				sum := p.board[quad[0]] + p.board[quad[1]] + p.board[quad[2]] + p.board[quad[3]]
-				if sum == 4 || sum == -4 {
-					return []int{}, true
-				}
-			}
-			for _, triplet := range losingTriplets[m] {
-				sum := p.board[triplet[0]] + p.board[triplet[1]] + p.board[triplet[2]]
-				if sum == 3 || sum == -3 {
-					return []int{}, true
-				}
-			}
-		}
+	// A board with a win or a loss on it has no
+	// valid moves. I don't believe "cat" games exist
+	// in Squava, but a full board ends the game too.
+	if p.winner != game.UNSET {
+		return nil, true
 	}
-
-	// Get here, p.board does not represent a win or a loss.
-	// Pick out empty cells in p.board for a list of valid moves.
-	// I don't believe "cat" games exist in Squava, but this code
-	// handles that case.
-
-	endOfGame := true
 	var moves []int
-	for i := 0; i < 25; i++ {
-		if p.board[i] == UNSET {
-			endOfGame = false
-			moves = append(moves, i)
-		}
+	for empty := p.board.Empty(); empty != 0; empty &= empty - 1 {
+		moves = append(moves, bits.TrailingZeros64(empty))
 	}
-
-	return moves, endOfGame
+	return moves, len(moves) == 0
 }
 
 func (p *GameState) GetResult(playerjm int) float64 {
-	cached := p.cachedResults[playerjm+1]
-	if cached >= 0.0 {
-		return cached
-	}
-	// Need to check all 4-in-a-row wins before checking
-	// any 3-in-a-row losses, otherwise the result ends
-	// up wrong.
-	for _, i := range importantCells {
-		if p.board[i] != UNSET {
-			for _, quad := range winningQuads[i] {
-				sum := p.board[quad[0]] + p.board[quad[1]] + p.board[quad[2]] + p.board[quad[3]]
-				if sum == 4 || sum == -4 {
-					if sum == 4*playerjm {
-						p.cachedResults[playerjm+1] = 1.0
-						return 1.0
-					}
-					p.cachedResults[playerjm+1] = 0.0
-					return 0.0
-				}
-			}
-		}
-	}
-	for _, i := range importantCells {
-		if p.board[i] != UNSET {
-			for _, triplet := range losingTriplets[i] {
-				sum := p.board[triplet[0]] + p.board[triplet[1]] + p.board[triplet[2]]
-				if sum == 3 || sum == -3 {
-					if sum == 3*playerjm {
-						p.cachedResults[playerjm+1] = 0.0
-						return 0.0
-					}
-					p.cachedResults[playerjm+1] = 1.0
-					return 1.0
-				}
-			}
-		}
+	if p.winner == playerjm {
+		return 1.0
 	}
-	p.cachedResults[playerjm+1] = 0.0
-	return 0.0 // Should probably never get here.
+	return 0.0
 }
 
 func (p *GameState) String() string {
-	s := "   0 1 2 3 4\n"
-	for i := 0; i < 25; i++ {
-		if (i % 5) == 0 {
-			s += string((i/5)+'0') + "  "
-		}
-		s += string("O_X"[p.board[i]+1]) + " "
-		if (i % 5) == 4 {
-			s += "\n"
-		}
-	}
-	return s
-}
-
-var importantCells = [9]int{2, 7, 10, 11, 12, 13, 14, 17, 22}
-
-// 25 rows only to make looping easier. The filled-in
-// rows are the only quads you actually have to check
-// to find out if there's a win
-var winningQuads = [25][][]int{
-	{}, {},
-	{{0, 1, 2, 3}, {1, 2, 3, 4}, {2, 7, 12, 17}},
-	{}, {}, {}, {},
-	{{5, 6, 7, 8}, {6, 7, 8, 9}, {7, 12, 17, 22}},
-	{}, {},
-	{{0, 5, 10, 15}, {5, 10, 15, 20}},
-	{{1, 6, 11, 16}, {6, 11, 16, 21}, {3, 7, 11, 15}, {5, 11, 17, 23}},
-	{{10, 11, 12, 13}, {11, 12, 13, 14}, {4, 8, 12, 16}, {8, 12, 16, 20}, {0, 6, 12, 18}, {6, 12, 18, 24}},
-	{{3, 8, 13, 18}, {8, 13, 18, 23}, {1, 7, 13, 19}, {9, 13, 17, 21}},
-	{{4, 9, 14, 19}, {9, 14, 19, 24}},
-	{}, {},
-	{{15, 16, 17, 18}, {16, 17, 18, 19}},
-	{}, {}, {}, {},
-	{{20, 21, 22, 23}, {21, 22, 23, 24}},
-	{}, {},
-}
-
-// 25 rows only to make looping easier. The filled-in
-// rows are the only triplets you actually have to check
-// to find out if there's a loss.
-var losingTriplets = [][][]int{
-	{}, {},
-	{{0, 1, 2}, {1, 2, 3}, {2, 3, 4}, {2, 7, 12}, {2, 6, 10}, {14, 8, 2}},
-	{}, {}, {}, {},
-	{{5, 6, 7}, {6, 7, 8}, {7, 8, 9}, {2, 7, 12}, {7, 12, 17}, {3, 7, 11}, {7, 11, 15}, {1, 7, 13}, {7, 13, 19}},
-	{}, {},
-	{{10, 11, 12}, {0, 5, 10}, {5, 10, 15}, {10, 15, 20}, {2, 6, 10}, {10, 16, 22}},
-	{{10, 11, 12}, {11, 12, 13}, {1, 6, 11}, {6, 11, 16}, {11, 16, 21}, {3, 7, 11}, {7, 11, 15}, {5, 11, 17}, {11, 17, 23}},
-	{{10, 11, 12}, {11, 12, 13}, {12, 13, 14}, {2, 7, 12}, {7, 12, 17}, {12, 17, 22}, {0, 6, 12}, {6, 12, 18}, {12, 18, 24}, {4, 8, 12}, {8, 12, 16}, {12, 16, 20}},
-	{{11, 12, 13}, {12, 13, 14}, {3, 8, 13}, {8, 13, 18}, {13, 18, 23}, {1, 7, 13}, {7, 13, 19}, {21, 17, 13}, {17, 13, 9}},
-	{{12, 13, 14}, {4, 9, 14}, {9, 14, 19}, {14, 19, 24}, {22, 18, 14}, {14, 8, 2}},
-	{}, {},
-	{{15, 16, 17}, {16, 17, 18}, {17, 18, 19}, {7, 12, 17}, {12, 17, 22}, {5, 11, 17}, {11, 17, 23}, {21, 17, 13}, {17, 13, 9}},
-	{}, {}, {}, {},
-	{{20, 21, 22}, {21, 22, 23}, {22, 23, 24}, {12, 17, 22}, {10, 16, 22}, {22, 18, 14}},
-	{}, {},
+	return p.board.String()
 }
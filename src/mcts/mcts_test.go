@@ -0,0 +1,157 @@
+package mcts
+
+import (
+	"context"
+	"testing"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/score"
+	"squava/src/solver"
+)
+
+// newPlayer returns an MCTS player with moves made, X first
+func newPlayer(moves [][2]int, mode Parallel) *MCTS {
+	p := New(true, 0)
+	p.SetIterations(4000)
+	p.SetParallel(mode, 4)
+	player := game.MAXIMIZER
+	for _, move := range moves {
+		p.MakeMove(move[0], move[1], player)
+		player = -player
+	}
+	return p
+}
+
+var modes = []Parallel{Serial, Root, Tree, Leaf}
+
+// Every kind of search finds an immediate win, and
+// grows trees that make sense, as go test -race checks.
+func TestParallel(t *testing.T) {
+	moves := [][2]int{{0, 0}, {4, 4}, {0, 1}, {2, 4}, {0, 3}, {4, 1}}
+	for _, mode := range modes {
+		p := newPlayer(moves, mode)
+		move, _, err := p.ChooseMove(context.Background())
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		if move != (engine.Move{X: 0, Y: 2}) {
+			t.Errorf("%v: chose %v, want the win at <0,2>", mode, move)
+		}
+
+		// From the empty board, with nothing proven
+		// to cut the search short, and with RAVE
+		p = newPlayer(nil, mode)
+		p.SetRAVE(300)
+		if _, info, err := p.ChooseMove(context.Background()); err != nil || info.LeafCount < 4000 {
+			t.Errorf("%v: %d playouts, %v", mode, info.LeafCount, err)
+		}
+		if err := checkTree(p.movesNode, 0); err != "" {
+			t.Errorf("%v: %s", mode, err)
+		}
+	}
+}
+
+// checkTree returns what's wrong with the counts in the tree under
+// node, which has had its visits from the parent's point of view:
+// children's visits add up to no more than the node's.
+func checkTree(node *Node, depth int) string {
+	var visits float64
+	for _, c := range node.childNodes {
+		visits += c.visits
+		if c.wins > c.visits {
+			return "more wins than visits"
+		}
+		if c.parentNode != node {
+			return "child with the wrong parent"
+		}
+		if depth < 3 {
+			if err := checkTree(c, depth+1); err != "" {
+				return err
+			}
+		}
+	}
+	if visits > node.visits {
+		return "children visited more than their parent"
+	}
+	return ""
+}
+
+func TestGameOver(t *testing.T) {
+	moves := [][2]int{{0, 0}, {4, 4}, {0, 1}, {4, 3}, {0, 3}, {3, 4}, {0, 2}}
+	for _, mode := range modes {
+		p := newPlayer(moves, mode)
+		if move, _, err := p.ChooseMove(context.Background()); err != engine.ErrNoMove {
+			t.Errorf("%v: chose %v, %v after X won", mode, move, err)
+		}
+	}
+}
+
+// X to move wins in 3: X takes <2,2>, threatening 4 in a row at
+// <2,1>, and O can't block there without 3 in a row from <1,0>.
+var winIn3 = [][2]int{{2, 3}, {0, 0}, {2, 0}, {1, 0}, {4, 2}, {3, 2}}
+
+func TestProvesForcedWin(t *testing.T) {
+	for _, mode := range modes {
+		p := newPlayer(winIn3, mode)
+		p.SetIterations(1000000)
+		move, info, err := p.ChooseMove(context.Background())
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		if !info.Value.IsWin() {
+			t.Errorf("%v: %v worth %v, want a proven win", mode, move, info.Value)
+		}
+		if info.LeafCount >= 1000000 {
+			t.Errorf("%v: didn't stop when the win was proven", mode)
+		}
+		b := p.game.board
+		if result, err := solver.Solve(context.Background(), game.FourWins, b, game.MINIMIZER, 0); err != nil || result.Outcome != solver.FirstPlayerWin {
+			t.Errorf("%v: after %v, %v, %v", mode, move, result.Outcome, err)
+		}
+	}
+}
+
+// node makes a child of parent, with visits and wins
+func node(parent *Node, move int, visits, wins float64, proven, plies int) *Node {
+	n := &Node{move: move, parentNode: parent, visits: visits, wins: wins, proven: proven, plies: plies}
+	parent.childNodes = append(parent.childNodes, n)
+	parent.visits += visits
+	return n
+}
+
+func TestBestMove(t *testing.T) {
+	root := &Node{move: -1}
+	most := node(root, 0, 100, 40, unproven, 0)
+	node(root, 1, 20, 18, unproven, 0) // best win rate, and UCB1
+	node(root, 2, 50, 0, provenLoss, 2)
+	if got := root.bestMove(); got != most {
+		t.Errorf("chose move %d, want the most visited, 0", got.move)
+	}
+	if got := most.value(); got != 400 {
+		t.Errorf("value %d, want 400, the share of wins", got)
+	}
+	if root.UCTSelectChild(1.0, 0) == most {
+		t.Errorf("UCB1 chose the most visited move")
+	}
+
+	slow := node(root, 3, 5, 5, provenWin, 5)
+	quick := node(root, 4, 1, 1, provenWin, 3)
+	if got := root.bestMove(); got != quick {
+		t.Errorf("chose move %d, want the quickest win, 4", got.move)
+	}
+	if got := score.Score(slow.value()); got != score.Win(5) {
+		t.Errorf("proven win's value %v, want %v", got, score.Win(5))
+	}
+
+	root = &Node{move: -1}
+	node(root, 0, 10, 0, provenLoss, 2)
+	slowest := node(root, 1, 10, 0, provenLoss, 6)
+	node(root, 2, 10, 0, provenLoss, 4)
+	if got := root.bestMove(); got != slowest {
+		t.Errorf("chose move %d, want the slowest loss, 1", got.move)
+	}
+	if got := (&Node{move: -1}).bestMove(); got != nil {
+		t.Errorf("chose move %d with no moves", got.move)
+	}
+}
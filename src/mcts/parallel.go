@@ -0,0 +1,333 @@
+package mcts
+
+/* Parallel Monte Carlo tree search, three ways.
+ *
+ * Root parallelization grows an independent tree in each goroutine,
+ * and adds up the visits and wins of the trees' root moves at the
+ * end. Nothing is shared while they search, so nothing waits.
+ *
+ * Tree parallelization grows one tree. Each node has a lock, and a
+ * goroutine holds a node's lock only while it chooses a child, adds
+ * one, or backs a result up through the node, so goroutines wait for
+ * each other only at the same node. Every node it walks through gets
+ * a virtual loss, a visit without a win, until the playout's result
+ * comes back, so that the other goroutines walking down meanwhile
+ * tend to try other moves.
+ *
+ * Leaf parallelization grows one tree in one goroutine, and plays
+ * out each new node several times at once, in goroutines of their own.
+ */
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"squava/src/engine"
+	"squava/src/game"
+)
+
+// Parallel is a way of searching in more than one goroutine
+type Parallel int
+
+const (
+	// Serial searches in one goroutine, whatever the thread count
+	Serial Parallel = iota
+	// Root grows a tree per goroutine, merged by visit counts
+	Root
+	// Tree grows one tree, with virtual loss
+	Tree
+	// Leaf plays out each new node in every goroutine
+	Leaf
+)
+
+var parallelNames = [...]string{"serial", "root", "tree", "leaf"}
+
+func (m Parallel) String() string {
+	if m >= 0 && int(m) < len(parallelNames) {
+		return parallelNames[m]
+	}
+	return fmt.Sprintf("Parallel(%d)", int(m))
+}
+
+// ParseParallel converts "serial", "root", "tree" or "leaf",
+// as in a command line flag, to Parallel.
+func ParseParallel(name string) (Parallel, error) {
+	for m, n := range parallelNames {
+		if strings.ToLower(name) == n {
+			return Parallel(m), nil
+		}
+	}
+	return Serial, fmt.Errorf("unknown MCTS parallelization %q, want serial, root, tree or leaf", name)
+}
+
+// SetParallel makes the player search with mode, in threads
+// goroutines. The iterations get shared out among them.
+func (p *MCTS) SetParallel(mode Parallel, threads int) {
+	if threads < 1 {
+		threads = 1
+	}
+	p.parallel = mode
+	p.threads = threads
+}
+
+// search runs the player's kind of search from its position,
+// and returns what UCT does.
+func (p *MCTS) search(ctx context.Context) (*Node, int, int, bool) {
+	if p.threads > 1 {
+		switch p.parallel {
+		case Root:
+			return rootUCT(ctx, p.game, p.iterations, p.UCTK, p.raveK, p.threads)
+		case Tree:
+			return treeUCT(ctx, p.game, p.iterations, p.UCTK, p.raveK, p.movesNode, p.threads)
+		case Leaf:
+			return leafUCT(ctx, p.game, p.iterations, p.UCTK, p.raveK, p.movesNode, p.threads)
+		}
+	}
+	return UCT(ctx, p.game, p.iterations, p.UCTK, p.raveK, p.movesNode)
+}
+
+// rootUCT runs UCT in threads goroutines, each on a new tree, so
+// that no tree's visits from earlier moves outweigh the others'. It
+// chooses a move any tree proved wins, or else the root move with the
+// most visits, all trees together, that no tree proved loses. It
+// returns that move's node from the first tree that has one, or
+// nil if the game is over, and its value is its share of wins, all
+// trees together.
+func rootUCT(ctx context.Context, rootstate *GameState, itermax int, UCTK float64, raveK float64, threads int) (*Node, int, int, bool) {
+
+	share := itermax / threads
+	if share < 1 {
+		share = 1
+	}
+
+	roots := make([]*Node, threads)
+	leaves := make([]int, threads)
+	stopped := make([]bool, threads)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func(t int) {
+			defer wg.Done()
+			var best *Node
+			best, leaves[t], _, stopped[t] = UCT(ctx, rootstate, share, UCTK, raveK, nil)
+			if best != nil {
+				roots[t] = best.parentNode
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	// A move proven in any tree is proven in them all
+	var visits, wins [game.MaxCells]float64
+	var nodes [game.MaxCells]*Node
+	for _, root := range roots {
+		if root == nil {
+			continue
+		}
+		for _, c := range root.childNodes {
+			visits[c.move] += c.visits
+			wins[c.move] += c.wins
+			if nodes[c.move] == nil || c.proven != unproven {
+				nodes[c.move] = c
+			}
+		}
+	}
+
+	best := -1
+	var win, loss *Node
+	for move, node := range nodes {
+		switch {
+		case node == nil:
+		case node.proven == provenWin:
+			if win == nil || node.plies < win.plies {
+				win = node
+			}
+		case node.proven == provenLoss:
+			if loss == nil || node.plies > loss.plies {
+				loss = node
+			}
+		case best < 0 || visits[move] > visits[best]:
+			best = move
+		}
+	}
+
+	leafCount, anyStopped := 0, false
+	for t := range leaves {
+		leafCount += leaves[t]
+		anyStopped = anyStopped || stopped[t]
+	}
+
+	switch {
+	case win != nil:
+		return win, leafCount, win.value(), anyStopped
+	case loss != nil && best < 0:
+		return loss, leafCount, loss.value(), anyStopped
+	case best < 0:
+		return nil, leafCount, 0, anyStopped
+	}
+	return nodes[best], leafCount, int(1000. * wins[best] / visits[best]), anyStopped
+}
+
+// treeUCT does itermax iterations on rootnode's tree, among
+// threads goroutines, with virtual loss, and returns what UCT does.
+// A goroutine holds one node's lock at a time, or a node's and then
+// one of its children's, never a child's and then its parent's.
+func treeUCT(ctx context.Context, rootstate *GameState, itermax int, UCTK float64, raveK float64, rootnode *Node, threads int) (*Node, int, int, bool) {
+
+	if rootnode == nil {
+		rootnode = NewNode(-1, nil, rootstate)
+	} else {
+		rootnode.playerJustMoved = rootstate.playerJustMoved
+	}
+
+	var started, leaves int64
+	var stopped int32
+
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				// Every goroutine's first iteration is done, so
+				// rootnode always has a child to choose.
+				if i&63 == 1 && engine.Cancelled(ctx) {
+					atomic.StoreInt32(&stopped, 1)
+					return
+				}
+				if atomic.AddInt64(&started, 1) > int64(itermax) {
+					return
+				}
+
+				// Walk down, adding a virtual loss to each node
+				node := rootnode
+				state := rootstate.Clone()
+				node.mu.Lock()
+				if node.proven != unproven {
+					node.mu.Unlock()
+					return
+				}
+				node.visits++
+				for len(node.untriedMoves) == 0 && len(node.childNodes) > 0 {
+					child := node.selectChild(UCTK, raveK, true)
+					node.mu.Unlock()
+					node = child
+					state.DoMove(node.move)
+					node.mu.Lock()
+					node.visits++
+				}
+				if len(node.untriedMoves) > 0 {
+					m := node.expand(state, rng)
+					state.DoMove(m)
+					child := node.AddChild(m, state)
+					child.visits++
+					node.mu.Unlock()
+					node = child
+					node.prove(true)
+				} else {
+					node.mu.Unlock()
+				}
+
+				state.playout(rng)
+
+				// The visits are counted, only the wins are left
+				for n := node; n != nil; n = n.parentNode {
+					n.mu.Lock()
+					n.wins += state.GetResult(n.playerJustMoved)
+					if raveK > 0 {
+						n.amaf(state, true)
+					}
+					n.mu.Unlock()
+				}
+				atomic.AddInt64(&leaves, 1)
+			}
+		}(rand.New(rand.NewSource(rand.Int63())))
+	}
+	wg.Wait()
+
+	moveChoice := rootnode.bestMove()
+	if moveChoice == nil {
+		return nil, int(leaves), 0, stopped != 0
+	}
+	return moveChoice, int(leaves), moveChoice.value(), stopped != 0
+}
+
+// leafUCT does itermax playouts on rootnode's tree, threads
+// at a time from each new node, and returns what UCT does.
+func leafUCT(ctx context.Context, rootstate *GameState, itermax int, UCTK float64, raveK float64, rootnode *Node, threads int) (*Node, int, int, bool) {
+
+	if rootnode == nil {
+		rootnode = NewNode(-1, nil, rootstate)
+	} else {
+		rootnode.playerJustMoved = rootstate.playerJustMoved
+	}
+
+	jobs := make(chan *GameState)
+	results := make(chan *GameState, threads)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for state := range jobs {
+				state.playout(rng)
+				results <- state
+			}
+		}(rand.New(rand.NewSource(rand.Int63())))
+	}
+
+	rng := rand.New(rand.NewSource(rand.Int63()))
+	leaves, stopped := 0, false
+
+	for i := 0; leaves < itermax; i++ {
+
+		if i&63 == 1 && engine.Cancelled(ctx) {
+			stopped = true
+			break
+		}
+		if rootnode.proven != unproven {
+			break
+		}
+
+		node := rootnode
+		state := rootstate.Clone()
+
+		for len(node.untriedMoves) == 0 && len(node.childNodes) > 0 {
+			node = node.UCTSelectChild(UCTK, raveK)
+			state.DoMove(node.move)
+		}
+
+		if len(node.untriedMoves) > 0 {
+			m := node.expand(state, rng)
+			state.DoMove(m)
+			node = node.AddChild(m, state)
+			node.prove(false)
+		}
+
+		for t := 0; t < threads; t++ {
+			jobs <- state.Clone()
+		}
+		for t := 0; t < threads; t++ {
+			result := <-results
+			for n := node; n != nil; n = n.parentNode {
+				n.Update(result.GetResult(n.playerJustMoved))
+				if raveK > 0 {
+					n.amaf(result, false)
+				}
+			}
+		}
+		leaves += threads
+	}
+	close(jobs)
+	wg.Wait()
+
+	moveChoice := rootnode.bestMove()
+	if moveChoice == nil {
+		return nil, leaves, 0, stopped
+	}
+	return moveChoice, leaves, moveChoice.value(), stopped
+}
@@ -0,0 +1,58 @@
+package mcts
+
+/* RAVE, rapid action value estimation. A squava move is a mark
+ * in a cell, worth much the same whichever turn it gets made on,
+ * so a playout through a node says something about every move
+ * from the node that the playout made later, not just the one it
+ * made first: all moves as first, AMAF. Each node keeps AMAF wins
+ * and visits as well as its own, and selection blends the two win
+ * rates, trusting AMAF less as a node's own visits pile up. With
+ * k, the equivalence parameter, the AMAF win rate's weight after
+ * n visits is sqrt(k/(3n+k)), one half when n is k.
+ */
+
+import (
+	"math"
+
+	"squava/src/game"
+)
+
+// SetRAVE sets the RAVE equivalence parameter, roughly how many
+// visits a node needs before its own win rate counts as much as
+// its AMAF win rate. 0 turns RAVE off, for plain UCT.
+func (p *MCTS) SetRAVE(k float64) {
+	if k < 0 {
+		k = 0
+	}
+	p.raveK = k
+}
+
+// amaf counts the playout that ended in state in the AMAF
+// statistics of each of p's child nodes whose move the player
+// to move at p made at some point in the playout. If locked,
+// it locks each child, and the caller holds p's lock.
+func (p *Node) amaf(state *GameState, locked bool) {
+	mover := -p.playerJustMoved
+	marks := state.board.Marks(mover)
+	result := state.GetResult(mover)
+	for _, c := range p.childNodes {
+		if marks&game.Bit(c.move) != 0 {
+			c.lock(locked)
+			c.raveVisits++
+			c.raveWins += result
+			c.unlock(locked)
+		}
+	}
+}
+
+// raveUCB1 is UCB1 with the node's win rate
+// blended with its AMAF win rate
+func (p *Node) raveUCB1(UCTK float64, raveK float64) float64 {
+	if raveK == 0 || p.raveVisits == 0 {
+		return p.UCB1(UCTK)
+	}
+	visits := p.visits + math.SmallestNonzeroFloat64
+	beta := math.Sqrt(raveK / (3.*p.visits + raveK))
+	rate := (1.-beta)*p.wins/visits + beta*p.raveWins/p.raveVisits
+	return rate + UCTK*math.Sqrt(2.*math.Log(p.parentNode.visits)/visits)
+}
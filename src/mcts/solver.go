@@ -0,0 +1,110 @@
+package mcts
+
+/* MCTS-Solver. Playouts only estimate a move's chances, but
+ * a move that ends the game, or one after which every reply
+ * loses, has an exact value. A node carries that value once
+ * it's proven, and the proof goes up the tree: a move with a
+ * winning reply loses, and a move with nothing but losing
+ * replies wins. Selection never goes down a proven loss, and
+ * always takes a proven win, and the search stops once the
+ * root's move is decided.
+ */
+
+import (
+	"math/rand"
+
+	"squava/src/game"
+	"squava/src/score"
+	"squava/src/threats"
+)
+
+// Node.proven values, for the player who moved to the node
+const (
+	unproven   = 0
+	provenWin  = 1
+	provenLoss = -1
+)
+
+// prove works out what p, newly added to the tree,
+// proves about its ancestors, as far up as it goes. If
+// locked, it holds one node's lock at a time, or a node's
+// and then one of its children's.
+func (p *Node) prove(locked bool) {
+	for node := p; node.parentNode != nil; node = node.parentNode {
+		node.lock(locked)
+		proven, plies := node.proven, node.plies
+		node.unlock(locked)
+		if proven == unproven {
+			return
+		}
+		parent := node.parentNode
+		parent.lock(locked)
+		ok := parent.proveBy(proven, plies, locked)
+		parent.unlock(locked)
+		if !ok {
+			return
+		}
+	}
+}
+
+// proveBy works out what a child node's proven result, plies
+// plies off, proves about p, and returns true if it proves p.
+func (p *Node) proveBy(proven int, plies int, locked bool) bool {
+	if p.proven != unproven {
+		return false
+	}
+	if proven == provenWin {
+		// The move to p allows this win
+		p.proven, p.plies = provenLoss, plies+1
+		return true
+	}
+	// The move to p wins if every reply loses,
+	// taking as long as the longest of them.
+	if len(p.untriedMoves) > 0 {
+		return false
+	}
+	longest := 0
+	for _, c := range p.childNodes {
+		c.lock(locked)
+		proven, plies := c.proven, c.plies
+		c.unlock(locked)
+		if proven != provenLoss {
+			return false
+		}
+		if plies > longest {
+			longest = plies
+		}
+	}
+	p.proven, p.plies = provenWin, longest+1
+	return true
+}
+
+// expand chooses which of p's untried moves to add to the
+// tree, in state, p's position: a winning move if there is one,
+// since that proves p, otherwise one at random.
+func (p *Node) expand(state *GameState, rng *rand.Rand) int {
+	holes := threats.Holes(&state.board, state.rules, -state.playerJustMoved)
+	if holes != 0 {
+		for _, m := range p.untriedMoves {
+			if holes&game.Bit(m) != 0 {
+				return m
+			}
+		}
+	}
+	return p.untriedMoves[rng.Intn(len(p.untriedMoves))]
+}
+
+// value returns the value of choosing node, a proven
+// win or loss, or 1000 times its share of wins.
+func (p *Node) value() int {
+	switch p.proven {
+	case provenWin:
+		return int(score.Win(p.plies))
+	case provenLoss:
+		return int(score.Loss(p.plies))
+	}
+	if p.visits == 0 {
+		return 0
+	}
+	return int(1000. * p.wins / p.visits)
+}
@@ -1,13 +1,20 @@
 package mcts3
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/score"
+	"squava/src/tablebase"
 )
 
 type GameState struct {
 	player        int
-	board         [25]int
+	board         game.Board
 	cachedResults [3]float64
 }
 
@@ -25,17 +32,12 @@ type Node struct {
 	winner       int
 }
 
-// Manifest constants to improve understanding
-const (
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
-)
-
 type MCTS3 struct {
-	board           [25]int
+	board           game.Board
 	playerJustMoved int
 	iterations      int
+	rules           game.Rules
+	tablebase       *tablebase.Table
 }
 
 func New(_ bool, _ int) *MCTS3 {
@@ -51,54 +53,82 @@ func (p *MCTS3) SetIterations(iterations int) {
 }
 
 func (p *MCTS3) MakeMove(x, y int, player int) {
-	p.board[5*x+y] = player
+	p.board.MakeMove(x, y, player)
 	p.playerJustMoved = player
 }
 
-func (p *MCTS3) SetDepth(_ int) {
+// SwapSides trades the player's marks for its opponent's,
+// for the swap rule
+func (p *MCTS3) SwapSides() {
+	for i, row := range p.board {
+		for j := range row {
+			p.board[i][j] = -p.board[i][j]
+		}
+	}
+	p.playerJustMoved = -p.playerJustMoved
 }
 
-// ChooseMove should choose computer's next move and
-// return x,y coords of move and its score.
-func (p *MCTS3) ChooseMove() (xcoord int, ycoord int, value int, leafcount int) {
-
-	var best int
+func (p *MCTS3) SetDepth(_ int) {
+}
 
-	best, value, leafcount = bestMove(p.board, p.iterations)
+// ChooseMove should choose computer's next move and return it,
+// and its score. Cancelling ctx cuts the iterations short.
+func (p *MCTS3) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
+
+	start := time.Now()
+
+	if p.tablebase != nil {
+		bd := game.NewBitboard(&p.board)
+		if cell, v, ok := p.tablebase.BestMove(&bd, game.MAXIMIZER); ok && cell >= 0 {
+			// Perfect play, no playouts needed
+			xcoord, ycoord := game.Coords(cell)
+			p.board.MakeMove(xcoord, ycoord, game.MAXIMIZER)
+			return engine.Move{X: xcoord, Y: ycoord}, engine.SearchInfo{
+				Value:   v,
+				Elapsed: time.Since(start),
+				PV:      engine.Moves(game.Standard, p.tablebase.Line(&bd, game.MAXIMIZER)),
+			}, nil
+		}
+	}
 
-	p.board[best] = MAXIMIZER
+	best, value, leafcount, stopped, pv := bestMove(ctx, p.board, p.rules, p.iterations)
 
-	// Since this player's "board" is a plain array, a move has to translate
+	// Since this player's moves are cell numbers, a move has to translate
 	// to <x,y> coords
-	xcoord = best / 5
-	ycoord = best % 5
+	xcoord, ycoord := game.Coords(best)
+	p.board.MakeMove(xcoord, ycoord, game.MAXIMIZER)
 	fmt.Printf("best move %d <%d,%d>\n", best, xcoord, ycoord)
 
-	return
+	return engine.Move{X: xcoord, Y: ycoord}, engine.SearchInfo{
+		Value:     score.Score(value),
+		LeafCount: leafcount,
+		Stopped:   stopped,
+		Elapsed:   time.Since(start),
+		PV:        pv,
+	}, nil
 }
 
-func bestMove(board [25]int, iterations int) (move int, value int, leafCount int) {
+func bestMove(ctx context.Context, board game.Board, rules game.Rules, iterations int) (move int, value int, leafCount int, stopped bool, pv []engine.Move) {
 
 	fmt.Printf("enter bestMove, %d iterations\n", iterations)
 
 	root := &Node{
-		player: MINIMIZER,
+		player: game.MINIMIZER,
 	}
 	root.untriedMoves = make([]int, 0, 25)
-	for i := range board {
-		if board[i] == UNSET {
-			root.untriedMoves = append(root.untriedMoves, i)
-		}
-	}
+	root.untriedMoves = append(root.untriedMoves, board.Moves()...)
 
 	var state GameState
 
 	for iters := 0; iters < iterations; iters++ {
-		fmt.Printf("iteration %d\n", iters)
-		for j := 0; j < 25; j++ {
-			state.board[j] = board[j]
+		// Always do one iteration, so root has a child to choose
+		if iters > 0 && engine.Cancelled(ctx) {
+			stopped = true
+			break
 		}
-		state.player = MINIMIZER
+		fmt.Printf("iteration %d\n", iters)
+		state.board = board
+		state.player = game.MINIMIZER
 
 		node := root
 
@@ -109,8 +139,8 @@ func bestMove(board [25]int, iterations int) (move int, value int, leafCount int
 			node = node.selectBestChild()
 			fmt.Printf("with move %d, player %d,  %.0f/%.0f/%.3f ", node.move, node.player, node.wins, node.visits, node.score)
 			state.player = 0 - state.player
-			state.board[node.move] = state.player
-			fmt.Printf("board now:\n%s\n", boardString(state.board))
+			state.doMove(node.move)
+			fmt.Printf("board now:\n%v\n", &state.board)
 		}
 		fmt.Print("End Selection\n\n")
 
@@ -126,12 +156,12 @@ func bestMove(board [25]int, iterations int) (move int, value int, leafCount int
 			mv := node.untriedMoves[rand.Intn(len(node.untriedMoves))]
 
 			state.player = 0 - state.player
-			state.board[mv] = state.player
-			fmt.Printf("expansion move %d, board:\n%s\n", mv, boardString(state.board))
+			state.doMove(mv)
+			fmt.Printf("expansion move %d, board:\n%v\n", mv, &state.board)
 
 			node = node.AddChild(mv, &state) // AddChild take mv out of untriedMoves slice
-			node.winner = findWinner(&(state.board))
-			if node.winner == MAXIMIZER {
+			node.winner = rules.BoardWinner(&state.board)
+			if node.winner == game.MAXIMIZER {
 				node.score = 1.0
 				win = true
 			}
@@ -141,18 +171,18 @@ func bestMove(board [25]int, iterations int) (move int, value int, leafCount int
 
 		fmt.Printf("Simulation\n")
 		// Simulation
-		if node.winner == UNSET {
+		if node.winner == game.UNSET {
 			moves := (&state).RemainingMoves()
 
 			for len(moves) > 0 {
 				// fmt.Printf("\tRemaining moves: %v\n", moves)
 				m := moves[rand.Intn(len(moves))]
 				state.player = 0 - state.player
-				state.board[m] = state.player
+				state.doMove(m)
 				// fmt.Printf("\tmove %d, player %d\n", m, state.player)
-				winner := findWinner(&(state.board))
-				if winner != UNSET {
-					if winner == MAXIMIZER {
+				winner := rules.BoardWinner(&state.board)
+				if winner != game.UNSET {
+					if winner == game.MAXIMIZER {
 						win = true
 					}
 					break
@@ -161,7 +191,7 @@ func bestMove(board [25]int, iterations int) (move int, value int, leafCount int
 			}
 		}
 
-		fmt.Printf("board after playout:\n%s\n", boardString(state.board))
+		fmt.Printf("board after playout:\n%v\n", &state.board)
 		fmt.Print("End Simulation\n\n")
 
 		leafCount++
@@ -175,7 +205,7 @@ func bestMove(board [25]int, iterations int) (move int, value int, leafCount int
 		// Back propagation
 		for node != nil {
 			node.visits += 1.0
-			// if node.winner == UNSET {
+			// if node.winner == game.UNSET {
 			node.wins += winIncr
 			node.score = node.wins / node.visits
 			// }
@@ -197,6 +227,7 @@ func bestMove(board [25]int, iterations int) (move int, value int, leafCount int
 	moveNode := root.selectBestChild()
 	fmt.Printf("\nbest move node move %d, player %d, %.0f/%.0f/%.3f\n", moveNode.move, moveNode.player, moveNode.wins, moveNode.visits, moveNode.score)
 	move = moveNode.move
+	pv = moveNode.principalVariation()
 
 	return
 }
@@ -234,6 +265,24 @@ func (node *Node) AddChild(mv int, state *GameState) *Node {
 	return ch
 }
 
+// principalVariation follows the most-visited child nodes from
+// node on down, the line of moves the search expects.
+func (node *Node) principalVariation() []engine.Move {
+	var pv []engine.Move
+	for node != nil {
+		x, y := game.Coords(node.move)
+		pv = append(pv, engine.Move{X: x, Y: y})
+		var next *Node
+		for _, c := range node.childNodes {
+			if next == nil || c.visits > next.visits {
+				next = c
+			}
+		}
+		node = next
+	}
+	return pv
+}
+
 func (node *Node) selectBestChild() *Node {
 	best := node.childNodes[0]
 	bestScore := node.childNodes[0].score
@@ -252,119 +301,46 @@ func (node *Node) selectBestChild() *Node {
 // unmade on state.board
 func (state *GameState) RemainingMoves() []int {
 	mvs := make([]int, 0, 25)
-	j := 0
-	for i := 0; i < 25; i++ {
-		if state.board[i] == UNSET {
-			mvs = append(mvs, i)
-			j++
+	for i, row := range state.board {
+		for j, mark := range row {
+			if mark == game.UNSET {
+				mvs = append(mvs, game.Cell(i, j))
+			}
 		}
 	}
 	return mvs
 }
 
-func (p *MCTS3) PrintBoard() {
-	fmt.Printf("%s\n", p)
+// doMove puts state.player's mark on cell number m
+func (state *GameState) doMove(m int) {
+	x, y := game.Coords(m)
+	state.board.MakeMove(x, y, state.player)
 }
 
-func (p *MCTS3) SetScores(_ bool) {
+// SetRules changes what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does.
+func (p *MCTS3) SetRules(rules game.Rules) {
+	p.rules = rules
 }
 
-// FindWinner will return MAXIMIZER or MINIMIZER if somebody won,
-// UNSET if nobody wins based on current board.
-func (p *MCTS3) FindWinner() int {
-	return findWinner(&(p.board))
+// SetTablebase gives the player a tablebase, nil for none
+func (p *MCTS3) SetTablebase(tb *tablebase.Table) {
+	p.tablebase = tb
 }
 
-// findWinner will return MAXIMIZER or MINIMIZER if somebody won,
-// UNSET if nobody wins based on argument board.
-func findWinner(board *[25]int) int {
-	for _, i := range importantCells {
-		if (*board)[i] != UNSET {
-			for _, quad := range winningQuads[i] {
-				sum := (*board)[quad[0]] + (*board)[quad[1]] + (*board)[quad[2]] + (*board)[quad[3]]
-				switch sum {
-				case 4:
-					return MAXIMIZER
-				case -4:
-					return MINIMIZER
-				}
-			}
-		}
-	}
-	for _, i := range importantCells {
-		if (*board)[i] != UNSET {
-			for _, triplet := range losingTriplets[i] {
-				sum := (*board)[triplet[0]] + (*board)[triplet[1]] + (*board)[triplet[2]]
-				switch sum {
-				case 3:
-					return MINIMIZER
-				case -3:
-					return MAXIMIZER
-				}
-			}
-		}
-	}
-	return UNSET
-}
-
-func (p *MCTS3) String() string {
-	return boardString(p.board)
+func (p *MCTS3) PrintBoard() {
+	fmt.Printf("%s\n", p)
 }
 
-func boardString(board [25]int) string {
-	s := "   0 1 2 3 4\n"
-	for i := 0; i < 25; i++ {
-		if (i % 5) == 0 {
-			s += string((i/5)+'0') + "  "
-		}
-		s += string("O_X"[board[i]+1]) + " "
-		if (i % 5) == 4 {
-			s += "\n"
-		}
-	}
-	return s
+func (p *MCTS3) SetScores(_ bool) {
 }
 
-var importantCells = [9]int{2, 7, 10, 11, 12, 13, 14, 17, 22}
-
-// 25 rows only to make looping easier. The filled-in
-// rows are the only quads you actually have to check
-// to find out if there's a win
-var winningQuads = [25][][]int{
-	{}, {},
-	{{0, 1, 2, 3}, {1, 2, 3, 4}, {2, 7, 12, 17}},
-	{}, {}, {}, {},
-	{{5, 6, 7, 8}, {6, 7, 8, 9}, {7, 12, 17, 22}},
-	{}, {},
-	{{0, 5, 10, 15}, {5, 10, 15, 20}},
-	{{1, 6, 11, 16}, {6, 11, 16, 21}, {3, 7, 11, 15}, {5, 11, 17, 23}},
-	{{10, 11, 12, 13}, {11, 12, 13, 14}, {4, 8, 12, 16}, {8, 12, 16, 20}, {0, 6, 12, 18}, {6, 12, 18, 24}},
-	{{3, 8, 13, 18}, {8, 13, 18, 23}, {1, 7, 13, 19}, {9, 13, 17, 21}},
-	{{4, 9, 14, 19}, {9, 14, 19, 24}},
-	{}, {},
-	{{15, 16, 17, 18}, {16, 17, 18, 19}},
-	{}, {}, {}, {},
-	{{20, 21, 22, 23}, {21, 22, 23, 24}},
-	{}, {},
+// FindWinner will return game.MAXIMIZER or game.MINIMIZER if somebody won,
+// game.UNSET if nobody wins based on current board.
+func (p *MCTS3) FindWinner() int {
+	return p.rules.BoardWinner(&p.board)
 }
 
-// 25 rows only to make looping easier. The filled-in
-// rows are the only triplets you actually have to check
-// to find out if there's a loss.
-var losingTriplets = [][][]int{
-	{}, {},
-	{{0, 1, 2}, {1, 2, 3}, {2, 3, 4}, {2, 7, 12}, {2, 6, 10}, {14, 8, 2}},
-	{}, {}, {}, {},
-	{{5, 6, 7}, {6, 7, 8}, {7, 8, 9}, {2, 7, 12}, {7, 12, 17}, {3, 7, 11}, {7, 11, 15}, {1, 7, 13}, {7, 13, 19}},
-	{}, {},
-	{{10, 11, 12}, {0, 5, 10}, {5, 10, 15}, {10, 15, 20}, {2, 6, 10}, {10, 16, 22}},
-	{{10, 11, 12}, {11, 12, 13}, {1, 6, 11}, {6, 11, 16}, {11, 16, 21}, {3, 7, 11}, {7, 11, 15}, {5, 11, 17}, {11, 17, 23}},
-	{{10, 11, 12}, {11, 12, 13}, {12, 13, 14}, {2, 7, 12}, {7, 12, 17}, {12, 17, 22}, {0, 6, 12}, {6, 12, 18}, {12, 18, 24}, {4, 8, 12}, {8, 12, 16}, {12, 16, 20}},
-	{{11, 12, 13}, {12, 13, 14}, {3, 8, 13}, {8, 13, 18}, {13, 18, 23}, {1, 7, 13}, {7, 13, 19}, {21, 17, 13}, {17, 13, 9}},
-	{{12, 13, 14}, {4, 9, 14}, {9, 14, 19}, {14, 19, 24}, {22, 18, 14}, {14, 8, 2}},
-	{}, {},
-	{{15, 16, 17}, {16, 17, 18}, {17, 18, 19}, {7, 12, 17}, {12, 17, 22}, {5, 11, 17}, {11, 17, 23}, {21, 17, 13}, {17, 13, 9}},
-	{}, {}, {}, {},
-	{{20, 21, 22}, {21, 22, 23}, {22, 23, 24}, {12, 17, 22}, {10, 16, 22}, {22, 18, 14}},
-	{}, {},
+func (p *MCTS3) String() string {
+	return p.board.String()
 }
@@ -8,7 +8,7 @@ import (
 // best possible move.
 
 type MoveKeeper struct {
-	moves [25][2]int
+	moves [64][2]int
 	next  int        // index into moves[]
 	max   int
 	deterministic bool
@@ -1,69 +1,92 @@
 package negascout
 
 import (
+	"context"
 	"fmt"
+	"math/bits"
 	"math/rand"
+	"time"
 
+	"squava/src/engine"
+	"squava/src/game"
 	"squava/src/movekeeper"
-)
-
-type board [5][5]int
-
-const (
-	WIN       = 10000
-	LOSS      = -10000
-	MAXIMIZER = 1
-	MINIMIZER = -1
-	UNSET     = 0
+	"squava/src/score"
+	"squava/src/tablebase"
+	"squava/src/threats"
+	"squava/src/ttable"
 )
 
 type NegaScout struct {
-	bd            *board
+	bd            game.Bitboard
+	scores        []int    // bias for each cell
+	symmetries    []int    // transforms of the board that leave scores unchanged
+	valueQuads    []uint64 // quads that staticValue looks at
+	initialOrder  []int    // cells in the order reorderMoves considers them
 	leafNodeCount int
 	maxDepth      int
 	deterministic bool
+	rules         game.Rules
+	table         *ttable.Table
+	tablebase     *tablebase.Table
+	timeLimit     time.Duration
+	ctx           context.Context // nil when nothing can cancel the search
+	stopped       bool            // ctx was cancelled mid-search
+	nodeCount     int
+	pv            engine.PVTable
+	threatPlies   int // how far to look for forced wins by threats
+	quiescence    int // most forced moves past the horizon
+	extensions    int // forced moves past the horizon, last search
 }
 
-// Arrays of losing triplets and winning quads, indexed
-// by <x,y> coords of all pairs composing each of the quads
-// or triplets. Makes deltaValue() a lot more efficient
-var indexedLosingTriplets [5][5][][][]int
-var indexedWinningQuads [5][5][][][]int
-var indexedCalcs bool = false
-
 func (p *NegaScout) Name() string {
 	return "NegaScout"
 }
 
-func calculateIndexedMatrices() {
-	// Set up for use by deltaValue()
-	for _, triplet := range losingTriplets {
-		for _, pair := range triplet {
-			indexedLosingTriplets[pair[0]][pair[1]] = append(indexedLosingTriplets[pair[0]][pair[1]], triplet)
-		}
-	}
-	for _, quad := range winningQuads {
-		for _, pair := range quad {
-			indexedWinningQuads[pair[0]][pair[1]] = append(
-				indexedWinningQuads[pair[0]][pair[1]], quad)
-		}
-	}
-}
-
 func New(deterministic bool, maxdepth int) *NegaScout {
-	if !indexedCalcs {
-		calculateIndexedMatrices()
-		indexedCalcs = true
-	}
 	var r NegaScout
-	r.bd = new(board)
 	r.maxDepth = maxdepth
 	r.deterministic = deterministic
+	r.SetShape(game.Standard)
+	r.table = ttable.New(ttable.DefaultMB, ttable.DEPTH)
+	r.threatPlies = threats.DefaultPlies
+	r.quiescence = threats.DefaultQuiescence
 	return &r
 }
 
+// SetShape starts over with an empty board of shape,
+// and no bias on any cell until SetScores.
+func (p *NegaScout) SetShape(shape *game.Shape) {
+	p.bd = shape.Bitboard()
+	p.scores = make([]int, shape.Cells())
+	p.symmetries = shape.Symmetries(p.scores)
+	p.initialOrder = p.initialOrder[:0]
+	if shape == game.Standard {
+		p.valueQuads = checkableQuadMasks
+		for _, pair := range initialOrderedMoves {
+			p.initialOrder = append(p.initialOrder, game.Cell(pair[0], pair[1]))
+		}
+	} else {
+		p.valueQuads = shape.Quads()
+		for cell := 0; cell < shape.Cells(); cell++ {
+			p.initialOrder = append(p.initialOrder, cell)
+		}
+	}
+	if p.table != nil {
+		p.table.Clear()
+	}
+}
+
 func (p *NegaScout) MakeMove(x, y int, player int) {
-	p.bd[x][y] = player
+	p.bd.MakeMove(p.bd.Shape().Cell(x, y), player)
+}
+
+// SwapSides trades the player's marks for its opponent's,
+// for the swap rule
+func (p *NegaScout) SwapSides() {
+	p.bd = p.bd.Swapped()
+	if p.table != nil {
+		p.table.Clear()
+	}
 }
 
 func (p *NegaScout) SetDepth(moveCounter int) {
@@ -78,34 +101,225 @@ func (p *NegaScout) SetDepth(moveCounter int) {
 	}
 }
 
-func (p *NegaScout) ChooseMove() (int, int, int, int) {
+// SetTimeLimit makes ChooseMove deepen its search one move at a
+// time until limit runs out, instead of searching to the depth
+// SetDepth sets. A zero limit goes back to a fixed depth.
+func (p *NegaScout) SetTimeLimit(limit time.Duration) {
+	p.timeLimit = limit
+}
+
+// ChooseMove - choose computer's next move, and make it on the internal
+// board. A search that ctx can cancel deepens one move at a time, so
+// that it has a move from the deepest completed search to return.
+func (p *NegaScout) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
 
-	moves := movekeeper.New(3*LOSS, p.deterministic)
+	start := time.Now()
 	p.leafNodeCount = 0
+	p.extensions = 0
+	if p.table != nil {
+		p.table.NewSearch()
+	}
 
 	p.reorderMoves()
 
-	beta := 2 * WIN
-	alpha := 2 * LOSS
-	score := 3 * LOSS
+	// p.maxDepth counts moves after MAXIMIZER's move at the root
+	maxDepth := p.maxDepth
+	if p.timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeLimit)
+		defer cancel()
+		maxDepth = bits.OnesCount64(p.bd.Empty()) - 1
+	}
+
+	move := engine.NoMove
+	var info engine.SearchInfo
+
+	if cell, v, ok := p.tablebaseMove(); ok {
+		// Perfect play, no need to search
+		info.Value, info.Depth = v, bits.OnesCount64(p.bd.Empty())
+		if cell >= 0 {
+			a, b := p.bd.Shape().Coords(cell)
+			move = engine.Move{X: a, Y: b}
+			info.PV = engine.Moves(p.bd.Shape(), p.tablebase.Line(&p.bd, game.MAXIMIZER))
+		}
+	} else if line := p.threatWin(ctx); line != nil {
+		// A forced win, no need to search
+		info.PV = engine.Moves(p.bd.Shape(), line)
+		move, info.Value, info.Depth = info.PV[0], score.Win(threats.Plies(line)), len(line)
+	} else if ctx.Done() == nil {
+		// Nothing can stop this search, no need to deepen
+		a, b, v, pv := p.searchRoot(-1)
+		move, info.Value, info.Depth, info.PV = engine.Move{X: a, Y: b}, score.Score(v), maxDepth+1, pv
+	} else {
+		// Iterative deepening: search 1 move deep, then 2 moves
+		// deep, and so on. Keep the move from the deepest search
+		// that finished. negaScout() doesn't check ctx right after
+		// the root move, so a 1-move-deep search always finishes.
+		p.ctx = ctx
+		p.nodeCount = 0
+		best := -1
+		for depth := 1; depth <= maxDepth+1; depth++ {
+			p.maxDepth = depth - 1
+			a, b, v, pv := p.searchRoot(best)
+			if p.stopped {
+				break
+			}
+			move, info.Value, info.Depth, info.PV = engine.Move{X: a, Y: b}, score.Score(v), depth, pv
+			if a < 0 {
+				break // full board
+			}
+			best = p.bd.Shape().Cell(a, b)
+			if score.Score(v).Decided() {
+				break // found a forced win or loss, deeper won't change it
+			}
+			if !engine.Deepen(ctx, start) {
+				break // next, deeper, search won't finish in time
+			}
+		}
+		info.Stopped = p.stopped
+		p.maxDepth = maxDepth
+		p.ctx = nil
+		p.stopped = false
+	}
+
+	info.LeafCount = p.leafNodeCount
+	info.Elapsed = time.Since(start)
+
+	if info.Stopped && info.Depth == 0 {
+		return move, info, engine.ErrNoMove
+	}
+	if move != engine.NoMove {
+		p.MakeMove(move.X, move.Y, game.MAXIMIZER)
+	}
+
+	return move, info, nil
+}
+
+// tablebaseMove returns MAXIMIZER's best move, a cell number,
+// and its value, and true, if the player has a tablebase with
+// the position on its board in it.
+func (p *NegaScout) tablebaseMove() (int, score.Score, bool) {
+	if p.tablebase == nil {
+		return -1, 0, false
+	}
+	return p.tablebase.BestMove(&p.bd, game.MAXIMIZER)
+}
+
+// threatWin returns a forced win by threats for MAXIMIZER,
+// if the player looks for them, or nil
+func (p *NegaScout) threatWin(ctx context.Context) []int {
+	if p.threatPlies == 0 {
+		return nil
+	}
+	line, nodes := threats.Search(ctx, &p.bd, p.rules, game.MAXIMIZER, p.threatPlies)
+	p.leafNodeCount += nodes
+	return line
+}
+
+// quiesce returns the value of the position at the horizon, where
+// player makes the move at ply, and the static value is value. It
+// makes forced moves, extended of them made already, until the
+// position is quiet, or the game is decided.
+func (p *NegaScout) quiesce(ply int, player int, value int, extended int) int {
+	if extended >= p.quiescence || score.Score(value).Decided() {
+		return value
+	}
+
+	cell, winner := threats.Reply(&p.bd, p.rules, player)
+	switch {
+	case winner == player:
+		return player * int(score.Win(ply))
+	case winner == -player:
+		return -player * int(score.Win(ply+1))
+	case cell < 0:
+		return value // quiet
+	}
+
+	p.extensions++
+	p.bd.MakeMove(cell, player)
+	_, value = p.staticValue(ply)
+	if score.Score(value).Decided() {
+		// Filling the hole lost. Not filling it
+		// loses too, a move later.
+		value = -player * int(score.Win(ply+1))
+	} else {
+		value = p.quiesce(ply+1, -player, value, extended+1)
+	}
+	p.bd.UnmakeMove(cell, player)
+
+	return value
+}
+
+// SetQuiescence sets how many forced moves past the
+// horizon the search makes, at most. 0 makes none.
+func (p *NegaScout) SetQuiescence(plies int) {
+	p.quiescence = plies
+}
+
+// Extensions returns how many forced moves past
+// the horizon the player's last search made
+func (p *NegaScout) Extensions() int {
+	return p.extensions
+}
+
+// SetThreatDepth sets how many plies ahead ChooseMove looks
+// for a forced win by threats, before it searches. 0 doesn't look.
+func (p *NegaScout) SetThreatDepth(plies int) {
+	p.threatPlies = plies
+}
+
+// searchRoot values MAXIMIZER's moves, trying cell number
+// first before the others, if it's not -1, and returns the
+// coords and value of the best move, and its principal variation.
+func (p *NegaScout) searchRoot(first int) (int, int, int, []engine.Move) {
+
+	moves := movekeeper.New(3*game.LOSS, p.deterministic)
+
+	beta := 2 * game.WIN
+	alpha := 2 * game.LOSS
+	score := 3 * game.LOSS
 	n := beta
 
+	// Moves that are rotations or reflections of other moves
+	// on a symmetric board have the same value, skip them.
+	unique := p.bd.UniqueMovesUnder(p.symmetries)
+
+	var ordered [game.MaxCells]int
+	count := 0
+	if first >= 0 {
+		ordered[count] = first
+		count++
+	}
 	for _, cell := range orderedMoves[2] {
-		i, j := cell[0], cell[1]
-		if p.bd[i][j] == UNSET {
-			p.bd[i][j] = MAXIMIZER
-			cur := -p.negaScout(1, MINIMIZER, -n, -alpha)
+		if cell != first {
+			ordered[count] = cell
+			count++
+		}
+	}
+
+	var lines [game.MaxCells][]engine.Move
+
+	for _, cell := range ordered[:count] {
+		if unique&game.Bit(cell) != 0 {
+			p.bd.MakeMove(cell, game.MAXIMIZER)
+			cur := -p.negaScout(1, game.MINIMIZER, -n, -alpha)
 			if cur > score {
 				if n == beta {
 					score = cur
 				} else {
-					score = -p.negaScout(1, MINIMIZER, -beta, -cur)
+					score = -p.negaScout(1, game.MINIMIZER, -beta, -cur)
 				}
 			}
-			p.bd[i][j] = UNSET
+			p.bd.UnmakeMove(cell, game.MAXIMIZER)
+			if p.stopped {
+				break
+			}
+			p.pv.Update(0, cell)
+			lines[cell] = p.pv.Line(p.bd.Shape(), 0)
 			if score > alpha {
 				alpha = score
 			}
+			i, j := p.bd.Shape().Coords(cell)
 			moves.SetMove(i, j, alpha)
 			if alpha >= beta {
 				break
@@ -115,35 +329,39 @@ func (p *NegaScout) ChooseMove() (int, int, int, int) {
 	}
 
 	a, b, v := moves.ChooseMove()
-
-	p.MakeMove(a, b, MAXIMIZER)
-
-	return a, b, v, p.leafNodeCount
+	var pv []engine.Move
+	if a >= 0 {
+		pv = lines[p.bd.Shape().Cell(a, b)]
+	}
+	return a, b, v, pv
 }
 
-func (p *NegaScout) FindWinner() int {
-	for _, quad := range winningQuads {
-		sum := p.bd[quad[0][0]][quad[0][1]]
-		sum += p.bd[quad[1][0]][quad[1][1]]
-		sum += p.bd[quad[2][0]][quad[2][1]]
-		sum += p.bd[quad[3][0]][quad[3][1]]
-
-		if sum == 4 || sum == -4 {
-			return p.bd[quad[0][0]][quad[0][1]]
+// cancelled returns true once the context of a search that can
+// be cancelled is done. It only looks every so often.
+func (p *NegaScout) cancelled() bool {
+	if p.ctx != nil && !p.stopped {
+		p.nodeCount++
+		if p.nodeCount&1023 == 0 && engine.Cancelled(p.ctx) {
+			p.stopped = true
 		}
 	}
+	return p.stopped
+}
 
-	for _, triplet := range losingTriplets {
-		sum := p.bd[triplet[0][0]][triplet[0][1]]
-		sum += p.bd[triplet[1][0]][triplet[1][1]]
-		sum += p.bd[triplet[2][0]][triplet[2][1]]
+// SetTablebase gives the player a tablebase to play perfectly
+// from once the board is full enough, or nil for none.
+func (p *NegaScout) SetTablebase(tb *tablebase.Table) {
+	p.tablebase = tb
+}
 
-		if sum == 3 || sum == -3 {
-			return -p.bd[triplet[0][0]][triplet[0][1]]
-		}
-	}
+func (p *NegaScout) FindWinner() int {
+	return p.rules.Winner(&p.bd)
+}
 
-	return 0
+// SetRules changes what a move that fills in both
+// a 4-in-a-row and a 3-in-a-row does.
+func (p *NegaScout) SetRules(rules game.Rules) {
+	p.rules = rules
 }
 
 var deadlyQuads [4][4][2]int = [4][4][2]int{
@@ -153,58 +371,55 @@ var deadlyQuads [4][4][2]int = [4][4][2]int{
 	{{3, 0}, {2, 1}, {1, 2}, {0, 3}},
 }
 
-// It turns out that you only have to look at
-// the 4-in-a-rows that contain these 9 cells
-// to check every 4-in-a-row. Similarly, you
-// only need to check these 9 cells to check
-// all the losing 3-in-a-row combos. You don't
-// have to look at each and every cell.
-var checkableCells [9][2]int = [9][2]int{
-	{0, 2}, {1, 2}, {2, 0},
-	{2, 1}, {2, 2}, {2, 3},
-	{2, 4}, {3, 2}, {4, 2},
+// Bitboard masks of the inner and outer pairs of deadlyQuads
+var deadlyQuadMasks [4]struct{ inner, outer uint64 }
+
+// Bitboard masks of the quads through each of game.CheckableCells.
+// Quads through more than one checkable cell appear more than once.
+var checkableQuadMasks []uint64
+
+func init() {
+	for n, quad := range deadlyQuads {
+		for k, pair := range quad {
+			bit := game.Bit(game.Cell(pair[0], pair[1]))
+			if k == 1 || k == 2 {
+				deadlyQuadMasks[n].inner |= bit
+			} else {
+				deadlyQuadMasks[n].outer |= bit
+			}
+		}
+	}
+	for _, cell := range game.CheckableCells {
+		for _, quad := range game.CellQuadMasks[game.Cell(cell[0], cell[1])] {
+			checkableQuadMasks = append(checkableQuadMasks, quad)
+		}
+	}
 }
 
 func (p *NegaScout) staticValue(ply int) (stopRecursing bool, value int) {
 
 	p.leafNodeCount++
 
-	for _, cell := range checkableCells {
-		relevantQuads := indexedWinningQuads[cell[0]][cell[1]]
-		for _, quad := range relevantQuads {
-			sum := p.bd[quad[0][0]][quad[0][1]]
-			sum += p.bd[quad[1][0]][quad[1][1]]
-			sum += p.bd[quad[2][0]][quad[2][1]]
-			sum += p.bd[quad[3][0]][quad[3][1]]
-
-			if sum == 4 || sum == -4 {
-				return true, p.bd[quad[0][0]][quad[0][1]] * (WIN - ply)
-			}
-			if sum == 3 || sum == -3 {
-				value += sum * 10
-			}
-		}
+	if winner := p.rules.Winner(&p.bd); winner != game.UNSET {
+		return true, winner * int(score.Win(ply))
 	}
 
-	for _, cell := range checkableCells {
-		relevantTriplets := indexedLosingTriplets[cell[0]][cell[1]]
-		for _, triplet := range relevantTriplets {
-			sum := p.bd[triplet[0][0]][triplet[0][1]]
-			sum += p.bd[triplet[1][0]][triplet[1][1]]
-			sum += p.bd[triplet[2][0]][triplet[2][1]]
-
-			if sum == 3 || sum == -3 {
-				return true, -sum / 3 * (WIN - ply)
-			}
+	near := p.bd.Shape().WinLength() - 1
+	for _, quad := range p.valueQuads {
+		if sum := p.bd.Sum(quad); sum == near || sum == -near {
+			value += sum / near * 30
 		}
 	}
 
-	for _, quad := range deadlyQuads {
-		outer := p.bd[quad[0][0]][quad[0][1]] + p.bd[quad[3][0]][quad[3][1]]
-		inner := p.bd[quad[1][0]][quad[1][1]] + p.bd[quad[2][0]][quad[2][1]]
+	// The deadly quads are those of a 5x5 board
+	if p.bd.Shape() == game.Standard {
+		for _, quad := range deadlyQuadMasks {
+			outer := p.bd.Sum(quad.outer)
+			inner := p.bd.Sum(quad.inner)
 
-		if (inner == 2 || inner == -2) && outer == 0 {
-			value -= inner / 2 * 5
+			if (inner == 2 || inner == -2) && outer == 0 {
+				value -= inner / 2 * 5
+			}
 		}
 	}
 
@@ -212,9 +427,10 @@ func (p *NegaScout) staticValue(ply int) (stopRecursing bool, value int) {
 		// Give it a slight bias for those early
 		// moves when all losing-triplets and winning-quads
 		// are beyond the horizon.
-		for x := 0; x < 5; x++ {
-			for y := 0; y < 5; y++ {
-				value += p.bd[x][y] * scores[x][y]
+		for _, player := range [2]int{game.MAXIMIZER, game.MINIMIZER} {
+			for marks := p.bd.Marks(player); marks != 0; marks &= marks - 1 {
+				cell := bits.TrailingZeros64(marks)
+				value += player * p.scores[cell]
 			}
 		}
 	}
@@ -231,18 +447,77 @@ func (p *NegaScout) staticValue(ply int) (stopRecursing bool, value int) {
 
 func (p *NegaScout) negaScout(ply int, player int, alpha int, beta int) (value int) {
 
+	if ply > 1 && p.cancelled() {
+		return 0 // caller throws this away
+	}
+
+	p.pv.Clear(ply)
+
+	if p.tablebase != nil {
+		if v, ok := p.tablebase.Probe(&p.bd, player); ok {
+			p.leafNodeCount++
+			// v counts plies from the next one, values count from the root
+			return int(v.Later(ply))
+		}
+	}
+
+	depth := p.maxDepth - ply + 1
+	key, transform := ttable.KeyUnder(&p.bd, player, p.symmetries)
+	ttMove := -1
+	if p.table != nil && depth > 0 {
+		if e, ok := p.table.Probe(key); ok {
+			if e.Move >= 0 {
+				shape := p.bd.Shape()
+				ttMove = shape.TransformedCell(shape.InverseTransform(transform), int(e.Move))
+			}
+			if int(e.Depth) >= depth {
+				v := ttable.FromTable(int(e.Value), ply)
+				switch int(e.Bound) {
+				case ttable.EXACT:
+					return v
+				case ttable.LOWER:
+					if v > alpha {
+						alpha = v
+					}
+				case ttable.UPPER:
+					if v < beta {
+						beta = v
+					}
+				}
+				if alpha >= beta {
+					return v
+				}
+			}
+		}
+	}
+
 	stopRecursing, boardValue := p.staticValue(ply)
 	if stopRecursing {
-		return player * boardValue
+		return player * p.quiesce(ply+1, player, boardValue, 0)
 	}
 
-	score := 3 * LOSS // Even 2*LOSS greater than this
+	alphaOrig := alpha
+	score := 3 * game.LOSS // Even 2*LOSS greater than this
 	n := beta
-
+	bestMove := -1
+
+	// Best move from an earlier search of this position goes first
+	var moves [game.MaxCells]int
+	count := 0
+	if ttMove >= 0 {
+		moves[count] = ttMove
+		count++
+	}
 	for _, cell := range orderedMoves[player+1] {
-		i, j := cell[0], cell[1]
-		if p.bd[i][j] == UNSET {
-			p.bd[i][j] = player
+		if cell != ttMove {
+			moves[count] = cell
+			count++
+		}
+	}
+
+	for _, cell := range moves[:count] {
+		if p.bd.Empty()&game.Bit(cell) != 0 {
+			p.bd.MakeMove(cell, player)
 			cur := -p.negaScout(ply+1, -player, -n, -alpha)
 			if cur > score {
 				if n == beta || ply == p.maxDepth-2 {
@@ -250,8 +525,13 @@ func (p *NegaScout) negaScout(ply int, player int, alpha int, beta int) (value i
 				} else {
 					score = -p.negaScout(ply+1, -player, -beta, -cur)
 				}
+				bestMove = cell
+				p.pv.Update(ply, cell)
+			}
+			p.bd.UnmakeMove(cell, player)
+			if p.stopped {
+				return 0
 			}
-			p.bd[i][j] = UNSET
 			if score > alpha {
 				alpha = score
 			}
@@ -262,133 +542,73 @@ func (p *NegaScout) negaScout(ply int, player int, alpha int, beta int) (value i
 		}
 	}
 
+	if p.table != nil && bestMove >= 0 && !p.stopped {
+		bound := ttable.EXACT
+		if score <= alphaOrig {
+			bound = ttable.UPPER
+		} else if score >= beta {
+			bound = ttable.LOWER
+		}
+		if bestMove >= 0 {
+			bestMove = p.bd.Shape().TransformedCell(transform, bestMove)
+		}
+		p.table.Store(key, depth, ttable.ToTable(score, ply), bound, bestMove)
+	}
+
 	return score
 }
 
-func (p *NegaScout) PrintBoard() {
-	fmt.Printf("   0 1 2 3 4\n")
-	for i, row := range p.bd {
-		fmt.Printf("%d  ", i)
-		for _, v := range row {
-			var marker string
-			switch v {
-			case MAXIMIZER:
-				marker = "X"
-			case MINIMIZER:
-				marker = "O"
-			case UNSET:
-				marker = "_"
-			}
-			fmt.Printf("%s ", marker)
-		}
-		fmt.Printf("\n")
-	}
+// SetTable replaces the player's transposition table.
+// A nil table turns off the transposition table.
+func (p *NegaScout) SetTable(table *ttable.Table) {
+	p.table = table
 }
 
-var losingTriplets [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}},
-	[][]int{[]int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}},
-	[][]int{[]int{2, 0}, []int{1, 1}, []int{0, 2}},
-	[][]int{[]int{2, 0}, []int{3, 1}, []int{4, 2}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}},
-	[][]int{[]int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 2}, []int{1, 3}, []int{2, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 2}, []int{2, 3}, []int{1, 4}},
-	[][]int{[]int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 2}, []int{3, 3}, []int{2, 4}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{2, 4}, []int{3, 4}, []int{4, 4}},
+// Table returns the player's transposition table, so
+// that its hit rate can get reported.
+func (p *NegaScout) Table() *ttable.Table {
+	return p.table
 }
-var winningQuads [][][]int = [][][]int{
-	[][]int{[]int{0, 0}, []int{1, 0}, []int{2, 0}, []int{3, 0}},
-	[][]int{[]int{0, 0}, []int{0, 1}, []int{0, 2}, []int{0, 3}},
-	[][]int{[]int{0, 0}, []int{1, 1}, []int{2, 2}, []int{3, 3}},
-	[][]int{[]int{0, 1}, []int{1, 1}, []int{2, 1}, []int{3, 1}},
-	[][]int{[]int{0, 1}, []int{0, 2}, []int{0, 3}, []int{0, 4}},
-	[][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}, []int{3, 4}},
-	[][]int{[]int{0, 2}, []int{1, 2}, []int{2, 2}, []int{3, 2}},
-	[][]int{[]int{0, 3}, []int{1, 3}, []int{2, 3}, []int{3, 3}},
-	[][]int{[]int{0, 4}, []int{1, 4}, []int{2, 4}, []int{3, 4}},
-	[][]int{[]int{1, 0}, []int{2, 0}, []int{3, 0}, []int{4, 0}},
-	[][]int{[]int{1, 0}, []int{1, 1}, []int{1, 2}, []int{1, 3}},
-	[][]int{[]int{1, 0}, []int{2, 1}, []int{3, 2}, []int{4, 3}},
-	[][]int{[]int{1, 1}, []int{2, 1}, []int{3, 1}, []int{4, 1}},
-	[][]int{[]int{1, 1}, []int{1, 2}, []int{1, 3}, []int{1, 4}},
-	[][]int{[]int{1, 1}, []int{2, 2}, []int{3, 3}, []int{4, 4}},
-	[][]int{[]int{1, 2}, []int{2, 2}, []int{3, 2}, []int{4, 2}},
-	[][]int{[]int{1, 3}, []int{2, 3}, []int{3, 3}, []int{4, 3}},
-	[][]int{[]int{1, 4}, []int{2, 4}, []int{3, 4}, []int{4, 4}},
-	[][]int{[]int{2, 0}, []int{2, 1}, []int{2, 2}, []int{2, 3}},
-	[][]int{[]int{2, 1}, []int{2, 2}, []int{2, 3}, []int{2, 4}},
-	[][]int{[]int{3, 0}, []int{3, 1}, []int{3, 2}, []int{3, 3}},
-	[][]int{[]int{3, 0}, []int{2, 1}, []int{1, 2}, []int{0, 3}},
-	[][]int{[]int{3, 1}, []int{3, 2}, []int{3, 3}, []int{3, 4}},
-	[][]int{[]int{3, 1}, []int{2, 2}, []int{1, 3}, []int{0, 4}},
-	[][]int{[]int{4, 0}, []int{4, 1}, []int{4, 2}, []int{4, 3}},
-	[][]int{[]int{4, 0}, []int{3, 1}, []int{2, 2}, []int{1, 3}},
-	[][]int{[]int{4, 1}, []int{4, 2}, []int{4, 3}, []int{4, 4}},
-	[][]int{[]int{4, 1}, []int{3, 2}, []int{2, 3}, []int{1, 4}},
+
+func (p *NegaScout) PrintBoard() {
+	fmt.Printf("%v", &p.bd)
 }
 
-var scores [5][5]int
+var standardScores = [25]int{
+	3, 3, 0, 3, 3,
+	3, 4, 1, 4, 3,
+	0, 1, 0, 1, 0,
+	3, 4, 1, 4, 3,
+	3, 3, 0, 3, 3,
+}
 
+// SetScores sets a small bias on each cell. Boards other
+// than 5x5 get no bias unless it's random, and on a torus
+// no cell is a corner or on an edge. Random biases usually
+// leave the valuation with no symmetry, and then the table
+// keys and the root moves take none into account.
 func (p *NegaScout) SetScores(randomize bool) {
 	if randomize {
 		var vals [11]int = [11]int{-5, -4, -3 - 2, -1, 0, 1, 2, 3, 4, 5}
-		for i, row := range scores {
-			for j, _ := range row {
-				scores[i][j] = vals[rand.Intn(11)]
-			}
+		for cell := range p.scores {
+			p.scores[cell] = vals[rand.Intn(11)]
 		}
+	} else if p.bd.Shape() == game.Standard {
+		copy(p.scores, standardScores[:])
 	} else {
-		scores = [5][5]int{
-			[5]int{3, 3, 0, 3, 3},
-			[5]int{3, 4, 1, 4, 3},
-			[5]int{0, 1, 0, 1, 0},
-			[5]int{3, 4, 1, 4, 3},
-			[5]int{3, 3, 0, 3, 3},
+		for cell := range p.scores {
+			p.scores[cell] = 0
 		}
 	}
+	p.symmetries = p.bd.Shape().Symmetries(p.scores)
+	if p.table != nil {
+		p.table.Clear()
+	}
 }
 
 // Need a list of all possible moves, in an order that
 // works for the first 2 or 3 moves by instances of NegaScout.
+// Boards other than 5x5 start from raster order.
 var initialOrderedMoves [25][2]int = [25][2]int{
 	{1, 1}, {1, 3}, {3, 3}, {3, 1},
 	{0, 1}, {0, 3}, {1, 4}, {3, 4}, {4, 3}, {4, 1}, {3, 0}, {1, 0},
@@ -400,7 +620,7 @@ var initialOrderedMoves [25][2]int = [25][2]int{
 
 // Used in func negaScout(), orderedMoves[0] by MINIMIZER,
 // orderedMoves[2] by MAXIMIZER, orderedMoves[1] unused.
-var orderedMoves [3][][2]int
+var orderedMoves [3][]int
 
 // Reorder all legal moves into something like a "best order"
 // This considers moves as good, bad or dull. Good or bad depends
@@ -409,57 +629,52 @@ var orderedMoves [3][][2]int
 // MINIMIZER uses an array of bad-dull-good moves, MAXIMIZER uses an
 // array of good-dull-bad moves, both in ChooseMove() and negaScout().
 func (p *NegaScout) reorderMoves() {
-	var goodCells [][2]int
-	var badCells [][2]int
-	var dullCells [][2]int
+	var goodCells []int
+	var badCells []int
+	var dullCells []int
 	unsetCount := 0
-	for _, cell := range initialOrderedMoves {
-		if p.bd[cell[0]][cell[1]] == UNSET {
+	shape := p.bd.Shape()
+	win, lose := shape.WinLength(), shape.LoseLength()
+	for _, cell := range p.initialOrder {
+		if p.bd.Empty()&game.Bit(cell) != 0 {
 			unsetCount++
 			interesting := false
-			relevantQuads := indexedWinningQuads[cell[0]][cell[1]]
-			for _, quad := range relevantQuads {
-				sum := p.bd[quad[0][0]][quad[0][1]]
-				sum += p.bd[quad[1][0]][quad[1][1]]
-				sum += p.bd[quad[2][0]][quad[2][1]]
-				sum += p.bd[quad[3][0]][quad[3][1]]
-
-				if sum == 3 {
+			for _, quad := range shape.CellQuads(cell) {
+				sum := p.bd.Sum(quad)
+
+				if sum == win-1 {
 					// It's a hole in a potential 4-in-a-row
 					goodCells = append(goodCells, cell)
 					interesting = true
 					break
 				}
-				if sum == 2 {
+				if sum == win-2 {
 					goodCells = append(goodCells, cell)
 					interesting = true
 					break
 				}
-				if sum == -3 {
+				if sum == -(win - 1) {
 					// It's a hole in a potential 4-in-a-row
 					badCells = append(badCells, cell)
 					interesting = true
 					break
 				}
-				if sum == -2 {
+				if sum == -(win - 2) {
 					badCells = append(badCells, cell)
 					interesting = true
 					break
 				}
 			}
 			if !interesting {
-				relevantTriplets := indexedLosingTriplets[cell[0]][cell[1]]
-				for _, triplet := range relevantTriplets {
-					sum := p.bd[triplet[0][0]][triplet[0][1]]
-					sum += p.bd[triplet[1][0]][triplet[1][1]]
-					sum += p.bd[triplet[2][0]][triplet[2][1]]
+				for _, triplet := range shape.CellTriplets(cell) {
+					sum := p.bd.Sum(triplet)
 
-					if sum == -2 {
+					if sum == -(lose - 1) {
 						goodCells = append(goodCells, cell)
 						interesting = true
 						break
 					}
-					if sum == 2 {
+					if sum == lose-1 {
 						badCells = append(badCells, cell)
 						interesting = true
 						break
@@ -0,0 +1,84 @@
+package score
+
+/* score - minimax values. A forced win or loss has a value
+ * near game.WIN or game.LOSS, less the number of plies (moves
+ * by either player) until it happens, so that a quicker win
+ * is worth more, and a slower loss is worth more. Anything
+ * else is a heuristic value from some board valuation.
+ */
+
+import (
+	"fmt"
+
+	"squava/src/game"
+)
+
+// Score is a minimax value, from the point of view
+// of the player who's about to move.
+type Score int
+
+// Win is the score of a forced win on the plies-th move
+// from now: 1 is the move about to be made.
+func Win(plies int) Score {
+	return Score(game.WIN - plies)
+}
+
+// Loss is the score of a forced loss on the plies-th move from now
+func Loss(plies int) Score {
+	return Score(game.LOSS + plies)
+}
+
+// IsWin returns true for a forced win
+func (s Score) IsWin() bool {
+	return s > game.WIN/2
+}
+
+// IsLoss returns true for a forced loss
+func (s Score) IsLoss() bool {
+	return s < game.LOSS/2
+}
+
+// Decided returns true for a forced win or loss,
+// false for a heuristic value.
+func (s Score) Decided() bool {
+	return s.IsWin() || s.IsLoss()
+}
+
+// Plies returns the number of plies until a forced
+// win or loss, or 0 for a heuristic value.
+func (s Score) Plies() int {
+	switch {
+	case s.IsWin():
+		return game.WIN - int(s)
+	case s.IsLoss():
+		return int(s) - game.LOSS
+	}
+	return 0
+}
+
+// Later returns a forced win or loss plies more plies
+// off, as when it gets looked at from plies moves earlier.
+// Heuristic values stay the same.
+func (s Score) Later(plies int) Score {
+	switch {
+	case s.IsWin():
+		return s - Score(plies)
+	case s.IsLoss():
+		return s + Score(plies)
+	}
+	return s
+}
+
+// String gives "win in 3" or "loss in 2" for forced
+// wins and losses, and a signed number for heuristic values.
+func (s Score) String() string {
+	switch {
+	case s.IsWin():
+		return fmt.Sprintf("win in %d", s.Plies())
+	case s.IsLoss():
+		return fmt.Sprintf("loss in %d", s.Plies())
+	case s > 0:
+		return fmt.Sprintf("+%d", int(s))
+	}
+	return fmt.Sprintf("%d", int(s))
+}
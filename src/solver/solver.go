@@ -0,0 +1,370 @@
+package solver
+
+/* solver - exact solution of squava positions by depth-first
+ * proof-number search (df-pn). A proof-number search proves or
+ * disproves a yes-or-no question about a position, here "can the
+ * player to move win?" and "can the player to move avoid losing?",
+ * going after whichever part of the game tree looks cheapest to
+ * settle. Two such searches decide a position as a win for the first
+ * player, a win for the second player, or a draw.
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/ttable"
+)
+
+// Outcome is the game-theoretic value of a position
+type Outcome int
+
+// Outcomes, from the point of view of the first player, X,
+// who is MAXIMIZER.
+const (
+	Unknown Outcome = iota
+	FirstPlayerWin
+	SecondPlayerWin
+	Draw
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case FirstPlayerWin:
+		return "first player (X) wins"
+	case SecondPlayerWin:
+		return "second player (O) wins"
+	case Draw:
+		return "draw"
+	}
+	return "unknown"
+}
+
+// Result of solving a position
+type Result struct {
+	Outcome Outcome
+	// Move is a move for the player to move that achieves Outcome,
+	// NoMove if the player to move loses, or the game is over.
+	Move engine.Move
+	// ProofSize is the number of distinct positions in the proof
+	// and disproof trees that decided Outcome, counting each
+	// transposition once, and counting the position itself.
+	ProofSize int
+	// Nodes is the number of positions the searches expanded
+	Nodes int
+}
+
+// ErrNodeLimit comes back from Solve when the searches expanded
+// more than the maximum number of positions without an answer.
+var ErrNodeLimit = errors.New("node limit reached")
+
+// infinity is the proof or disproof number of a settled question
+const infinity = 1 << 30
+
+// Goals of a search: what the player to move at the root
+// has to achieve for a proof.
+const (
+	win = iota
+	notLose
+)
+
+// entry holds the proof and disproof numbers of a position
+type entry struct {
+	pn, dn int
+}
+
+// Solver holds the state of one df-pn search
+type Solver struct {
+	rules    game.Rules
+	attacker int // player to move at the root
+	goal     int
+	table    map[uint64]entry
+	nodes    int
+	maxNodes int
+	ctx      context.Context
+	err      error
+}
+
+// Solve decides the position on bd, a board of any game.Shape,
+// under rules, with player to move, MAXIMIZER being the first
+// player. It gives up with ErrNodeLimit after expanding maxNodes
+// positions, 0 for no limit, or with ctx's error when ctx is
+// cancelled.
+func Solve(ctx context.Context, rules game.Rules, bd game.Bitboard, player int, maxNodes int) (Result, error) {
+	if winner := rules.Winner(&bd); winner != game.UNSET {
+		return Result{Outcome: outcome(winner), Move: engine.NoMove, ProofSize: 1}, nil
+	}
+	if bd.Empty() == 0 {
+		return Result{Outcome: Draw, Move: engine.NoMove, ProofSize: 1}, nil
+	}
+
+	var result Result
+
+	// Can the player to move win?
+	s := &Solver{rules: rules, attacker: player, goal: win, maxNodes: maxNodes, ctx: ctx}
+	proven, err := s.prove(&bd)
+	result.Nodes += s.nodes
+	if err != nil {
+		return result, err
+	}
+	if proven {
+		result.Outcome = outcome(player)
+		result.Move = s.bestMove(&bd)
+		result.ProofSize = s.proofSize(&bd, player, make(map[uint64]bool))
+		return result, nil
+	}
+	disproofSize := s.proofSize(&bd, player, make(map[uint64]bool))
+
+	// No. Can the player to move at least draw?
+	s = &Solver{rules: rules, attacker: player, goal: notLose, maxNodes: maxNodes - result.Nodes, ctx: ctx}
+	if maxNodes == 0 {
+		s.maxNodes = 0
+	}
+	proven, err = s.prove(&bd)
+	result.Nodes += s.nodes
+	if err != nil {
+		return result, err
+	}
+	result.ProofSize = s.proofSize(&bd, player, make(map[uint64]bool))
+	if proven {
+		result.Outcome = Draw
+		result.Move = s.bestMove(&bd)
+		result.ProofSize += disproofSize
+		return result, nil
+	}
+	result.Outcome = outcome(-player)
+	result.Move = engine.NoMove
+	return result, nil
+}
+
+func outcome(winner int) Outcome {
+	if winner == game.MAXIMIZER {
+		return FirstPlayerWin
+	}
+	return SecondPlayerWin
+}
+
+// prove runs df-pn from the root until it proves
+// or disproves s.goal for s.attacker.
+func (s *Solver) prove(bd *game.Bitboard) (bool, error) {
+	s.table = make(map[uint64]entry)
+	for {
+		s.mid(bd, s.attacker, infinity, infinity)
+		if s.err != nil {
+			return false, s.err
+		}
+		e := s.lookup(bd)
+		if e.pn == 0 {
+			return true, nil
+		}
+		if e.dn == 0 {
+			return false, nil
+		}
+	}
+}
+
+func key(bd *game.Bitboard) uint64 {
+	// Whose move it is follows from the number of marks
+	key, _ := ttable.Key(bd, game.MAXIMIZER)
+	return key
+}
+
+func (s *Solver) lookup(bd *game.Bitboard) entry {
+	if e, ok := s.table[key(bd)]; ok {
+		return e
+	}
+	return entry{pn: 1, dn: 1}
+}
+
+// terminal returns the proof and disproof numbers of the position
+// after player's move on cell, and true, if that move ended the game.
+func (s *Solver) terminal(bd *game.Bitboard, cell int) (entry, bool) {
+	winner := s.rules.MoveWinner(bd, cell)
+	switch {
+	case winner == s.attacker:
+		return entry{pn: 0, dn: infinity}, true
+	case winner != game.UNSET:
+		return entry{pn: infinity, dn: 0}, true
+	case bd.Empty() == 0:
+		if s.goal == notLose {
+			return entry{pn: 0, dn: infinity}, true
+		}
+		return entry{pn: infinity, dn: 0}, true
+	}
+	return entry{}, false
+}
+
+// child returns the proof and disproof numbers of the
+// position after player's move on cell.
+func (s *Solver) child(bd *game.Bitboard, cell int, player int) entry {
+	bd.MakeMove(cell, player)
+	e, ok := s.terminal(bd, cell)
+	if !ok {
+		e = s.lookup(bd)
+	}
+	bd.UnmakeMove(cell, player)
+	return e
+}
+
+// mid is df-pn's "multiple iterative deepening": it expands the
+// most-proving position below bd until bd's proof number reaches
+// pnLimit or its disproof number reaches dnLimit.
+func (s *Solver) mid(bd *game.Bitboard, player int, pnLimit, dnLimit int) {
+	if s.maxNodes > 0 && s.nodes >= s.maxNodes {
+		s.err = ErrNodeLimit
+		return
+	}
+	s.nodes++
+	if s.nodes&1023 == 0 && engine.Cancelled(s.ctx) {
+		s.err = s.ctx.Err()
+		return
+	}
+
+	var cells []int
+	for empty := bd.UniqueMoves(); empty != 0; empty &= empty - 1 {
+		cells = append(cells, bits.TrailingZeros64(empty))
+	}
+	or := player == s.attacker
+	children := make([]entry, len(cells))
+
+	for {
+		for i, cell := range cells {
+			children[i] = s.child(bd, cell, player)
+		}
+		e, best, second := combine(children, or)
+		s.table[key(bd)] = e
+		if e.pn >= pnLimit || e.dn >= dnLimit {
+			return
+		}
+
+		c := children[best]
+		var childPN, childDN int
+		if or {
+			childPN = min(pnLimit, second+1)
+			childDN = dnLimit - e.dn + c.dn
+		} else {
+			childPN = pnLimit - e.pn + c.pn
+			childDN = min(dnLimit, second+1)
+		}
+		bd.MakeMove(cells[best], player)
+		s.mid(bd, -player, childPN, childDN)
+		bd.UnmakeMove(cells[best], player)
+		if s.err != nil {
+			return
+		}
+	}
+}
+
+// combine computes the proof and disproof numbers of a position
+// from its children's, and returns the index of the most-proving
+// child, and the second-smallest proof (OR) or disproof (AND)
+// number, for that child's threshold.
+func combine(children []entry, or bool) (e entry, best int, second int) {
+	second = infinity
+	if or {
+		e.pn = infinity
+		for i, c := range children {
+			e.dn = add(e.dn, c.dn)
+			if c.pn < e.pn {
+				second = e.pn
+				e.pn = c.pn
+				best = i
+			} else if c.pn < second {
+				second = c.pn
+			}
+		}
+		return e, best, second
+	}
+	e.dn = infinity
+	for i, c := range children {
+		e.pn = add(e.pn, c.pn)
+		if c.dn < e.dn {
+			second = e.dn
+			e.dn = c.dn
+			best = i
+		} else if c.dn < second {
+			second = c.dn
+		}
+	}
+	return e, best, second
+}
+
+func add(a, b int) int {
+	if a+b >= infinity {
+		return infinity
+	}
+	return a + b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// bestMove returns a move of the root's proof
+func (s *Solver) bestMove(bd *game.Bitboard) engine.Move {
+	for empty := bd.UniqueMoves(); empty != 0; empty &= empty - 1 {
+		cell := bits.TrailingZeros64(empty)
+		if s.child(bd, cell, s.attacker).pn == 0 {
+			x, y := bd.Shape().Coords(cell)
+			return engine.Move{X: x, Y: y}
+		}
+	}
+	return engine.NoMove
+}
+
+// proofSize counts the positions in the proof or disproof
+// of the position on bd, with player to move. A proven OR node
+// or a disproven AND node needs one settling child, the other
+// kinds of node need all of their children.
+func (s *Solver) proofSize(bd *game.Bitboard, player int, seen map[uint64]bool) int {
+	k := key(bd)
+	if seen[k] {
+		return 0
+	}
+	seen[k] = true
+
+	proven := s.lookup(bd).pn == 0
+	one := proven == (player == s.attacker)
+	size := 1
+	for empty := bd.UniqueMoves(); empty != 0; empty &= empty - 1 {
+		cell := bits.TrailingZeros64(empty)
+		bd.MakeMove(cell, player)
+		e, ok := s.terminal(bd, cell)
+		if !ok {
+			e = s.lookup(bd)
+		}
+		settles := (proven && e.pn == 0) || (!proven && e.dn == 0)
+		if one && !settles {
+			bd.UnmakeMove(cell, player)
+			continue
+		}
+		if ok {
+			if !seen[key(bd)] {
+				seen[key(bd)] = true
+				size++
+			}
+		} else {
+			size += s.proofSize(bd, -player, seen)
+		}
+		bd.UnmakeMove(cell, player)
+		if one {
+			break
+		}
+	}
+	return size
+}
+
+// String describes a Result, for printing
+func (r Result) String() string {
+	if r.Move == engine.NoMove {
+		return fmt.Sprintf("%v, proof size %d, %d nodes", r.Outcome, r.ProofSize, r.Nodes)
+	}
+	return fmt.Sprintf("%v, by %v, proof size %d, %d nodes", r.Outcome, r.Move, r.ProofSize, r.Nodes)
+}
@@ -0,0 +1,171 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"squava/src/alphabeta"
+	"squava/src/engine"
+	"squava/src/game"
+)
+
+// play makes moves, X first, on an empty Standard
+// board, and returns it and the player to move next.
+func play(moves [][2]int) (game.Bitboard, int) {
+	b := game.Standard.Bitboard()
+	player := game.MAXIMIZER
+	for _, move := range moves {
+		b.MakeMove(game.Cell(move[0], move[1]), player)
+		player = -player
+	}
+	return b, player
+}
+
+// searchOutcome decides the position with a full-depth alpha/beta
+// search, as a check on Solve. AlphaBeta always moves for
+// MAXIMIZER, so it gets the marks swapped when O is to move.
+func searchOutcome(t *testing.T, moves [][2]int) Outcome {
+	b, player := play(moves)
+	p := alphabeta.New(true, bits.OnesCount64(b.Empty()))
+	p.SetTable(nil)
+	p.SetScores(false)
+	mark := game.MAXIMIZER
+	for _, move := range moves {
+		p.MakeMove(move[0], move[1], mark)
+		mark = -mark
+	}
+	if player == game.MINIMIZER {
+		p.SwapSides()
+	}
+	_, info, err := p.Analyze(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("%v: %v", moves, err)
+	}
+	switch {
+	case info.Value.IsWin():
+		return outcome(player)
+	case info.Value.IsLoss():
+		return outcome(-player)
+	}
+	return Draw
+}
+
+// Positions with known outcomes, and nobody's 4 or 3 in a row yet
+var known = []struct {
+	moves [][2]int
+	want  Outcome
+}{
+	// X to move, and wins
+	{[][2]int{{1, 2}, {1, 4}, {4, 2}, {1, 3}, {4, 0}, {0, 3}, {2, 3}, {3, 1}, {1, 1}, {2, 4}, {0, 2}, {3, 2}, {4, 4}, {4, 3}}, FirstPlayerWin},
+	// O to move, and wins
+	{[][2]int{{1, 1}, {3, 3}, {1, 3}, {3, 1}, {0, 0}, {4, 4}, {2, 0}, {0, 4}, {4, 0}}, SecondPlayerWin},
+	// X to move, and loses
+	{[][2]int{{4, 0}, {1, 1}, {4, 4}, {0, 3}, {3, 0}, {2, 0}, {4, 2}, {1, 2}, {3, 1}, {0, 4}, {2, 4}, {3, 2}, {1, 0}, {0, 1}}, SecondPlayerWin},
+	// O to move, and loses
+	{[][2]int{{0, 0}, {4, 0}, {1, 4}, {0, 2}, {3, 3}, {2, 0}, {3, 4}, {2, 1}, {3, 0}, {2, 3}, {1, 2}, {0, 1}, {2, 2}, {4, 4}, {0, 4}}, FirstPlayerWin},
+	// X to move, draws
+	{[][2]int{{0, 0}, {4, 2}, {4, 4}, {1, 0}, {3, 2}, {4, 3}, {3, 3}, {1, 4}, {1, 2}, {0, 3}, {4, 0}, {3, 4}, {2, 4}, {3, 1}, {4, 1}, {2, 2}}, Draw},
+	{[][2]int{{3, 2}, {0, 3}, {4, 4}, {1, 1}, {3, 3}, {2, 3}, {2, 4}, {3, 0}, {1, 2}, {2, 2}, {0, 1}, {4, 2}, {2, 0}, {1, 0}, {2, 1}, {4, 3}, {0, 0}, {3, 1}}, Draw},
+}
+
+func TestKnownPositions(t *testing.T) {
+	for _, k := range known {
+		b, player := play(k.moves)
+		result, err := Solve(context.Background(), game.FourWins, b, player, 0)
+		if err != nil {
+			t.Fatalf("%v: %v", k.moves, err)
+		}
+		if result.Outcome != k.want {
+			t.Errorf("%v: %v, want %v", k.moves, result.Outcome, k.want)
+		}
+		if got := searchOutcome(t, k.moves); got != k.want {
+			t.Errorf("%v: alpha/beta says %v, want %v", k.moves, got, k.want)
+		}
+
+		// The player to move has a move that keeps the outcome,
+		// unless every move loses.
+		if result.Outcome != outcome(-player) && result.Move == engine.NoMove {
+			t.Errorf("%v: %v, but no move", k.moves, result.Outcome)
+		}
+		if result.Move == engine.NoMove {
+			continue
+		}
+		after := append(k.moves[:len(k.moves):len(k.moves)], [2]int{result.Move.X, result.Move.Y})
+		b, player = play(after)
+		next, err := Solve(context.Background(), game.FourWins, b, player, 0)
+		if err != nil {
+			t.Fatalf("%v: %v", after, err)
+		}
+		if next.Outcome != result.Outcome {
+			t.Errorf("%v: %v by %v, but it's %v after", k.moves, result.Outcome, result.Move, next.Outcome)
+		}
+	}
+}
+
+func TestFinishedGames(t *testing.T) {
+	won, _ := play([][2]int{{0, 0}, {4, 4}, {0, 1}, {4, 3}, {0, 3}, {3, 4}, {0, 2}})
+	result, err := Solve(context.Background(), game.FourWins, won, game.MINIMIZER, 0)
+	if err != nil || result.Outcome != FirstPlayerWin || result.Move != engine.NoMove {
+		t.Errorf("4 in a row for X: %v, %v", result, err)
+	}
+
+	lost, _ := play([][2]int{{0, 0}, {4, 4}, {0, 1}, {4, 3}, {0, 2}})
+	result, err = Solve(context.Background(), game.FourWins, lost, game.MINIMIZER, 0)
+	if err != nil || result.Outcome != SecondPlayerWin || result.Move != engine.NoMove {
+		t.Errorf("3 in a row for X: %v, %v", result, err)
+	}
+}
+
+// Random positions, 10 or so cells from the end,
+// come out the same from Solve and from alpha/beta.
+func TestAgreesWithAlphaBeta(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	counts := make(map[Outcome]int)
+	for n := 0; n < 60; n++ {
+		b := game.Standard.Bitboard()
+		player := game.MAXIMIZER
+		var moves [][2]int
+		for marks := 13 + r.Intn(6); len(moves) < marks; {
+			empty := b.Empty()
+			for k := r.Intn(bits.OnesCount64(empty)); k > 0; k-- {
+				empty &= empty - 1
+			}
+			cell := bits.TrailingZeros64(empty)
+			b.MakeMove(cell, player)
+			if game.FourWins.MoveWinner(&b, cell) != game.UNSET {
+				b.UnmakeMove(cell, player)
+				continue
+			}
+			x, y := game.Standard.Coords(cell)
+			moves = append(moves, [2]int{x, y})
+			player = -player
+		}
+
+		result, err := Solve(context.Background(), game.FourWins, b, player, 0)
+		if err != nil {
+			t.Fatalf("%v: %v", moves, err)
+		}
+		if want := searchOutcome(t, moves); result.Outcome != want {
+			t.Errorf("%v: %v, alpha/beta says %v", moves, result.Outcome, want)
+		}
+		counts[result.Outcome]++
+	}
+	if counts[FirstPlayerWin] == 0 || counts[SecondPlayerWin] == 0 {
+		t.Errorf("outcomes %v are all the same", counts)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	b := game.Standard.Bitboard()
+	if _, err := Solve(context.Background(), game.FourWins, b, game.MAXIMIZER, 100); err != ErrNodeLimit {
+		t.Errorf("empty board with 100 nodes: %v, want %v", err, ErrNodeLimit)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, game.FourWins, b, game.MAXIMIZER, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("empty board, cancelled: %v, want %v", err, context.Canceled)
+	}
+}
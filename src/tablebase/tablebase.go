@@ -0,0 +1,360 @@
+package tablebase
+
+/* tablebase - perfect play for squava positions with only a few
+ * empty cells left. Generate works backwards from the full board:
+ * every position with no empty cells is valued, then every position
+ * with 1 empty cell from those, and so on up to the most empty cells
+ * asked for. Positions where somebody already won aren't in the
+ * table, and of the 8 rotations and reflections of a position only
+ * the canonical one is.
+ *
+ * A table on disk is a 16 byte header (magic number, version, most
+ * empty cells, rules, count of positions) followed by one 8 byte record
+ * per position, sorted, so that a loaded table probes by binary search.
+ * The values depend on the rules for a move that fills in a 4-in-a-row
+ * and a 3-in-a-row at once, so a table only works for its own rules.
+ */
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"sort"
+
+	"squava/src/game"
+	"squava/src/score"
+)
+
+// DefaultEmpty is the most empty cells of the positions in a
+// table that tbgen makes by default. Each empty cell more makes
+// a table roughly 5 to 8 times bigger: about 5 million
+// positions and 40 megabytes for 5 empty cells.
+const DefaultEmpty = 5
+
+// Table holds the value of every undecided position with
+// up to maxEmpty empty cells. Each record is a position's key,
+// its first player's marks and then its second player's marks,
+// 25 bits each, shifted up 8 bits over the position's value.
+// A value of n > 0 means the player to move wins on the nth
+// move from now, -n loses on the nth move, 0 is a draw.
+type Table struct {
+	maxEmpty int
+	rules    game.Rules
+	records  []uint64
+}
+
+var magic = [4]byte{'S', 'Q', 'T', 'B'}
+
+const version = 1
+
+// ErrFormat comes back from Read for something that isn't a table
+var ErrFormat = errors.New("not a squava tablebase")
+
+// MaxEmpty returns the most empty cells a position in
+// the table can have.
+func (t *Table) MaxEmpty() int {
+	return t.maxEmpty
+}
+
+// Rules returns the rules the table's values are for
+func (t *Table) Rules() game.Rules {
+	return t.rules
+}
+
+// Len returns the number of positions in the table
+func (t *Table) Len() int {
+	return len(t.records)
+}
+
+// Generate values every undecided position with at most
+// maxEmpty empty cells under rules, fewest empty cells first, so
+// that every position's moves lead to positions already in the table.
+func Generate(maxEmpty int, rules game.Rules) *Table {
+	t := &Table{maxEmpty: maxEmpty, rules: rules}
+	var layer []uint64
+	for empty := 0; empty <= maxEmpty; empty++ {
+		layer = t.layer(empty, layer)
+		t.records = append(t.records, layer...)
+	}
+	sort.Slice(t.records, func(i, j int) bool { return t.records[i] < t.records[j] })
+	return t
+}
+
+// layer returns the sorted records of the canonical positions
+// with empty empty cells, valued by probing previous, the
+// sorted records of positions with one fewer empty cell.
+func (t *Table) layer(empty int, previous []uint64) []uint64 {
+	var records []uint64
+	var bd game.Bitboard
+	marks := 25 - empty
+	firstCount := (marks + 1) / 2
+	secondCount := marks / 2
+
+	// Every cell is empty, the first player's or the second
+	// player's. No mark can make a 3-in-a-row: every 4-in-a-row
+	// has one inside, so that keeps out every decided position.
+	var place func(cell, first, second int)
+	place = func(cell, first, second int) {
+		if cell == 25 {
+			canonical, _ := bd.Canonical()
+			if canonical == bd {
+				k := key(&bd)
+				records = append(records, k<<8|uint64(uint8(t.value(&bd, previous))))
+			}
+			return
+		}
+		left := 25 - cell
+		if left > firstCount-first+secondCount-second {
+			place(cell+1, first, second)
+		}
+		if first < firstCount {
+			bd.MakeMove(cell, game.MAXIMIZER)
+			if t.rules.MoveWinner(&bd, cell) == game.UNSET {
+				place(cell+1, first+1, second)
+			}
+			bd.UnmakeMove(cell, game.MAXIMIZER)
+		}
+		if second < secondCount {
+			bd.MakeMove(cell, game.MINIMIZER)
+			if t.rules.MoveWinner(&bd, cell) == game.UNSET {
+				place(cell+1, first, second+1)
+			}
+			bd.UnmakeMove(cell, game.MINIMIZER)
+		}
+	}
+	place(0, 0, 0)
+
+	sort.Slice(records, func(i, j int) bool { return records[i] < records[j] })
+	return records
+}
+
+// value finds the value of the undecided position on bd, first
+// player MAXIMIZER, from the values of the positions its moves
+// lead to in records.
+func (t *Table) value(bd *game.Bitboard, records []uint64) int8 {
+	empty := bd.Empty()
+	if empty == 0 {
+		return 0 // a draw
+	}
+	player := toMove(bd)
+	best := score.Score(3 * game.LOSS)
+	for moves := bd.UniqueMoves(); moves != 0; moves &= moves - 1 {
+		cell := bits.TrailingZeros64(moves)
+		bd.MakeMove(cell, player)
+		var v score.Score
+		switch t.rules.MoveWinner(bd, cell) {
+		case player:
+			v = score.Win(1)
+		case -player:
+			v = score.Loss(1)
+		default:
+			v = -lookup(records, key(bd)).Later(1)
+		}
+		bd.UnmakeMove(cell, player)
+		if v > best {
+			best = v
+		}
+	}
+	return encode(best)
+}
+
+func encode(s score.Score) int8 {
+	switch {
+	case s.IsWin():
+		return int8(s.Plies())
+	case s.IsLoss():
+		return -int8(s.Plies())
+	}
+	return 0
+}
+
+func decode(v int8) score.Score {
+	switch {
+	case v > 0:
+		return score.Win(int(v))
+	case v < 0:
+		return score.Loss(int(-v))
+	}
+	return 0
+}
+
+// toMove returns MAXIMIZER if it's the first player's move on bd
+func toMove(bd *game.Bitboard) int {
+	if bits.OnesCount64(bd.Marks(game.MAXIMIZER)) == bits.OnesCount64(bd.Marks(game.MINIMIZER)) {
+		return game.MAXIMIZER
+	}
+	return game.MINIMIZER
+}
+
+// key returns the key of the canonical board of bd, first player MAXIMIZER
+func key(bd *game.Bitboard) uint64 {
+	canonical, _ := bd.Canonical()
+	return canonical.Marks(game.MAXIMIZER) | canonical.Marks(game.MINIMIZER)<<25
+}
+
+// lookup finds the value of the position with key k in
+// sorted records, which has to have that position.
+func lookup(records []uint64, k uint64) score.Score {
+	i := sort.Search(len(records), func(i int) bool { return records[i]>>8 >= k })
+	if i == len(records) || records[i]>>8 != k {
+		panic(fmt.Sprintf("tablebase: position %x missing", k))
+	}
+	return decode(int8(records[i]))
+}
+
+// Probe returns the value of the position on bd for player,
+// who is about to move, and true, if the table has the position.
+// Positions with too many empty cells, positions where somebody
+// already won, impossible positions and positions on boards
+// other than game.Standard aren't in the table.
+func (t *Table) Probe(bd *game.Bitboard, player int) (score.Score, bool) {
+	if bd.Shape() != game.Standard || bits.OnesCount64(bd.Empty()) > t.maxEmpty {
+		return 0, false
+	}
+	// Either player can be MAXIMIZER on bd, but the table's
+	// positions have the first player as MAXIMIZER.
+	mine, theirs := bd.Marks(player), bd.Marks(-player)
+	first, second := mine, theirs
+	switch bits.OnesCount64(mine) - bits.OnesCount64(theirs) {
+	case 0:
+	case -1:
+		first, second = theirs, mine
+	default:
+		return 0, false
+	}
+	b := game.FromMarks(first, second)
+	k := key(&b)
+	i := sort.Search(len(t.records), func(i int) bool { return t.records[i]>>8 >= k })
+	if i == len(t.records) || t.records[i]>>8 != k {
+		return 0, false
+	}
+	return decode(int8(t.records[i])), true
+}
+
+// BestMove returns player's best move on bd, a cell number, and
+// its value, and true, if the table has bd's position. Of moves
+// with the same value, it returns the lowest-numbered cell. A full
+// board has no move, -1, and is a draw.
+func (t *Table) BestMove(bd *game.Bitboard, player int) (int, score.Score, bool) {
+	if v, ok := t.Probe(bd, player); !ok || bd.Empty() == 0 {
+		return -1, v, ok
+	}
+	bestCell, best := -1, score.Score(3*game.LOSS)
+	for moves := bd.Empty(); moves != 0; moves &= moves - 1 {
+		cell := bits.TrailingZeros64(moves)
+		bd.MakeMove(cell, player)
+		var v score.Score
+		switch t.rules.MoveWinner(bd, cell) {
+		case player:
+			v = score.Win(1)
+		case -player:
+			v = score.Loss(1)
+		default:
+			if opponent, ok := t.Probe(bd, -player); ok {
+				v = -opponent.Later(1)
+			} else {
+				v = 0 // a full board
+			}
+		}
+		bd.UnmakeMove(cell, player)
+		if v > best {
+			bestCell, best = cell, v
+		}
+	}
+	return bestCell, best, true
+}
+
+// Line returns the cell numbers of the moves both players make
+// from bd's position on, with player moving first, if both play
+// BestMove, or nil if the table doesn't have the position.
+func (t *Table) Line(bd *game.Bitboard, player int) []int {
+	b := *bd
+	var line []int
+	for {
+		cell, _, ok := t.BestMove(&b, player)
+		if !ok || cell < 0 {
+			return line
+		}
+		line = append(line, cell)
+		b.MakeMove(cell, player)
+		if t.rules.MoveWinner(&b, cell) != game.UNSET {
+			return line
+		}
+		player = -player
+	}
+}
+
+// Write writes the table in its on-disk format
+func (t *Table) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 16)
+	copy(header, magic[:])
+	header[4] = version
+	header[5] = byte(t.maxEmpty)
+	header[6] = byte(t.rules)
+	binary.LittleEndian.PutUint64(header[8:], uint64(len(t.records)))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	var buf [8]byte
+	for _, r := range t.records {
+		binary.LittleEndian.PutUint64(buf[:], r)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Read reads a table that Write wrote
+func Read(r io.Reader) (*Table, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if [4]byte{header[0], header[1], header[2], header[3]} != magic || header[4] != version {
+		return nil, ErrFormat
+	}
+	t := &Table{maxEmpty: int(header[5]), rules: game.Rules(header[6])}
+	count := binary.LittleEndian.Uint64(header[8:])
+	if _, err := game.ParseRules(t.rules.String()); err != nil || t.maxEmpty > 25 || count > 1<<32 {
+		return nil, ErrFormat
+	}
+	t.records = make([]uint64, count)
+	var buf [8]byte
+	for i := range t.records {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return nil, err
+		}
+		t.records[i] = binary.LittleEndian.Uint64(buf[:])
+	}
+	return t, nil
+}
+
+// Load reads the table in file filename
+func Load(filename string) (*Table, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Save writes the table to file filename
+func (t *Table) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := t.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
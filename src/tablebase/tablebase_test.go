@@ -0,0 +1,198 @@
+package tablebase
+
+import (
+	"bytes"
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"squava/src/game"
+	"squava/src/score"
+)
+
+// bruteForce values the position on bd for player, who is about
+// to move, by searching every move to the end of the game, with no
+// table and no symmetry.
+func bruteForce(rules game.Rules, bd *game.Bitboard, player int) score.Score {
+	if bd.Empty() == 0 {
+		return 0
+	}
+	best := score.Score(3 * game.LOSS)
+	for moves := bd.Empty(); moves != 0; moves &= moves - 1 {
+		cell := bits.TrailingZeros64(moves)
+		bd.MakeMove(cell, player)
+		var v score.Score
+		switch rules.MoveWinner(bd, cell) {
+		case player:
+			v = score.Win(1)
+		case -player:
+			v = score.Loss(1)
+		default:
+			v = -bruteForce(rules, bd, -player).Later(1)
+		}
+		bd.UnmakeMove(cell, player)
+		if v > best {
+			best = v
+		}
+	}
+	return best
+}
+
+// randomPosition plays random moves, X first, until empty cells
+// are left, and returns the board and the player to move, or
+// false if somebody won on the way.
+func randomPosition(r *rand.Rand, rules game.Rules, empty int) (game.Bitboard, int, bool) {
+	b := game.Standard.Bitboard()
+	player := game.MAXIMIZER
+	for bits.OnesCount64(b.Empty()) > empty {
+		cells := b.Empty()
+		for k := r.Intn(bits.OnesCount64(cells)); k > 0; k-- {
+			cells &= cells - 1
+		}
+		cell := bits.TrailingZeros64(cells)
+		b.MakeMove(cell, player)
+		if rules.MoveWinner(&b, cell) != game.UNSET {
+			return b, player, false
+		}
+		player = -player
+	}
+	return b, player, true
+}
+
+var tables = []struct {
+	rules    game.Rules
+	maxEmpty int
+}{
+	{game.FourWins, 3},
+	{game.ThreeLoses, 3},
+	{game.FourContainsThree, 3},
+}
+
+func TestProbe(t *testing.T) {
+	for _, tt := range tables {
+		tb := Generate(tt.maxEmpty, tt.rules)
+		r := rand.New(rand.NewSource(1))
+		for n := 0; n < 300; n++ {
+			// Any of the table's positions, which are all undecided
+			k := tb.records[r.Intn(tb.Len())] >> 8
+			b := game.FromMarks(k&game.AllCells, k>>25)
+			player := toMove(&b)
+			want := bruteForce(tt.rules, &b, player)
+
+			got, ok := tb.Probe(&b, player)
+			if !ok || got != want {
+				t.Fatalf("%v: %v to move on\n%v\nprobes %v %v, brute force says %v",
+					tt.rules, player, &b, got, ok, want)
+			}
+			// The same position with the marks swapped, and rotated
+			// or reflected, is in the table just the same.
+			swapped := b.Swapped()
+			if got, ok := tb.Probe(&swapped, -player); !ok || got != want {
+				t.Fatalf("%v: swapped\n%v\nprobes %v %v, want %v", tt.rules, &swapped, got, ok, want)
+			}
+			image := b.Transform(1 + r.Intn(7))
+			if got, ok := tb.Probe(&image, player); !ok || got != want {
+				t.Fatalf("%v: image\n%v\nprobes %v %v, want %v", tt.rules, &image, got, ok, want)
+			}
+
+			cell, value, ok := tb.BestMove(&b, player)
+			if !ok || value != want {
+				t.Fatalf("%v: best move on\n%v\nworth %v %v, want %v", tt.rules, &b, value, ok, want)
+			}
+			if cell < 0 {
+				if b.Empty() != 0 {
+					t.Fatalf("%v: no best move on\n%v", tt.rules, &b)
+				}
+				continue
+			}
+			b.MakeMove(cell, player)
+			after := score.Win(1)
+			if winner := tt.rules.MoveWinner(&b, cell); winner == -player {
+				after = score.Loss(1)
+			} else if winner == game.UNSET {
+				after = -bruteForce(tt.rules, &b, -player).Later(1)
+			}
+			if after != want {
+				t.Fatalf("%v: best move %d on\n%v\nis worth %v, want %v", tt.rules, cell, &b, after, want)
+			}
+		}
+	}
+}
+
+func TestProbeMissing(t *testing.T) {
+	tb := Generate(2, game.FourWins)
+	r := rand.New(rand.NewSource(2))
+	for n := 0; n < 100; n++ {
+		b, player, undecided := randomPosition(r, game.FourWins, 3+r.Intn(6))
+		if _, ok := tb.Probe(&b, player); ok && undecided {
+			t.Fatalf("found\n%v\nwith %d empty cells", &b, bits.OnesCount64(b.Empty()))
+		}
+	}
+	for n := 0; n < 100; {
+		b, player, undecided := randomPosition(r, game.FourWins, 0)
+		if undecided {
+			continue
+		}
+		n++
+		if _, ok := tb.Probe(&b, -player); ok && bits.OnesCount64(b.Empty()) <= 2 {
+			t.Fatalf("found\n%v\nwhere %v won", &b, player)
+		}
+	}
+	shape, err := game.NewShape(4, 4, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := shape.Bitboard()
+	for n := 0; n < 14; n++ {
+		b.MakeMove(n, 1-2*(n&1))
+	}
+	if _, ok := tb.Probe(&b, game.MAXIMIZER); ok {
+		t.Fatalf("found a position on a 4x4 board")
+	}
+}
+
+func TestLine(t *testing.T) {
+	tb := Generate(3, game.FourWins)
+	r := rand.New(rand.NewSource(3))
+	for n := 0; n < 100; {
+		b, player, ok := randomPosition(r, game.FourWins, 3)
+		if !ok {
+			continue
+		}
+		n++
+		want, _ := tb.Probe(&b, player)
+		line := tb.Line(&b, player)
+		if want.Decided() && len(line) != want.Plies() {
+			t.Fatalf("line %v on\n%v\nfor %v", line, &b, want)
+		}
+		if !want.Decided() && len(line) != 3 {
+			t.Fatalf("line %v on\n%v\nfor a draw", line, &b)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	tb := Generate(2, game.ThreeLoses)
+	var buf bytes.Buffer
+	if err := tb.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.MaxEmpty() != 2 || read.Rules() != game.ThreeLoses || read.Len() != tb.Len() {
+		t.Fatalf("read back %d empty, %v, %d positions, want 2, %v, %d",
+			read.MaxEmpty(), read.Rules(), read.Len(), game.ThreeLoses, tb.Len())
+	}
+	for i := range tb.records {
+		if read.records[i] != tb.records[i] {
+			t.Fatalf("record %d is %x, want %x", i, read.records[i], tb.records[i])
+		}
+	}
+
+	garbage := append([]byte("SQTX"), buf.Bytes()[4:]...)
+	if _, err := Read(bytes.NewReader(garbage)); err != ErrFormat {
+		t.Errorf("bad magic number: %v, want %v", err, ErrFormat)
+	}
+}
@@ -0,0 +1,221 @@
+package threats
+
+/* threats - threat-space search for forced wins. The attacker only
+ * makes forcing moves: a move that leaves a hole, an empty cell where
+ * the attacker's next mark makes a winning 4-in-a-row. The defender
+ * has to fill a lone hole or lose, so the search only looks at the
+ * one reply. Two holes at once, like the triangle traps, win outright,
+ * as does a hole the defender can only fill by making their own
+ * losing 3-in-a-row. Any defender move that wins right away, or any
+ * hole of the defender's that the attacker can't block with a
+ * forcing move, ends the line. With so few moves to look at, the
+ * search sees forced wins much deeper than a full-width search.
+ */
+
+import (
+	"context"
+	"math/bits"
+
+	"squava/src/engine"
+	"squava/src/game"
+)
+
+// DefaultPlies is how far ahead engines look for a forced
+// win by threats before they search, counting both players' moves
+const DefaultPlies = 15
+
+// DefaultQuiescence is how many forced moves, the ones Reply
+// returns, engines make past their search horizon, at most
+const DefaultQuiescence = 6
+
+// searcher holds one search's board, and the positions
+// where it found no forced win
+type searcher struct {
+	bd       game.Bitboard
+	rules    game.Rules
+	attacker int
+	ctx      context.Context
+	failed   map[[2]uint64]int // no forced win in this many plies, or fewer
+	nodes    int
+	stopped  bool
+}
+
+// Search looks for a forced win by threats for player, to
+// move on bd, of at most maxPlies plies, counting both
+// players' moves, shortest first. It returns the winning line,
+// player's moves and the forced replies, nil if there isn't
+// one, and the count of positions it looked at. A cancelled
+// ctx stops it, with no line.
+//
+// A line can end with a forced reply that loses. The other
+// player can put that off a ply, by letting player fill the
+// hole instead, so the win takes Plies(line) plies.
+func Search(ctx context.Context, bd *game.Bitboard, rules game.Rules, player int, maxPlies int) ([]int, int) {
+	s := &searcher{
+		bd:       *bd,
+		rules:    rules,
+		attacker: player,
+		ctx:      ctx,
+		failed:   make(map[[2]uint64]int),
+	}
+	// Wins come on the attacker's moves, odd plies
+	for plies := 1; plies <= maxPlies; plies += 2 {
+		if line := s.attack(plies); line != nil {
+			return line, s.nodes
+		}
+		if s.stopped {
+			break
+		}
+	}
+	return nil, s.nodes
+}
+
+// Plies returns how many plies it takes a line
+// that Search returns to win, at most
+func Plies(line []int) int {
+	return len(line) | 1
+}
+
+// attack returns a forced win for the attacker, to move,
+// in at most plies plies, or nil.
+func (s *searcher) attack(plies int) []int {
+	s.nodes++
+	if s.nodes&1023 == 0 && engine.Cancelled(s.ctx) {
+		s.stopped = true
+	}
+	if s.stopped {
+		return nil
+	}
+
+	attacker, defender := s.attacker, -s.attacker
+	if holes := s.holes(attacker); holes != 0 {
+		return []int{bits.TrailingZeros64(holes)}
+	}
+	if plies < 3 {
+		return nil
+	}
+
+	key := [2]uint64{s.bd.Marks(game.MAXIMIZER), s.bd.Marks(game.MINIMIZER)}
+	if p, ok := s.failed[key]; ok && p >= plies {
+		return nil
+	}
+
+	// A defender's hole has to get blocked, and
+	// the block has to be a forcing move itself.
+	candidates := s.forcing(attacker)
+	switch blocks := s.holes(defender); bits.OnesCount64(blocks) {
+	case 0:
+	case 1:
+		candidates &= blocks
+	default:
+		candidates = 0
+	}
+
+	for ; candidates != 0; candidates &= candidates - 1 {
+		cell := bits.TrailingZeros64(candidates)
+		s.bd.MakeMove(cell, attacker)
+		line := s.defend(cell, plies)
+		s.bd.UnmakeMove(cell, attacker)
+		if line != nil {
+			return append([]int{cell}, line...)
+		}
+	}
+
+	if !s.stopped {
+		s.failed[key] = plies
+	}
+	return nil
+}
+
+// defend returns the rest of a forced win for the attacker, who
+// just marked cell, in at most plies-1 more plies, or nil.
+func (s *searcher) defend(cell int, plies int) []int {
+	attacker, defender := s.attacker, -s.attacker
+	if s.rules.MoveWinner(&s.bd, cell) != game.UNSET {
+		return nil // winning moves were looked for already, so it lost
+	}
+	holes := s.holes(attacker)
+	if holes == 0 || s.holes(defender) != 0 {
+		return nil // not forcing, or the defender wins now
+	}
+	hole := bits.TrailingZeros64(holes)
+	if holes&(holes-1) != 0 {
+		// Two holes, the defender can only fill one
+		return []int{hole, bits.TrailingZeros64(holes &^ game.Bit(hole))}
+	}
+
+	s.bd.MakeMove(hole, defender)
+	var line []int
+	switch s.rules.MoveWinner(&s.bd, hole) {
+	case attacker:
+		line = []int{hole} // filling the hole lost
+	case game.UNSET:
+		if rest := s.attack(plies - 2); rest != nil {
+			line = append([]int{hole}, rest...)
+		}
+	}
+	s.bd.UnmakeMove(hole, defender)
+	return line
+}
+
+// Holes returns the empty cells of bd where player's
+// next mark wins the game
+func Holes(bd *game.Bitboard, rules game.Rules, player int) uint64 {
+	var holes uint64
+	for cells := nearly(bd, player, 1); cells != 0; cells &= cells - 1 {
+		cell := bits.TrailingZeros64(cells)
+		bd.MakeMove(cell, player)
+		if rules.MoveWinner(bd, cell) == player {
+			holes |= game.Bit(cell)
+		}
+		bd.UnmakeMove(cell, player)
+	}
+	return holes
+}
+
+// Reply returns the move that player, to move on bd, has to make,
+// and who wins by it. With a hole of their own, player wins there.
+// With two holes of the other player's, player loses, and Reply
+// returns one of them. Player has to fill a lone hole of the other
+// player's, and nobody wins yet. Otherwise Reply returns -1, no
+// move in particular, and nobody wins yet.
+func Reply(bd *game.Bitboard, rules game.Rules, player int) (cell int, winner int) {
+	if holes := Holes(bd, rules, player); holes != 0 {
+		return bits.TrailingZeros64(holes), player
+	}
+	holes := Holes(bd, rules, -player)
+	switch {
+	case holes == 0:
+		return -1, game.UNSET
+	case holes&(holes-1) != 0:
+		return bits.TrailingZeros64(holes), -player
+	}
+	return bits.TrailingZeros64(holes), game.UNSET
+}
+
+// holes returns the empty cells where player's
+// next mark wins the game
+func (s *searcher) holes(player int) uint64 {
+	return Holes(&s.bd, s.rules, player)
+}
+
+// forcing returns the empty cells where player's
+// next mark might leave a hole
+func (s *searcher) forcing(player int) uint64 {
+	return nearly(&s.bd, player, 2)
+}
+
+// nearly returns the empty cells of bd's winning lines that
+// are missing marks of player's, with none of the other
+// player's marks in them
+func nearly(bd *game.Bitboard, player int, missing int) uint64 {
+	mine, theirs := bd.Marks(player), bd.Marks(-player)
+	have := bd.Shape().WinLength() - missing
+	var cells uint64
+	for _, quad := range bd.Shape().Quads() {
+		if theirs&quad == 0 && bits.OnesCount64(mine&quad) == have {
+			cells |= quad &^ mine
+		}
+	}
+	return cells
+}
@@ -0,0 +1,131 @@
+package threats
+
+import (
+	"context"
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"squava/src/game"
+	"squava/src/solver"
+)
+
+// play makes moves, X first, on an empty Standard
+// board, and returns it and the player to move next.
+func play(moves [][2]int) (game.Bitboard, int) {
+	b := game.Standard.Bitboard()
+	player := game.MAXIMIZER
+	for _, move := range moves {
+		b.MakeMove(game.Cell(move[0], move[1]), player)
+		player = -player
+	}
+	return b, player
+}
+
+// checkLine plays line out on bd, player first, and fails
+// unless player wins by it. The other player's last forced
+// reply can lose already, with the other hole left over.
+func checkLine(t *testing.T, bd game.Bitboard, rules game.Rules, player int, line []int) {
+	t.Helper()
+	mover := player
+	for i, cell := range line {
+		bd.MakeMove(cell, mover)
+		if winner := rules.MoveWinner(&bd, cell); winner != game.UNSET {
+			if winner != player || i < len(line)-2 {
+				t.Errorf("%v: %d wins after %d moves", line, winner, i+1)
+			}
+			return
+		}
+		mover = -mover
+	}
+	t.Errorf("%v: nobody won", line)
+}
+
+// X completes the triangle (1,1), (1,4), (4,1), like O's in the
+// README, and leaves two holes at once, (1,3) and (3,1).
+func TestTriangleTrap(t *testing.T) {
+	bd, player := play([][2]int{{1, 2}, {0, 0}, {1, 4}, {0, 2}, {2, 1}, {4, 4}, {4, 1}, {3, 4}})
+	line, _ := Search(context.Background(), &bd, game.FourWins, player, DefaultPlies)
+	if len(line) == 0 || line[0] != game.Cell(1, 1) || Plies(line) != 3 {
+		t.Fatalf("line %v, want (1,1) and a win in 3", line)
+	}
+	checkLine(t, bd, game.FourWins, player, line)
+
+	bd.MakeMove(game.Cell(1, 1), player)
+	if holes, want := Holes(&bd, game.FourWins, player), game.Bit(game.Cell(1, 3))|game.Bit(game.Cell(3, 1)); holes != want {
+		t.Errorf("holes %x, want %x", holes, want)
+	}
+	if _, winner := Reply(&bd, game.FourWins, -player); winner != player {
+		t.Errorf("O's reply wins for %d, want %d", winner, player)
+	}
+}
+
+// X's (3,3) leaves a hole at (2,3), which O can
+// only fill by making a 3-in-a-row, (2,1) to (2,3).
+func TestForcedThree(t *testing.T) {
+	bd, player := play([][2]int{{0, 3}, {2, 1}, {1, 3}, {2, 2}})
+	line, _ := Search(context.Background(), &bd, game.FourWins, player, DefaultPlies)
+	want := []int{game.Cell(3, 3), game.Cell(2, 3)}
+	if len(line) != len(want) || line[0] != want[0] || line[1] != want[1] {
+		t.Fatalf("line %v, want %v", line, want)
+	}
+	if Plies(line) != 3 {
+		t.Errorf("%v wins in %d plies, want 3", line, Plies(line))
+	}
+	checkLine(t, bd, game.FourWins, player, line)
+
+	bd.MakeMove(game.Cell(3, 3), player)
+	if cell, winner := Reply(&bd, game.FourWins, -player); cell != game.Cell(2, 3) || winner != game.UNSET {
+		t.Errorf("O's reply %d, %d, want %d, nobody wins yet", cell, winner, game.Cell(2, 3))
+	}
+}
+
+// Every forced win Search finds in random positions
+// is a win for the player to move by Solve, too.
+func TestAgreesWithSolver(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, rules := range []game.Rules{game.FourWins, game.FourContainsThree} {
+		wins := 0
+		for n := 0; n < 300; n++ {
+			b := game.Standard.Bitboard()
+			player := game.MAXIMIZER
+			var moves [][2]int
+			for marks := 8 + r.Intn(8); len(moves) < marks; {
+				empty := b.Empty()
+				for k := r.Intn(bits.OnesCount64(empty)); k > 0; k-- {
+					empty &= empty - 1
+				}
+				cell := bits.TrailingZeros64(empty)
+				b.MakeMove(cell, player)
+				if rules.MoveWinner(&b, cell) != game.UNSET {
+					b.UnmakeMove(cell, player)
+					continue
+				}
+				x, y := game.Standard.Coords(cell)
+				moves = append(moves, [2]int{x, y})
+				player = -player
+			}
+
+			line, _ := Search(context.Background(), &b, rules, player, DefaultPlies)
+			if line == nil {
+				continue
+			}
+			wins++
+			checkLine(t, b, rules, player, line)
+			result, err := solver.Solve(context.Background(), rules, b, player, 0)
+			if err != nil {
+				t.Fatalf("%v: %v", moves, err)
+			}
+			want := solver.FirstPlayerWin
+			if player == game.MINIMIZER {
+				want = solver.SecondPlayerWin
+			}
+			if result.Outcome != want {
+				t.Errorf("%v rules %v: Search wins by %v, Solve says %v", moves, rules, line, result.Outcome)
+			}
+		}
+		if wins < 20 {
+			t.Errorf("rules %v: only %d forced wins", rules, wins)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package three
+
+/* three - squava for three players, X, O and #, who move in
+ * that order. A winning line (4-in-a-row on the usual board) wins
+ * the game outright. A losing line (3-in-a-row) puts the player
+ * who made it out: their marks stay on the board, and the other
+ * two keep playing. The last player left in wins. A full board
+ * is a draw between the players still in. game.Rules decides a
+ * move that makes a winning and a losing line at once.
+ *
+ * The engines here, Paranoid and MCTS, implement Engine rather
+ * than engine.Engine, which only knows about two players.
+ */
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+
+	"squava/src/engine"
+	"squava/src/game"
+)
+
+// Players is the number of players. Player 0 is X,
+// player 1 is O and player 2 is #.
+const Players = 3
+
+// NoPlayer is the winner of a drawn game, and
+// the contents of an empty cell
+const NoPlayer = -1
+
+// Marks has the mark of each player
+var Marks = [Players]string{"X", "O", "#"}
+
+// Engine is a three-player squava player. Like an engine.Engine,
+// it keeps its own board, and MakeMove tells it about the other
+// players' moves. The board knows whose move it is.
+type Engine interface {
+	Name() string
+	// SetGame starts over with an empty board of shape,
+	// with rules deciding a move that makes both lines.
+	SetGame(shape *game.Shape, rules game.Rules)
+	MakeMove(x, y int)
+	// ChooseMove searches until it's done, or until ctx is
+	// cancelled, and makes its move on its own board.
+	ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error)
+}
+
+// Board is a three-player game in progress: a bit per cell
+// for each player, who's out, and whose move it is. It's
+// small enough that searches copy it instead of unmaking moves.
+type Board struct {
+	marks  [Players]uint64
+	out    [Players]bool
+	toMove int
+	winner int
+	over   bool
+	shape  *game.Shape
+	rules  game.Rules
+}
+
+// NewBoard returns an empty board of shape, X to move
+func NewBoard(shape *game.Shape, rules game.Rules) Board {
+	return Board{winner: NoPlayer, shape: shape, rules: rules}
+}
+
+// Shape returns the board's shape
+func (b *Board) Shape() *game.Shape {
+	return b.shape
+}
+
+// ToMove returns the player whose move it is
+func (b *Board) ToMove() int {
+	return b.toMove
+}
+
+// Over returns true once somebody has won, or the board is full
+func (b *Board) Over() bool {
+	return b.over
+}
+
+// Winner returns the player who won, or NoPlayer
+// for a draw or a game that isn't over.
+func (b *Board) Winner() int {
+	return b.winner
+}
+
+// Out returns true if player made a losing line
+func (b *Board) Out(player int) bool {
+	return b.out[player]
+}
+
+// Marks returns the bits of all of player's marks
+func (b *Board) Marks(player int) uint64 {
+	return b.marks[player]
+}
+
+// Empty returns the bits of all the empty cells
+func (b *Board) Empty() uint64 {
+	return b.shape.AllCells() &^ (b.marks[0] | b.marks[1] | b.marks[2])
+}
+
+// At returns the player whose mark is in cell, or NoPlayer
+func (b *Board) At(cell int) int {
+	for player, marks := range b.marks {
+		if marks&game.Bit(cell) != 0 {
+			return player
+		}
+	}
+	return NoPlayer
+}
+
+// MakeMove puts the mark of the player to move in cell, decides
+// what that does, and passes the move to the next player still in.
+func (b *Board) MakeMove(cell int) {
+	player := b.toMove
+	b.marks[player] |= game.Bit(cell)
+	won, lost := b.rules.MoveLines(b.shape, b.marks[player], cell)
+	switch {
+	case won:
+		b.winner, b.over = player, true
+		return
+	case lost:
+		b.out[player] = true
+		if left := b.left(); len(left) == 1 {
+			b.winner, b.over = left[0], true
+			return
+		}
+	}
+	if b.Empty() == 0 {
+		b.over = true
+		return
+	}
+	for b.toMove = (b.toMove + 1) % Players; b.out[b.toMove]; {
+		b.toMove = (b.toMove + 1) % Players
+	}
+}
+
+// left returns the players still in
+func (b *Board) left() []int {
+	var players []int
+	for player, out := range b.out {
+		if !out {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// Threats returns the number of winning lines that are one
+// of player's marks short, with no other player's marks in them.
+func (b *Board) Threats(player int) (count int) {
+	mine := b.marks[player]
+	theirs := (b.marks[0] | b.marks[1] | b.marks[2]) &^ mine
+	near := b.shape.WinLength() - 1
+	for _, quad := range b.shape.Quads() {
+		if theirs&quad == 0 && bits.OnesCount64(mine&quad) == near {
+			count++
+		}
+	}
+	return count
+}
+
+// String renders the board the way game.Bitboard does
+func (b *Board) String() string {
+	s := b.shape
+	str := "  "
+	for y := 0; y < s.Width(); y++ {
+		str += fmt.Sprintf("%2d", y)
+	}
+	str += "\n"
+	for x := 0; x < s.Height(); x++ {
+		str += fmt.Sprintf("%-2d ", x)
+		for y := 0; y < s.Width(); y++ {
+			mark := "_"
+			if player := b.At(s.Cell(x, y)); player != NoPlayer {
+				mark = Marks[player]
+			}
+			str += mark + " "
+		}
+		str += "\n"
+	}
+	return str
+}
@@ -0,0 +1,180 @@
+package three
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"math/rand"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/score"
+)
+
+// MCTS is Monte Carlo tree search for three players. A playout
+// scores 1 for the winner, shares 1 among the players still in
+// after a draw, and 0 for everybody else. Each node keeps the
+// score of the player who made its move, who picks the child
+// that's best for them.
+type MCTS struct {
+	bd         Board
+	iterations int
+	UCTK       float64
+}
+
+type node struct {
+	move         int
+	player       int // who made move
+	parent       *node
+	children     []*node
+	wins         float64
+	visits       float64
+	untriedMoves []int
+}
+
+// NewMCTS makes an MCTS player that does iterations playouts a move
+func NewMCTS(iterations int, UCTK float64) *MCTS {
+	return &MCTS{
+		bd:         NewBoard(game.Standard, game.FourWins),
+		iterations: iterations,
+		UCTK:       UCTK,
+	}
+}
+
+func (p *MCTS) Name() string {
+	return "MCTS"
+}
+
+// SetGame starts over with an empty board
+func (p *MCTS) SetGame(shape *game.Shape, rules game.Rules) {
+	p.bd = NewBoard(shape, rules)
+}
+
+// MakeMove tells p about another player's move
+func (p *MCTS) MakeMove(x, y int) {
+	p.bd.MakeMove(p.bd.Shape().Cell(x, y))
+}
+
+// ChooseMove does p's playouts, or as many as it can before
+// ctx is cancelled, and makes the most visited move.
+func (p *MCTS) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
+	start := time.Now()
+	root := newNode(-1, NoPlayer, nil, &p.bd)
+	if len(root.untriedMoves) == 0 {
+		return engine.NoMove, engine.SearchInfo{}, engine.ErrNoMove
+	}
+
+	stopped := false
+	playouts := 0
+	for i := 0; i < p.iterations; i++ {
+		// Check ctx every so often, but only after the first
+		// iteration, so that root always has a child to choose.
+		if i&63 == 1 && engine.Cancelled(ctx) {
+			stopped = true
+			break
+		}
+
+		n := root
+		bd := p.bd
+		for len(n.untriedMoves) == 0 && len(n.children) > 0 {
+			n = n.selectChild(p.UCTK)
+			bd.MakeMove(n.move)
+		}
+		if len(n.untriedMoves) > 0 {
+			k := rand.Intn(len(n.untriedMoves))
+			m := n.untriedMoves[k]
+			n.untriedMoves = append(n.untriedMoves[:k], n.untriedMoves[k+1:]...)
+			player := bd.ToMove()
+			bd.MakeMove(m)
+			child := newNode(m, player, n, &bd)
+			n.children = append(n.children, child)
+			n = child
+		}
+
+		rewards := playout(&bd)
+		playouts++
+		for ; n != nil; n = n.parent {
+			n.visits++
+			if n.player != NoPlayer {
+				n.wins += rewards[n.player]
+			}
+		}
+	}
+
+	best := root.mostVisited()
+	p.bd.MakeMove(best.move)
+
+	var pv []engine.Move
+	shape := p.bd.Shape()
+	for n := best; n != nil; n = n.mostVisited() {
+		x, y := shape.Coords(n.move)
+		pv = append(pv, engine.Move{X: x, Y: y})
+	}
+
+	// Like the two-player MCTS's value, this is no minimax
+	// value, it's the chosen move's share of playouts won.
+	return pv[0], engine.SearchInfo{
+		Value:     score.Score(1000 * best.wins / best.visits),
+		LeafCount: playouts,
+		Stopped:   stopped,
+		Elapsed:   time.Since(start),
+		PV:        pv,
+	}, nil
+}
+
+func newNode(move, player int, parent *node, bd *Board) *node {
+	n := &node{move: move, player: player, parent: parent}
+	if !bd.Over() {
+		for empty := bd.Empty(); empty != 0; empty &= empty - 1 {
+			n.untriedMoves = append(n.untriedMoves, bits.TrailingZeros64(empty))
+		}
+	}
+	return n
+}
+
+// selectChild returns the child with the best UCB1 value
+// for the player about to move from n, the one who made
+// each child's move.
+func (n *node) selectChild(UCTK float64) *node {
+	var best *node
+	bestUCB1 := math.Inf(-1)
+	for _, c := range n.children {
+		ucb1 := c.wins/c.visits + UCTK*math.Sqrt(2.*math.Log(n.visits)/c.visits)
+		if ucb1 > bestUCB1 {
+			best, bestUCB1 = c, ucb1
+		}
+	}
+	return best
+}
+
+func (n *node) mostVisited() *node {
+	var best *node
+	for _, c := range n.children {
+		if best == nil || c.visits > best.visits {
+			best = c
+		}
+	}
+	return best
+}
+
+// playout makes random moves on bd until the game is over,
+// and returns what the game was worth to each player.
+func playout(bd *Board) (rewards [Players]float64) {
+	for !bd.Over() {
+		empty := bd.Empty()
+		for k := rand.Intn(bits.OnesCount64(empty)); k > 0; k-- {
+			empty &= empty - 1
+		}
+		bd.MakeMove(bits.TrailingZeros64(empty))
+	}
+	if winner := bd.Winner(); winner != NoPlayer {
+		rewards[winner] = 1
+		return rewards
+	}
+	left := bd.left()
+	for _, player := range left {
+		rewards[player] = 1 / float64(len(left))
+	}
+	return rewards
+}
@@ -0,0 +1,227 @@
+package three
+
+import (
+	"context"
+	"math/bits"
+	"math/rand"
+	"time"
+
+	"squava/src/engine"
+	"squava/src/game"
+	"squava/src/score"
+)
+
+// Paranoid searches as if the other two players were one
+// player out to get it: it maximizes its own value, and they
+// minimize it. That turns three-player minimax back into
+// alpha/beta. Getting put out is as bad as somebody else
+// winning, so the search stops there.
+type Paranoid struct {
+	bd            Board
+	me            int
+	maxDepth      int
+	deterministic bool
+	leafCount     int
+	pv            engine.PVTable
+	ctx           context.Context
+	stopped       bool
+	nodeCount     int
+}
+
+// NewParanoid makes a Paranoid player that looks
+// maxDepth moves, by any player, ahead.
+func NewParanoid(deterministic bool, maxDepth int) *Paranoid {
+	return &Paranoid{
+		bd:            NewBoard(game.Standard, game.FourWins),
+		maxDepth:      maxDepth,
+		deterministic: deterministic,
+	}
+}
+
+func (p *Paranoid) Name() string {
+	return "Paranoid"
+}
+
+// SetGame starts over with an empty board
+func (p *Paranoid) SetGame(shape *game.Shape, rules game.Rules) {
+	p.bd = NewBoard(shape, rules)
+}
+
+// MakeMove tells p about another player's move
+func (p *Paranoid) MakeMove(x, y int) {
+	p.bd.MakeMove(p.bd.Shape().Cell(x, y))
+}
+
+// ChooseMove deepens iteratively up to p's maximum depth, or
+// until ctx runs out, and makes the best move of the deepest
+// search it finished. If ctx has a deadline, the deadline decides
+// how deep it goes, up to the end of the game.
+func (p *Paranoid) ChooseMove(ctx context.Context) (engine.Move, engine.SearchInfo, error) {
+	start := time.Now()
+	p.me = p.bd.ToMove()
+	p.ctx = ctx
+	p.stopped = false
+	p.leafCount = 0
+	p.nodeCount = 0
+
+	shape := p.bd.Shape()
+	maxDepth := p.maxDepth
+	if _, ok := ctx.Deadline(); ok {
+		maxDepth = bits.OnesCount64(p.bd.Empty())
+	}
+
+	best := -1
+	var info engine.SearchInfo
+	for depth := 1; depth <= maxDepth; depth++ {
+		cell, value, line := p.root(depth)
+		if p.stopped && best >= 0 {
+			info.Stopped = true
+			break
+		}
+		best = cell
+		info.Value = value
+		info.Depth = depth
+		info.PV = line
+		if value.Decided() || !engine.Deepen(ctx, start) {
+			break
+		}
+	}
+	if best < 0 {
+		return engine.NoMove, info, engine.ErrNoMove
+	}
+
+	p.bd.MakeMove(best)
+	info.LeafCount = p.leafCount
+	info.Elapsed = time.Since(start)
+	x, y := shape.Coords(best)
+	return engine.Move{X: x, Y: y}, info, nil
+}
+
+// root searches each of p's moves to depth, and returns the
+// best, its value and its line, picking at random among equally
+// good moves unless p is deterministic.
+func (p *Paranoid) root(depth int) (int, score.Score, []engine.Move) {
+	shape := p.bd.Shape()
+	var bestCells []int
+	lines := make(map[int][]engine.Move)
+	bestValue := score.Score(3 * game.LOSS)
+	alpha, beta := score.Score(3*game.LOSS), score.Score(3*game.WIN)
+	for empty := p.bd.Empty(); empty != 0; empty &= empty - 1 {
+		cell := bits.TrailingZeros64(empty)
+		b := p.bd
+		b.MakeMove(cell)
+		// Search with a window one wider, so that moves
+		// as good as the best so far don't get cut off.
+		v := p.search(&b, 2, depth-1, alpha-1, beta)
+		if p.stopped {
+			break
+		}
+		if v < bestValue {
+			continue
+		}
+		if v > bestValue {
+			bestValue, bestCells = v, nil
+		}
+		bestCells = append(bestCells, cell)
+		p.pv.Update(1, cell)
+		lines[cell] = p.pv.Line(shape, 1)
+		if v > alpha {
+			alpha = v
+		}
+	}
+	if len(bestCells) == 0 {
+		return -1, bestValue, nil
+	}
+	cell := bestCells[0]
+	if !p.deterministic {
+		cell = bestCells[rand.Intn(len(bestCells))]
+	}
+	return cell, bestValue, lines[cell]
+}
+
+// search returns the value to p of the position on bd, the
+// move before ply just made, looking depth more moves ahead.
+func (p *Paranoid) search(bd *Board, ply int, depth int, alpha, beta score.Score) score.Score {
+	p.pv.Clear(ply)
+
+	switch {
+	case bd.Over() && bd.Winner() == p.me:
+		p.leafCount++
+		return score.Win(ply - 1)
+	case bd.Over() && bd.Winner() != NoPlayer, bd.Out(p.me):
+		p.leafCount++
+		return score.Loss(ply - 1)
+	case bd.Over():
+		p.leafCount++
+		return 0
+	case depth == 0:
+		p.leafCount++
+		return p.value(bd)
+	}
+
+	if p.cancelled() {
+		return 0
+	}
+
+	maximizing := bd.ToMove() == p.me
+	value := score.Score(3 * game.WIN)
+	if maximizing {
+		value = 3 * game.LOSS
+	}
+	for empty := bd.Empty(); empty != 0; empty &= empty - 1 {
+		cell := bits.TrailingZeros64(empty)
+		b := *bd
+		b.MakeMove(cell)
+		v := p.search(&b, ply+1, depth-1, alpha, beta)
+		if maximizing {
+			if v > value {
+				value = v
+				p.pv.Update(ply, cell)
+			}
+			if value > alpha {
+				alpha = value
+			}
+		} else {
+			if v < value {
+				value = v
+				p.pv.Update(ply, cell)
+			}
+			if value < beta {
+				beta = value
+			}
+		}
+		if beta <= alpha {
+			break
+		}
+	}
+	return value
+}
+
+// cancelled returns true once the search's context is
+// done. It only looks every 1024 nodes, leaves or not.
+func (p *Paranoid) cancelled() bool {
+	if !p.stopped {
+		p.nodeCount++
+		if p.nodeCount&1023 == 0 && engine.Cancelled(p.ctx) {
+			p.stopped = true
+		}
+	}
+	return p.stopped
+}
+
+// value is the heuristic value to p of the undecided position
+// on bd: p's winning lines one mark short, less the others'.
+func (p *Paranoid) value(bd *Board) score.Score {
+	var v int
+	for player := 0; player < Players; player++ {
+		if bd.Out(player) {
+			continue
+		}
+		if player == p.me {
+			v += 20 * bd.Threats(player)
+		} else {
+			v -= 10 * bd.Threats(player)
+		}
+	}
+	return score.Score(v)
+}
@@ -0,0 +1,298 @@
+package ttable
+
+/* ttable - a transposition table keyed by Zobrist hashes of
+ * squava positions. Squava has a lot of transpositions: the same
+ * X and O cells get reached by many different orders of moves, and
+ * a minimax search that doesn't remember positions it has already
+ * valued ends up re-searching them from scratch.
+ *
+ * Searches in different goroutines can share a table without locks.
+ * Each slot is two words, an entry packed into one and the key
+ * XORed with the entry in the other. A slot that two goroutines
+ * wrote at once, half of each, doesn't check out against its key,
+ * and a probe treats it as missing.
+ */
+
+import (
+	"math/bits"
+	"math/rand"
+	"sync/atomic"
+
+	"squava/src/game"
+	"squava/src/score"
+)
+
+// Bound types: how a stored value relates to the
+// true minimax value of a position.
+const (
+	EXACT = iota // value is the minimax value
+	LOWER        // minimax value is >= value, search failed high
+	UPPER        // minimax value is <= value, search failed low
+)
+
+// Replacement policies, deciding whether a Store overwrites
+// an entry for a different position in the same slot.
+const (
+	DEPTH  = iota // keep the deeper entry, unless it's from an earlier search
+	ALWAYS        // always overwrite
+)
+
+// DefaultMB is the size of a table that engines make for themselves
+const DefaultMB = 16
+
+// Entry is a single stored position
+type Entry struct {
+	Key        uint64
+	Value      int32
+	Depth      int8
+	Bound      uint8
+	Move       int8 // best move's cell number, -1 if none
+	generation uint8
+}
+
+// slot is an Entry as the table stores it: everything
+// but the key packed into data, and check, the key
+// XORed with data. Slots are 16 bytes, which the
+// size-in-megabytes calculation relies on.
+type slot struct {
+	check, data uint64
+}
+
+const slotSize = 16
+
+// Table is a fixed-size, direct-mapped transposition table.
+// Tables that Share returns have the same slots, and each
+// has its own generation and statistics.
+type Table struct {
+	slots      []slot
+	mask       uint64
+	policy     int
+	generation uint8
+	Probes     int
+	Hits       int
+	Stores     int
+}
+
+var zobrist [2][game.MaxCells]uint64
+var zobristMinimizer uint64
+
+// zobristBytes[side][n][bits] is the XOR of the keys of the
+// marks in bits, the nth byte of one side of a Bitboard. Bytes
+// don't line up with rows, so this works for any game.Shape.
+var zobristBytes [2][game.MaxCells / 8][256]uint64
+
+func init() {
+	// Fixed seed, so that keys are the same from run to run.
+	// The 25 cells of a 5x5 board get theirs first, so that
+	// their keys are the same as before bigger boards came along.
+	r := rand.New(rand.NewSource(5551212))
+	for side := range zobrist {
+		for cell := 0; cell < 25; cell++ {
+			zobrist[side][cell] = r.Uint64()
+		}
+	}
+	zobristMinimizer = r.Uint64()
+	for side := range zobrist {
+		for cell := 25; cell < game.MaxCells; cell++ {
+			zobrist[side][cell] = r.Uint64()
+		}
+	}
+	for side := range zobristBytes {
+		for n := range zobristBytes[side] {
+			for bits := uint(0); bits < 256; bits++ {
+				for k := uint(0); k < 8; k++ {
+					if bits&(1<<k) != 0 {
+						zobristBytes[side][n][bits] ^= zobrist[side][8*n+int(k)]
+					}
+				}
+			}
+		}
+	}
+}
+
+// Zobrist returns the key of player's mark on cell number cell.
+// XOR it into a hash when making or unmaking a move, for a hash
+// that isn't the same for rotations and reflections of the board.
+func Zobrist(cell int, player int) uint64 {
+	if player == game.MAXIMIZER {
+		return zobrist[0][cell]
+	}
+	return zobrist[1][cell]
+}
+
+// ToMove returns the key that distinguishes whose move it is,
+// to XOR into a position's hash.
+func ToMove(player int) uint64 {
+	if player == game.MINIMIZER {
+		return zobristMinimizer
+	}
+	return 0
+}
+
+// Hash calculates the Zobrist hash of a whole board, without
+// regard to whose move it is.
+func Hash(b *game.Bitboard) (hash uint64) {
+	for side, player := range [2]int{game.MAXIMIZER, game.MINIMIZER} {
+		for marks, n := b.Marks(player), 0; marks != 0; marks, n = marks>>8, n+1 {
+			hash ^= zobristBytes[side][n][marks&255]
+		}
+	}
+	return hash
+}
+
+// Key returns the table key of a position with player to move,
+// and the transform that makes its canonical board. All the
+// rotations and reflections of a position have the same key.
+// Moves stored under the key are moves on the canonical board:
+// the board Shape's TransformedCell with transform converts a move
+// to the canonical board, the inverse transform converts it back.
+// Positions on boards of different Shapes can have the same key,
+// so a table should only hold positions of one Shape.
+func Key(b *game.Bitboard, player int) (key uint64, transform int) {
+	canonical, transform := b.Canonical()
+	return Hash(&canonical) ^ ToMove(player), transform
+}
+
+// KeyUnder is Key, but only rotations and reflections in
+// symmetries, from the Shape's Symmetries, share a key. It's for
+// engines whose valuation isn't symmetric under all of them.
+func KeyUnder(b *game.Bitboard, player int, symmetries []int) (key uint64, transform int) {
+	canonical, transform := b.CanonicalUnder(symmetries)
+	return Hash(&canonical) ^ ToMove(player), transform
+}
+
+// New creates a Table that takes up to megabytes of memory,
+// rounded down to a power-of-2 number of entries, using
+// replacement policy DEPTH or ALWAYS.
+func New(megabytes int, policy int) *Table {
+	count := uint64(megabytes) << 20 / slotSize
+	if count < 1 {
+		count = 1
+	}
+	count = 1 << uint(63-bits.LeadingZeros64(count))
+	return &Table{
+		slots:  make([]slot, count),
+		mask:   count - 1,
+		policy: policy,
+	}
+}
+
+// Share returns a Table with the same slots as t, for a search
+// in another goroutine. It starts at t's generation, with no
+// statistics, so call NewSearch on each, once per search.
+func (t *Table) Share() *Table {
+	return &Table{
+		slots:      t.slots,
+		mask:       t.mask,
+		policy:     t.policy,
+		generation: t.generation,
+	}
+}
+
+// NewSearch marks the start of a new search. Entries from
+// earlier searches get replaced regardless of depth, and
+// the hit rate statistics start over.
+func (t *Table) NewSearch() {
+	t.generation++
+	t.Probes, t.Hits, t.Stores = 0, 0, 0
+}
+
+// Clear empties the table, and any tables sharing its slots.
+// Nothing else should be using them.
+func (t *Table) Clear() {
+	for i := range t.slots {
+		t.slots[i] = slot{}
+	}
+	t.NewSearch()
+}
+
+// load returns the entry in slot s, and false if the
+// slot is empty, or half of two different stores.
+func (s *slot) load() (Entry, bool) {
+	check := atomic.LoadUint64(&s.check)
+	data := atomic.LoadUint64(&s.data)
+	e := Entry{
+		Key:        check ^ data,
+		Value:      int32(data),
+		Depth:      int8(data >> 32),
+		Bound:      uint8(data >> 40),
+		Move:       int8(data >> 48),
+		generation: uint8(data >> 56),
+	}
+	return e, e.Depth != 0
+}
+
+// store puts e in slot s
+func (s *slot) store(e Entry) {
+	data := uint64(uint32(e.Value)) | uint64(uint8(e.Depth))<<32 |
+		uint64(e.Bound)<<40 | uint64(uint8(e.Move))<<48 | uint64(e.generation)<<56
+	atomic.StoreUint64(&s.check, e.Key^data)
+	atomic.StoreUint64(&s.data, data)
+}
+
+// Probe looks up key, returning a copy of the entry for
+// that position, and true if the table has one.
+func (t *Table) Probe(key uint64) (Entry, bool) {
+	t.Probes++
+	e, ok := t.slots[key&t.mask].load()
+	if !ok || e.Key != key {
+		return Entry{Move: -1}, false
+	}
+	t.Hits++
+	return e, true
+}
+
+// Store puts a position's value in the table, subject to the
+// replacement policy. depth is the number of moves searched
+// below the position, and must be at least 1.
+func (t *Table) Store(key uint64, depth int, value int, bound int, move int) {
+	s := &t.slots[key&t.mask]
+	if e, ok := s.load(); ok && t.policy == DEPTH && e.Key != key && e.generation == t.generation && int(e.Depth) > depth {
+		return
+	}
+	t.Stores++
+	s.store(Entry{
+		Key:        key,
+		Value:      int32(value),
+		Depth:      int8(depth),
+		Bound:      uint8(bound),
+		Move:       int8(move),
+		generation: t.generation,
+	})
+}
+
+// HitRate returns the fraction of probes since the
+// last NewSearch that found their position.
+func (t *Table) HitRate() float64 {
+	if t.Probes == 0 {
+		return 0.0
+	}
+	return float64(t.Hits) / float64(t.Probes)
+}
+
+// Win and loss scores have the number of plies from the root
+// of the search subtracted, so they depend on the root. ToTable
+// and FromTable convert them to and from plies from the stored
+// position, which doesn't.
+
+// ToTable converts a value found at ply for storing
+func ToTable(value int, ply int) int {
+	switch {
+	case score.Score(value).IsWin():
+		return value + ply
+	case score.Score(value).IsLoss():
+		return value - ply
+	}
+	return value
+}
+
+// FromTable converts a stored value for use at ply
+func FromTable(value int, ply int) int {
+	switch {
+	case score.Score(value).IsWin():
+		return value - ply
+	case score.Score(value).IsLoss():
+		return value + ply
+	}
+	return value
+}
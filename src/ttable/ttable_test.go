@@ -0,0 +1,109 @@
+package ttable
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"squava/src/game"
+)
+
+// randomBoard makes marks random moves on an empty
+// Standard board, alternating players, X first.
+func randomBoard(r *rand.Rand, marks int) game.Bitboard {
+	b := game.Standard.Bitboard()
+	player := game.MAXIMIZER
+	for ; marks > 0; marks-- {
+		empty := b.Empty()
+		n := r.Intn(bits.OnesCount64(empty))
+		for ; n > 0; n-- {
+			empty &= empty - 1
+		}
+		b.MakeMove(bits.TrailingZeros64(empty), player)
+		player = -player
+	}
+	return b
+}
+
+// allSymmetries is every rotation and reflection of the Standard board
+var allSymmetries = game.Standard.Symmetries(nil)
+
+func TestKeySymmetric(t *testing.T) {
+	shape := game.Standard
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		b := randomBoard(r, 1+r.Intn(12))
+		key, transform := Key(&b, game.MAXIMIZER)
+		if other, _ := Key(&b, game.MINIMIZER); other == key {
+			t.Fatalf("same key with either player to move in\n%v", &b)
+		}
+
+		// A move stored from b comes back out as the same move,
+		// transformed, in each image of b, or as one just as good
+		// if the image is symmetric
+		cell := bits.TrailingZeros64(b.Empty())
+		stored := shape.TransformedCell(transform, cell)
+		for _, u := range allSymmetries {
+			image := b.Transform(u)
+			k, v := Key(&image, game.MAXIMIZER)
+			if k != key {
+				t.Fatalf("image of\n%v\nunder transform %d has a different key", &b, u)
+			}
+			got := shape.TransformedCell(shape.InverseTransform(v), stored)
+			want := shape.TransformedCell(u, cell)
+			same := false
+			for _, w := range allSymmetries {
+				if image.Transform(w) == image && shape.TransformedCell(w, want) == got {
+					same = true
+				}
+			}
+			if !same {
+				t.Fatalf("move %d of\n%v\ncame back as %d in image %d, want %d", cell, &b, got, u, want)
+			}
+		}
+	}
+}
+
+func TestKeyUnder(t *testing.T) {
+	identity := allSymmetries[:1]
+	r := rand.New(rand.NewSource(2))
+	for n := 0; n < 200; n++ {
+		b := randomBoard(r, 1+r.Intn(12))
+		key, transform := KeyUnder(&b, game.MAXIMIZER, identity)
+		if transform != 0 {
+			t.Fatalf("transform %d with only the identity", transform)
+		}
+		if key != Hash(&b) {
+			t.Fatalf("key with only the identity isn't the board's hash")
+		}
+		for _, u := range allSymmetries[1:] {
+			image := b.Transform(u)
+			if k, _ := KeyUnder(&image, game.MAXIMIZER, identity); k == key && image != b {
+				t.Fatalf("image of\n%v\nunder transform %d has the same key", &b, u)
+			}
+		}
+	}
+}
+
+func TestStoreProbe(t *testing.T) {
+	table := New(1, DEPTH)
+	table.NewSearch()
+	b := game.Standard.Bitboard()
+	b.MakeMove(game.Cell(1, 1), game.MAXIMIZER)
+	key, _ := Key(&b, game.MINIMIZER)
+
+	if _, ok := table.Probe(key); ok {
+		t.Fatalf("empty table has an entry")
+	}
+	table.Store(key, 4, -37, UPPER, 12)
+	e, ok := table.Probe(key)
+	if !ok {
+		t.Fatalf("stored entry missing")
+	}
+	if e.Value != -37 || e.Depth != 4 || e.Bound != UPPER || e.Move != 12 {
+		t.Errorf("got back %+v", e)
+	}
+	if _, ok := table.Probe(key ^ 1); ok {
+		t.Errorf("a different key finds the entry")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+/*
+ * Generate an endgame tablebase: perfect play for every squava
+ * position with up to -k empty cells, for engines to load.
+ *
+ * ./tbgen                      # squava.tb, up to 5 empty cells
+ * ./tbgen -k 6 -o squava6.tb
+ * ./tbgen -rule lose -o lose.tb
+ */
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"squava/src/game"
+	"squava/src/tablebase"
+)
+
+func main() {
+	maxEmpty := flag.Int("k", tablebase.DefaultEmpty, "most empty cells of a position in the table")
+	filename := flag.String("o", "squava.tb", "tablebase file to write")
+	rulesName := flag.String("rule", "win", "a move that makes a 4-in-a-row and a 3-in-a-row: win, lose, or contained (wins if the 3 is inside the 4)")
+	flag.Parse()
+
+	rules, err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if *maxEmpty < 0 || *maxEmpty > 25 {
+		fmt.Fprintf(os.Stderr, "Bad number of empty cells %d\n", *maxEmpty)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	table := tablebase.Generate(*maxEmpty, rules)
+	fmt.Printf("%d positions with up to %d empty cells, -rule %v, %v\n", table.Len(), table.MaxEmpty(), table.Rules(), time.Since(start))
+
+	if err := table.Save(*filename); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}